@@ -0,0 +1,49 @@
+import axios from 'src/utils/axios';
+
+// getMetrics returns the curated metrics series of a function, or of all the functions of the
+// user when functionId is not set. Times are in milliseconds.
+export const getMetrics = async (functionId, series, startTime, endTime, step) => {
+  const url = functionId
+    ? `/eywa/api/functions/${functionId}/metrics`
+    : `/eywa/api/metrics`;
+
+  const response = await axios.get(url, {
+    params: {
+      series: series.join(','),
+      range: `${Math.max(1, Math.round((endTime - startTime) / 1000))}s`,
+      step: `${Math.max(1, Math.round(step / 1000))}s`,
+      end: Math.floor(endTime / 1000)
+    }
+  });
+
+  return response.data.series;
+};
+
+// findSeries returns the series with the name, and the label value for series split by a label
+export const findSeries = (series, name, label, value) => {
+  return series.find((s) => s.name === name && (!label || (s.labels && s.labels[label] === value)));
+};
+
+// chartData converts the points of a series to [milliseconds, value] pairs
+export const chartData = (series) => {
+  if (!series) {
+    return [];
+  }
+
+  return series.points.map(({ timestamp, value }) => [timestamp * 1000, value]);
+};
+
+// lastValue returns the latest value of a series, 0 when it has none
+export const lastValue = (series) => {
+  if (!series) {
+    return 0;
+  }
+
+  for (let i = series.points.length - 1; i >= 0; i--) {
+    if (series.points[i].value !== null) {
+      return series.points[i].value;
+    }
+  }
+
+  return 0;
+};
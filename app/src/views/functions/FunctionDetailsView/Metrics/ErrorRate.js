@@ -8,7 +8,7 @@ import {
   CardContent,
   useTheme
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, findSeries, lastValue } from 'src/utils/metrics';
 import {
   roundInterval
 } from 'src/utils/time';
@@ -26,18 +26,8 @@ const ErrorRate = ({ functionId, endTime, range, width }) => {
       step = step < minInterval ? minInterval : step;
       endTime = Math.floor(endTime / step) * step;
 
-      const payload = {
-        "type": "instant",
-        "series": ["gateway_function_invocation_total"],
-        "group_by": "function_id",
-        "label_matchers": `function_id="${functionId}",code=~"4..|5.."`,
-        "query": `((sum by(<<.GroupBy>>) (rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms]))) / (sum by(<<.GroupBy>>) (rate(<<index .Series 0>>[${step}ms])))) * 100`,
-        "end": endTime / 1000
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const data = response.data.Data;
-      setSeries([data.result.length == 0 ? [0] : isNaN(data.result[0].value[1]) ? [0] : data.result[0].value[1]]);
+      const metrics = await getMetrics(functionId, ['error_percent'], endTime - step, endTime, step);
+      setSeries([lastValue(findSeries(metrics, 'error_percent'))]);
 
     } catch (err) {
       console.error(err);
@@ -9,11 +9,10 @@ import {
   CardContent,
   useTheme
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, findSeries, chartData } from 'src/utils/metrics';
 import {
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 
 const RequestAndErrorRates = ({ functionId, endTime, range, width }) => {
   const theme = useTheme();
@@ -32,24 +31,18 @@ const RequestAndErrorRates = ({ functionId, endTime, range, width }) => {
       endTime = Math.floor(endTime / step) * step;
       const startTime = endTime - range;
 
-      Promise.all([
-        getAllRequests(startTime, endTime, step),
-        getXXXRequests(`code=~"2.."`, startTime, endTime, step),
-        getXXXRequests(`code=~"4.."`, startTime, endTime, step),
-        getXXXRequests(`code=~"5.."`, startTime, endTime, step)
-      ])
-        .then(([allRequests, status2XX, status4XX, status5XX]) => {
-          if (allRequests.length > 0) allRequests[0] = { ...allRequests[0], name: "Request Rate" }
-          if (status2XX.length > 0) status2XX[0] = { ...status2XX[0], name: "2XX Success Rate" }
-          if (status4XX.length > 0) status4XX[0] = { ...status4XX[0], name: "4XX Errors Rate" }
-          if (status5XX.length > 0) status5XX[0] = { ...status5XX[0], name: "5XX Errors Rate" }
-          const series = allRequests.concat(status2XX).concat(status4XX).concat(status5XX)
+      const metrics = await getMetrics(functionId, ['request_rate', 'status_rate'], startTime, endTime, step);
+      const series = [
+        { name: "Request Rate", data: chartData(findSeries(metrics, 'request_rate')) },
+        { name: "2XX Success Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '2xx')) },
+        { name: "4XX Errors Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '4xx')) },
+        { name: "5XX Errors Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '5xx')) }
+      ];
 
-          const newChart = buildChart(series);
-          addTimeAxis(newChart.options, width, endTime, range);
+      const newChart = buildChart(series);
+      addTimeAxis(newChart.options, width, endTime, range);
 
-          setChart(newChart);
-        })
+      setChart(newChart);
     } catch (err) {
       console.error(err);
       enqueueSnackbar('Failed to get metrics', {
@@ -58,50 +51,6 @@ const RequestAndErrorRates = ({ functionId, endTime, range, width }) => {
     }
   };
 
-  const getAllRequests = async (startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_invocation_total"],
-      "label_matchers": `function_id="${functionId}"`,
-      "group_by": "function_id",
-      "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  };
-
-
-  const getXXXRequests = async (codeLabel, startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_invocation_total"],
-      "label_matchers": `function_id="${functionId}",${codeLabel}`,
-      "group_by": "function_id",
-      "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  }
-
-
-  const processMetrics = (data) => {
-    return data.result.map(({ values }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        data: data
-      }
-    });
-  }
-
   useEffect(() => {
     getSeries();
   }, [range, endTime, width])
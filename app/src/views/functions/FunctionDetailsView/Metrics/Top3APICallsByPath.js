@@ -9,11 +9,10 @@ import {
   CardContent,
   useTheme
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, chartData } from 'src/utils/metrics';
 import {
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 
 const Top3APICallsByPath = ({ functionId, endTime, range, width }) => {
   const theme = useTheme();
@@ -28,19 +27,8 @@ const Top3APICallsByPath = ({ functionId, endTime, range, width }) => {
       endTime = Math.floor(endTime / step) * step;
       const startTime = endTime - range;
 
-      const payload = {
-        "type": "range",
-        "series": ["gateway_function_invocation_total"],
-        "group_by": "path",
-        "label_matchers": `function_id="${functionId}"`,
-        "query": `topk(3, sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>))`,
-        "start": startTime / 1000,
-        "end": endTime / 1000,
-        "step": step / 1000
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const series = processMetrics(response.data.Data, true);
+      const metrics = await getMetrics(functionId, ['top_paths'], startTime, endTime, step);
+      const series = metrics.map((s) => ({ name: s.labels.path, data: chartData(s) }));
 
       const newChart = buildChart(series);
       addTimeAxis(newChart.options, width, endTime, range);
@@ -54,19 +42,6 @@ const Top3APICallsByPath = ({ functionId, endTime, range, width }) => {
     }
   };
 
-  const processMetrics = (data, label = false) => {
-    return data.result.map(({ values, metric }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        name: label ? JSON.stringify(metric) : undefined,
-        data: data
-      }
-    });
-  }
-
   useEffect(() => {
     getSeries();
   }, [range, endTime, width])
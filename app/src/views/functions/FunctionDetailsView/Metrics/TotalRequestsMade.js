@@ -8,7 +8,7 @@ import {
   Typography,
   makeStyles
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, findSeries, lastValue } from 'src/utils/metrics';
 
 const useStyles = makeStyles((theme) => ({
   root: {
@@ -45,17 +45,10 @@ const TotalRequests = ({ functionId, endTime }) => {
 
   const getSeries = async () => {
     try {
-      const payload = {
-        "type": "instant",
-        "series": ["gateway_function_invocation_total"],
-        "group_by": "function_id",
-        "label_matchers": `function_id="${functionId}"`,
-        "query": `sum(<<index .Series 0>>{<<.LabelMatchers>>}) by(<<.GroupBy>>)`
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const data = response.data.Data;
-      setTotalRequests(data.result.length > 0 ? data.result[0].value[1] : 0);
+      const step = 60 * 1000;
+      const now = Date.now();
+      const metrics = await getMetrics(functionId, ['invocations_total'], now - step, now, step);
+      setTotalRequests(lastValue(findSeries(metrics, 'invocations_total')));
 
     } catch (err) {
       console.error(err);
@@ -1,6 +1,7 @@
 import React, {
   useState,
-  useCallback
+  useCallback,
+  useEffect
 } from 'react';
 import { useHistory } from 'react-router-dom';
 import { useDropzone } from 'react-dropzone';
@@ -12,6 +13,7 @@ import {
   Button,
   Card,
   CardContent,
+  Checkbox,
   Container,
   Grid,
   IconButton,
@@ -24,6 +26,7 @@ import {
   TextField,
   Tooltip,
   FormControl,
+  FormControlLabel,
   InputLabel,
   Select,
   SvgIcon,
@@ -39,6 +42,7 @@ import FileCopyIcon from '@material-ui/icons/FileCopy';
 import Page from 'src/components/Page';
 import Header from './Header';
 import bytesToSize from 'src/utils/bytesToSize';
+import useIsMountedRef from 'src/hooks/useIsMountedRef';
 
 const FilesDropzone = ({ setFieldValue, ...rest }) => {
   const classes = useStyles();
@@ -181,7 +185,33 @@ const useStyles = makeStyles((theme) => ({
 const ImageCreateView = () => {
   const classes = useStyles();
   const history = useHistory();
+  const isMountedRef = useIsMountedRef();
   const { enqueueSnackbar } = useSnackbar();
+  const [runtimes, setRuntimes] = useState([]);
+
+  const getRuntimes = useCallback(async () => {
+    try {
+      const response = await axios.get('/eywa/api/runtimes');
+
+      if (isMountedRef.current) {
+        setRuntimes(response.data.objects);
+      }
+    } catch (err) {
+      console.error(err);
+      enqueueSnackbar('Failed to get runtimes', {
+        variant: 'error'
+      });
+    }
+  }, [isMountedRef]);
+
+  useEffect(() => {
+    getRuntimes();
+  }, [getRuntimes]);
+
+  const executablePath = (name) => {
+    const runtime = runtimes.find((r) => r.name === name);
+    return runtime ? runtime.executable_path : undefined;
+  };
 
   return (
     <Page
@@ -199,6 +229,7 @@ const ImageCreateView = () => {
               runtime: "go",
               version: "0.1.0",
               executablePath: "",
+              runTests: true,
               files: [],
               submit: null
             }}
@@ -238,8 +269,9 @@ const ImageCreateView = () => {
                 formData.append("version", values.version)
                 formData.append("name", values.name)
                 formData.append("runtime", values.runtime)
+                formData.append("run_tests", values.runTests)
 
-                if (values.runtime === "custom") {
+                if (executablePath(values.runtime) && values.executablePath) {
                   formData.append("executable_path", values.executablePath)
                 }
 
@@ -352,15 +384,17 @@ const ImageCreateView = () => {
                             onBlur={handleBlur}
                             variant="outlined"
                           >
-                            <MenuItem value={"go"}>Go</MenuItem>
-                            <MenuItem value={"node14"}>Node 14</MenuItem>
-                            <MenuItem value={"python3"}>Python 3</MenuItem>
-                            <MenuItem value={"ruby"}>Ruby</MenuItem>
-                            <MenuItem value={"custom"}>Custom</MenuItem>
+                            {runtimes.map((runtime) => (
+                              <MenuItem key={runtime.name} value={runtime.name}>
+                                {runtime.name}
+                                {runtime.version && ` (${runtime.version})`}
+                                {runtime.deprecated && ' - deprecated'}
+                              </MenuItem>
+                            ))}
                           </Select>
                         </FormControl>
                       </Grid>
-                      {values.runtime === "custom" &&
+                      {executablePath(values.runtime) &&
                         <Grid
                           item
                           xs={12}
@@ -369,16 +403,34 @@ const ImageCreateView = () => {
                             error={Boolean(touched.executablePath && errors.executablePath)}
                             fullWidth
                             helperText={touched.executablePath && errors.executablePath}
-                            label="Executable filepath (relative to your zip structure)"
+                            label={executablePath(values.runtime) === "required"
+                              ? "Executable filepath (relative to your zip structure)"
+                              : "Function process (optional)"}
                             name="executablePath"
                             onBlur={handleBlur}
                             onChange={handleChange}
-                            required
+                            required={executablePath(values.runtime) === "required"}
                             value={values.executablePath}
                             variant="outlined"
                           />
                         </Grid>
                       }
+                      <Grid
+                        item
+                        xs={12}
+                      >
+                        <FormControlLabel
+                          control={
+                            <Checkbox
+                              color="primary"
+                              checked={values.runTests}
+                              name="runTests"
+                              onChange={handleChange}
+                            />
+                          }
+                          label="Run the function tests before building the image"
+                        />
+                      </Grid>
                     </Grid>
                     <Grid item xs={12} md={12}>
                       <FilesDropzone
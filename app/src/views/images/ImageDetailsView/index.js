@@ -48,28 +48,43 @@ const ImageBuildView = () => {
   const { enqueueSnackbar } = useSnackbar();
   const [buildInfo, setBuildInfo] = useState([]);
   const [image, setImage] = useState(null)
-  const [building, setBuilding] = useState(false);
+  const [tests, setTests] = useState(null)
+  const [vulnerabilities, setVulnerabilities] = useState(null)
   const [userScrolled, setUserScrolled] = useState(false);
-  const [time, setTime] = useState(0);
-  const [timer, setTimer] = useState(null);
+  const wasBuildingRef = useRef(false);
 
-  const getImage = async () => {
+  const building = image !== null && (image.state === 'building' || image.state === 'queued');
+
+  const getImage = async (notify) => {
     try {
       const url = `/eywa/api/images/${imageId}`
       const response = await axios.get(url)
 
       const data = response.data
 
+      if (!isMountedRef.current) {
+        return;
+      }
+
       setImage(data)
-      if (building == true && data.state !== "building") {
-        let msg = data.state === "success" ? "Image built successfully" : "Image build failed"
+      wasBuildingRef.current = data.state === 'building' || data.state === 'queued';
+      if (!wasBuildingRef.current) {
+        getTests();
+        getVulnerabilities();
+      }
+      if (notify) {
+        let msg = data.state === "success"
+          ? "Image built successfully"
+          : data.state === "cancelled"
+            ? "Image build cancelled"
+            : data.state === "timed_out"
+              ? "Image build timed out"
+              : "Image build failed"
         let variant = data.state === "success" ? "success" : "error"
         enqueueSnackbar(msg, {
           variant: variant
         });
       }
-      setBuilding(data.state === 'building')
-
     } catch (err) {
       console.error(err);
       enqueueSnackbar('Failed to get image', {
@@ -78,17 +93,42 @@ const ImageBuildView = () => {
     }
   };
 
-  const getImageBuildInfo = async () => {
+  const getTests = async () => {
+    try {
+      const response = await axios.get(`/eywa/api/images/${imageId}/tests`)
+
+      if (isMountedRef.current) {
+        setTests(response.data)
+      }
+    } catch (err) {
+      // Builds without tests have no results
+      if (!err.response || err.response.status !== 404) {
+        console.error(err);
+      }
+    }
+  };
+
+  const getVulnerabilities = async () => {
     try {
-      const url = `/eywa/api/images/${imageId}/buildlogs`
-      const response = await axios.get(url);
+      const response = await axios.get(`/eywa/api/images/${imageId}/vulnerabilities`)
 
-      const diff = response.data.logs.filter(x => !buildInfo.includes(x));
+      if (isMountedRef.current) {
+        setVulnerabilities(response.data)
+      }
+    } catch (err) {
+      console.error(err);
+    }
+  };
 
-      setBuildInfo(buildInfo => [...buildInfo, ...diff]);
+  const handleCancel = async () => {
+    try {
+      await axios.post(`/eywa/api/images/${imageId}/cancel`)
+      enqueueSnackbar('Cancelling image build', {
+        variant: 'info'
+      });
     } catch (err) {
       console.error(err);
-      enqueueSnackbar('Failed to get images build logs', {
+      enqueueSnackbar('Failed to cancel image build', {
         variant: 'error'
       });
     }
@@ -104,22 +144,28 @@ const ImageBuildView = () => {
   }, [])
 
   useEffect(() => {
-    setTimer(setInterval(() => { setTime(time => time + 1) }, 1000));
-    return () => { clearInterval(timer) };
-  }, []);
+    getImage(false);
 
-  useEffect(() => {
-    if (image && image.state !== "building") {
-      clearInterval(timer);
-      console.log("done building");
-      console.log("time: ", time);
-    }
-  }, [image])
+    // The logs are streamed as server-sent events, a reconnecting source resumes after the last line
+    const source = new EventSource(`/eywa/api/images/${imageId}/buildlogs`);
+    source.addEventListener('log', (event) => {
+      setBuildInfo(buildInfo => [...buildInfo, event.data]);
+    });
+    source.addEventListener('state', () => {
+      source.close();
+      // Only notify when the build finished while it was followed
+      getImage(wasBuildingRef.current);
+    });
+    source.onerror = () => {
+      if (source.readyState === EventSource.CLOSED) {
+        enqueueSnackbar('Failed to get images build logs', {
+          variant: 'error'
+        });
+      }
+    };
 
-  useEffect(() => {
-    getImage();
-    getImageBuildInfo()
-  }, [time]);
+    return () => source.close();
+  }, [imageId]);
 
   useEffect(() => {
     if (fieldRef.current && !userScrolled) {
@@ -182,6 +228,41 @@ const ImageBuildView = () => {
                     </Label>
                   </div>
                 </Box>
+                {tests &&
+                  <>
+                    <Box ml={3} mr={1}>
+                      <Typography variant="body1">
+                        Tests:
+                      </Typography>
+                    </Box>
+                    <Box>
+                      <div className={classes.valueContainer}>
+                        <Label color={tests.status === "passed" ? 'success' : 'error'}>
+                          {`${tests.passed} passed, ${tests.failed} failed, ${tests.skipped} skipped`}
+                          {tests.coverage !== undefined && `, ${tests.coverage.toFixed(1)}% coverage`}
+                        </Label>
+                      </div>
+                    </Box>
+                  </>
+                }
+                {vulnerabilities && vulnerabilities.scan_state &&
+                  <>
+                    <Box ml={3} mr={1}>
+                      <Typography variant="body1">
+                        Vulnerabilities:
+                      </Typography>
+                    </Box>
+                    <Box>
+                      <div className={classes.valueContainer}>
+                        <Label color={vulnerabilities.deployable ? 'success' : 'error'}>
+                          {vulnerabilities.scan_state === "scanned"
+                            ? `${vulnerabilities.summary.critical} critical, ${vulnerabilities.summary.high} high, ${vulnerabilities.summary.medium} medium, ${vulnerabilities.summary.low} low`
+                            : "scan failed"}
+                        </Label>
+                      </div>
+                    </Box>
+                  </>
+                }
               </Box>
             </CardContent>
             <Divider />
@@ -196,6 +277,18 @@ const ImageBuildView = () => {
           </Paper>
         </Box>
       </Container>
+      {building &&
+        <Fab
+          variant="extended"
+          size="small"
+          color="secondary"
+          aria-label="cancel"
+          onClick={handleCancel}
+          style={{ position: "absolute", right: "20px", bottom: "20px" }}
+        >
+          Cancel build
+        </Fab>
+      }
       {!building &&
         <Fab
           variant="extended"
@@ -31,14 +31,13 @@ import {
   X as XIcon
 } from 'react-feather';
 import useIsMountedRef from 'src/hooks/useIsMountedRef';
-import axios from 'src/utils/axios';
+import { getMetrics, chartData } from 'src/utils/metrics';
 import {
   formatTime,
   formatTimeNoSeconds,
   formatDuration,
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 
 const useStyles = makeStyles((theme) => ({
   container: {
@@ -148,19 +147,8 @@ const QueueDwellHeatMap = ({ functionId }) => {
       const endTime = Math.floor(getEndTime() / step) * step;
       const startTime = endTime - range;
 
-      const payload = {
-        "type": "range",
-        "series": ["gateway_queue_dwell_duration_milliseconds_bucket"],
-        "group_by": "le",
-        "label_matchers": `function_id="${functionId}"`,
-        "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-        "start": startTime / 1000,
-        "end": endTime / 1000,
-        "step": step / 1000
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const series = processMetrics(response.data.Data);
+      const metrics = await getMetrics(functionId, ['dwell_buckets'], startTime, endTime, step);
+      const series = processMetrics(metrics);
 
       const newChart = buildChart(series);
       addTimeAxis(newChart.options, getWidth(), endTime);
@@ -177,18 +165,12 @@ const QueueDwellHeatMap = ({ functionId }) => {
     setLoading(false);
   };
 
-  const processMetrics = (data, label = false) => {
-    const series = data.result.map(({ values, metric }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        label: metric.le,
-        name: metric.le + " ms",
-        data: data
-      }
-    });
+  const processMetrics = (metrics) => {
+    const series = metrics.map((s) => ({
+      label: s.labels.le,
+      name: s.labels.le + " ms",
+      data: chartData(s)
+    }));
     return series.sort((sortSeriesByLabel));
   }
 
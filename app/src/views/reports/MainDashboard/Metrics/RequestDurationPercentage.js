@@ -9,11 +9,10 @@ import {
   CardContent,
   useTheme
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, findSeries, chartData } from 'src/utils/metrics';
 import {
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 
 
 const RequestDurationPercentage = ({ functionId, endTime, range, width }) => {
@@ -29,26 +28,20 @@ const RequestDurationPercentage = ({ functionId, endTime, range, width }) => {
       endTime = Math.floor(endTime / step) * step;
       const startTime = endTime - range;
 
-      Promise.all([
-        getBelowXXX(10, startTime, endTime, step),
-        getBelowXXX(50, startTime, endTime, step),
-        getBelowXXX(100, startTime, endTime, step),
-        getBelowXXX(500, startTime, endTime, step),
-        getAboveXXX(500, startTime, endTime, step)
-      ])
-        .then(([below10, below50, below100, below500, above500]) => {
-          if (below10.length > 0) below10[0] = { ...below10[0], name: "Duration < 10ms (%)" }
-          if (below50.length > 0) below50[0] = { ...below50[0], name: "Duration < 50ms (%)" }
-          if (below100.length > 0) below100[0] = { ...below100[0], name: "Duration < 100ms (%)" }
-          if (below500.length > 0) below500[0] = { ...below500[0], name: "Duration < 500ms (%)" }
-          if (above500.length > 0) above500[0] = { ...above500[0], name: "Duration > 500ms (%)" }
-          const series = below10.concat(below50).concat(below100).concat(below500).concat(above500)
+      const metrics = await getMetrics(functionId, ['duration_buckets'], startTime, endTime, step);
+      const below500 = chartData(findSeries(metrics, 'duration_buckets', 'le', '500'));
+      const series = [
+        { name: "Duration < 10ms (%)", data: chartData(findSeries(metrics, 'duration_buckets', 'le', '10')) },
+        { name: "Duration < 50ms (%)", data: chartData(findSeries(metrics, 'duration_buckets', 'le', '50')) },
+        { name: "Duration < 100ms (%)", data: chartData(findSeries(metrics, 'duration_buckets', 'le', '100')) },
+        { name: "Duration < 500ms (%)", data: below500 },
+        { name: "Duration > 500ms (%)", data: below500.map(([t, v]) => [t, v === null ? null : 100 - v]) }
+      ];
 
-          const newChart = buildChart(series);
-          addTimeAxis(newChart.options, width, endTime, range);
+      const newChart = buildChart(series);
+      addTimeAxis(newChart.options, width, endTime, range);
 
-          setChart(newChart);
-        })
+      setChart(newChart);
     } catch (err) {
       console.error(err);
       enqueueSnackbar('Failed to get metrics', {
@@ -57,48 +50,6 @@ const RequestDurationPercentage = ({ functionId, endTime, range, width }) => {
     }
   };
 
-  const getAboveXXX = async (le, startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_duration_milliseconds_bucket", "gateway_function_duration_milliseconds_count"],
-      "label_matchers": `function_id="${functionId}",le="${le}"`,
-      "query": `((<<index .Series 0>>{<<.LabelMatchers>>}) / ignoring (le) <<index .Series 1>>{function_id="${functionId}"}) * 100`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  };
-
-
-  const getBelowXXX = async (le, startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_duration_milliseconds_bucket", "gateway_function_duration_milliseconds_count"],
-      "label_matchers": `function_id="${functionId}",le="${le}"`,
-      "query": `(sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) / sum(rate(<<index .Series 1>>{function_id="${functionId}"}[${step}ms]))) * 100`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  }
-
-
-  const processMetrics = (data) => {
-    return data.result.map(({ values }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        data: data
-      }
-    });
-  }
-
   useEffect(() => {
     getSeries();
   }, [range, endTime, width])
@@ -9,11 +9,10 @@ import {
   CardContent,
   useTheme
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, chartData } from 'src/utils/metrics';
 import {
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 
 const RequestRateByMethod = ({ functionId, endTime, range, width }) => {
   const theme = useTheme();
@@ -29,19 +28,8 @@ const RequestRateByMethod = ({ functionId, endTime, range, width }) => {
       endTime = Math.floor(endTime / step) * step;
       const startTime = endTime - range;
 
-      const payload = {
-        "type": "range",
-        "series": ["gateway_function_invocation_started"],
-        "group_by": "method",
-        "label_matchers": `function_id="${functionId}"`,
-        "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-        "start": startTime / 1000,
-        "end": endTime / 1000,
-        "step": step / 1000
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const series = processMetrics(response.data.Data, true);
+      const metrics = await getMetrics(functionId, ['method_rate'], startTime, endTime, step);
+      const series = metrics.map((s) => ({ name: s.labels.method, data: chartData(s) }));
 
       const newChart = buildChart(series);
       addTimeAxis(newChart.options, width, endTime, range);
@@ -55,19 +43,6 @@ const RequestRateByMethod = ({ functionId, endTime, range, width }) => {
     }
   };
 
-  const processMetrics = (data, label = false) => {
-    return data.result.map(({ values, metric }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        name: label ? JSON.stringify(metric) : undefined,
-        data: data
-      }
-    });
-  }
-
   useEffect(() => {
     getSeries();
   }, [range, endTime, width])
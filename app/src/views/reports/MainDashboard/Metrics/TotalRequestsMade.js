@@ -8,7 +8,7 @@ import {
   Typography,
   makeStyles
 } from '@material-ui/core';
-import axios from 'src/utils/axios';
+import { getMetrics, findSeries, lastValue } from 'src/utils/metrics';
 
 const TotalRequests = ({ endTime }) => {
   const { enqueueSnackbar } = useSnackbar();
@@ -16,16 +16,10 @@ const TotalRequests = ({ endTime }) => {
 
   const getSeries = async () => {
     try {
-      const payload = {
-        "type": "instant",
-        "series": ["gateway_function_invocation_total"],
-        "group_by": "user_id",
-        "query": `sum(<<index .Series 0>>) by(<<.GroupBy>>)`
-      }
-
-      const response = await axios.post(`/eywa/api/metrics/query`, payload);
-      const data = response.data.Data;
-      setTotalRequests(data.result.length > 0 ? data.result[0].value[1] : 0);
+      const step = 60 * 1000;
+      const now = Date.now();
+      const metrics = await getMetrics(null, ['invocations_total'], now - step, now, step);
+      setTotalRequests(lastValue(findSeries(metrics, 'invocations_total')));
 
     } catch (err) {
       console.error(err);
@@ -10,8 +10,8 @@ import {
 import {
   roundInterval
 } from 'src/utils/time';
-import { parseValue } from 'src/utils/numbers';
 import axios from 'src/utils/axios';
+import { getMetrics, findSeries, chartData, lastValue } from 'src/utils/metrics';
 import Page from 'src/components/Page';
 import Header from './Header';
 import FunctionOverview from './FunctionsOverview';
@@ -60,26 +60,20 @@ const DashboardAlternativeView = () => {
       getDBStats();
       getTop5Paths(startTime, endTime, step);
 
-      Promise.all([
-        getAllRequests(startTime, endTime, step),
-        getXXXRequests(`code=~"2.."`, startTime, endTime, step),
-        getXXXRequests(`code=~"4.."`, startTime, endTime, step),
-        getXXXRequests(`code=~"5.."`, startTime, endTime, step)
-      ])
-        .then(([allRequests, status2XX, status4XX, status5XX]) => {
-          if (allRequests.length > 0) allRequests[0] = { ...allRequests[0], name: "Request Rate" }
-          if (status2XX.length > 0) status2XX[0] = { ...status2XX[0], name: "2XX Success Rate" }
-          if (status4XX.length > 0) status4XX[0] = { ...status4XX[0], name: "4XX Errors Rate" }
-          if (status5XX.length > 0) status5XX[0] = { ...status5XX[0], name: "5XX Errors Rate" }
-          const series = allRequests.concat(status2XX).concat(status4XX).concat(status5XX)
-
-          setRequestAndErrorRatesData({
-            series: series,
-            width: width,
-            endTime: endTime,
-            startTime: startTime
-          })
-        })
+      const metrics = await getMetrics(null, ['request_rate', 'status_rate'], startTime, endTime, step);
+      const series = [
+        { name: "Request Rate", data: chartData(findSeries(metrics, 'request_rate')) },
+        { name: "2XX Success Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '2xx')) },
+        { name: "4XX Errors Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '4xx')) },
+        { name: "5XX Errors Rate", data: chartData(findSeries(metrics, 'status_rate', 'status', '5xx')) }
+      ];
+
+      setRequestAndErrorRatesData({
+        series: series,
+        width: width,
+        endTime: endTime,
+        startTime: startTime
+      })
     } catch (err) {
       console.error(err);
       enqueueSnackbar('Failed to get metrics', {
@@ -89,18 +83,8 @@ const DashboardAlternativeView = () => {
   };
 
   const getTop5Paths = async (startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_invocation_total"],
-      "group_by": "path",
-      "label_matchers": `function_id!=""`,
-        "query": `topk(3, sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>))`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload);
-    const series = processMetrics(response.data.Data, true, true);
+    const metrics = await getMetrics(null, ['top_paths'], startTime, endTime, step);
+    const series = metrics.map((s) => ({ name: s.labels.path, data: chartData(s) }));
 
     setTop5Data({
       series: series,
@@ -110,73 +94,16 @@ const DashboardAlternativeView = () => {
     })
   };
 
-  const getAllRequests = async (startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_invocation_total"],
-      "group_by": "user_id",
-      "label_matchers": `function_id!=""`,
-      "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  };
-
-  const getXXXRequests = async (codeLabel, startTime, endTime, step) => {
-    const payload = {
-      "type": "range",
-      "series": ["gateway_function_invocation_total"],
-      "label_matchers": `${codeLabel}`,
-      "group_by": "user_id",
-      "query": `sum(rate(<<index .Series 0>>{<<.LabelMatchers>>}[${step}ms])) by(<<.GroupBy>>)`,
-      "start": startTime / 1000,
-      "end": endTime / 1000,
-      "step": step / 1000
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload)
-    return processMetrics(response.data.Data)
-  }
-
-  const processMetrics = (data, label = false) => {
-    return data.result.map(({ values, metric }) => {
-      const data = []
-      for (let i = 0; i < values.length; i++) {
-        data.push([values[i][0] * 1000, parseValue(values[i][1])])
-      }
-      return {
-        name: label ? JSON.stringify(metric) : undefined,
-        data: data
-      }
-    });
-  }
-
   const getTotalRequests = async () => {
-    const payload = {
-      "type": "instant",
-      "series": ["gateway_function_invocation_total"],
-      "group_by": "user_id",
-      "label_matchers": `function_id!=""`,
-      "query": `sum by (<<.GroupBy>>) (round(increase(<<index .Series 0>>{<<.LabelMatchers>>}[15m])))`
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload);
-    const data = response.data.Data;
-    setTotalRequests(data.result.length > 0 ? data.result[0].value[1] : 0);
+    const endTime = moment().valueOf();
+    const metrics = await getMetrics(null, ['invocations'], endTime - range, endTime, range);
+    setTotalRequests(Math.round(lastValue(findSeries(metrics, 'invocations'))));
   };
 
   const getTotalRequestsSinceEpoch = async () => {
-    const payload = {
-      "type": "instant",
-      "series": ["gateway_function_invocation_total"],
-      "group_by": "user_id",
-      "label_matchers": `function_id!=""`,
-      "query": `sum by (<<.GroupBy>>) (<<index .Series 0>>{<<.LabelMatchers>>})`
-    }
-    const response = await axios.post(`/eywa/api/metrics/query`, payload);
-    const data = response.data.Data;
-    setTotalRequestsSinceEpoch(data.result.length > 0 ? data.result[0].value[1] : 0);
+    const endTime = moment().valueOf();
+    const metrics = await getMetrics(null, ['invocations_total'], endTime - minInterval, endTime, minInterval);
+    setTotalRequestsSinceEpoch(lastValue(findSeries(metrics, 'invocations_total')));
   };
 
   const getFunctions = async () => {
@@ -53,6 +53,7 @@ func GetInvocationList(c echo.Context) error {
 
 		brief.Age = startedAt
 		brief.Status = tl[len(tl)-1].Response
+		brief.TraceID = traceID(tl)
 
 		for _, t := range tl {
 			if isErrorResponse(t.Response) {
@@ -97,6 +98,7 @@ func GetInvocation(c echo.Context) error {
 		Method:    timelines[0].Method,
 		Response:  timelines[len(timelines)-1].Response,
 		Age:       timelines[0].Timestamp,
+		TraceID:   traceID(timelines),
 	}
 
 	initEvent := types.EventDetails{
@@ -171,6 +173,17 @@ func GetInvocation(c echo.Context) error {
 	return c.JSON(http.StatusOK, tld)
 }
 
+// traceID returns the first trace id recorded for the invocation
+func traceID(timelines []types.TimelineLog) string {
+	for _, t := range timelines {
+		if t.TraceID != "" {
+			return t.TraceID
+		}
+	}
+
+	return ""
+}
+
 func isErrorResponse(response int) bool {
 	return response < 200 || response >= 400
 }
@@ -199,6 +212,16 @@ func GetEventLogs(c echo.Context) error {
 		})
 	}
 
+	if _, err := types.EventLogFields(query.Fields).Value(); err != nil {
+		log.Error(err)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "Validation error",
+			"details": map[string]string{
+				"fields": err.Error(),
+			},
+		})
+	}
+
 	query.TimestampMax = top
 	query.TimestampMin = bottom
 	query.UserID = auth.UserID
@@ -217,6 +240,53 @@ func GetEventLogs(c echo.Context) error {
 	})
 }
 
+// GetAlertLogs returns the history of fired and resolved alerts
+func GetAlertLogs(c echo.Context) error {
+	auth := c.Get("auth").(*auth.Auth)
+	db := c.Get("db").(*db.Client)
+	perPage := c.Get("per_page").(int)
+	pageNumber := c.Get("page_number").(int)
+
+	query := types.AlertLogsQuery{
+		UserID:     auth.UserID,
+		FunctionID: c.QueryParam("function_id"),
+		State:      c.QueryParam("state"),
+	}
+
+	alerts, total, err := db.GetAlertLogs(query, pageNumber, perPage)
+	if err != nil {
+		log.Errorf("Error getting alert logs: %s", err)
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, types.AlertLogsResponse{
+		Page:       pageNumber,
+		PerPage:    perPage,
+		Objects:    alerts,
+		TotalCount: total,
+	})
+}
+
+// GetFiringAlerts returns the alerts of all users whose latest state is firing, only operators can get them
+func GetFiringAlerts(c echo.Context) error {
+	auth := c.Get("auth").(*auth.Auth)
+	db := c.Get("db").(*db.Client)
+
+	if !auth.IsOperator() {
+		return c.JSON(http.StatusForbidden, "Forbidden")
+	}
+
+	alerts, err := db.GetFiringAlerts()
+	if err != nil {
+		log.Errorf("Error getting firing alerts: %s", err)
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, types.FiringAlertsResponse{
+		Objects: alerts,
+	})
+}
+
 func checkTimeRange(c echo.Context, top, bottom time.Time) (time.Time, time.Time, error) {
 	if bottom.IsZero() {
 		if !top.IsZero() {
@@ -0,0 +1,3 @@
+ALTER TABLE timeline_logs ADD COLUMN trace_id text NOT NULL DEFAULT '';
+
+CREATE INDEX timeline_logs_trace_id_idx ON timeline_logs USING btree (trace_id);
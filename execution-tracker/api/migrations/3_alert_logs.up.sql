@@ -0,0 +1,21 @@
+CREATE TABLE alert_logs (
+    id serial primary key,
+    user_id uuid NOT NULL,
+    function_id uuid NOT NULL,
+    function_name text,
+    rule_name text,
+    metric text,
+    threshold double precision,
+    value double precision,
+    state text,
+    channel text,
+    notify_error text NOT NULL DEFAULT '',
+    "timestamp" timestamp without time zone,
+    expires_at timestamp without time zone
+);
+
+CREATE INDEX alert_logs_user_id_idx ON alert_logs USING btree (user_id);
+CREATE INDEX alert_logs_function_id_idx ON alert_logs USING btree (function_id);
+CREATE INDEX alert_logs_state_idx ON alert_logs USING btree (state);
+CREATE INDEX alert_logs_timestamp_idx ON alert_logs USING btree ("timestamp");
+CREATE INDEX alert_logs_expires_at_idx ON alert_logs USING btree (expires_at);
@@ -0,0 +1,5 @@
+ALTER TABLE event_logs ADD COLUMN level text NOT NULL DEFAULT '';
+ALTER TABLE event_logs ADD COLUMN fields jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX event_logs_level_idx ON event_logs USING btree (level);
+CREATE INDEX event_logs_fields_idx ON event_logs USING gin (fields);
@@ -0,0 +1,2 @@
+ALTER TABLE event_logs ADD COLUMN cpu_seconds double precision;
+ALTER TABLE event_logs ADD COLUMN memory_bytes bigint NOT NULL DEFAULT 0;
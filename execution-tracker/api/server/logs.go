@@ -64,9 +64,46 @@ func logsAPI() []*swagger.Endpoint {
 		endpoint.Tags("Events"),
 	)
 
+	alertLogs := endpoint.New("GET", "/alerts", "Get alert history",
+		endpoint.Handler(controllers.GetAlertLogs),
+		endpoint.QueryMap(map[string]swagger.Parameter{
+			"page": {
+				Type:        "integer",
+				Minimum:     &[]int64{1}[0],
+				Description: "Page number to return",
+			},
+			"per_page": {
+				Type:        "integer",
+				Minimum:     &[]int64{0}[0],
+				Description: "Number of records per page",
+			},
+			"function_id": {
+				Type:        "string",
+				Format:      "uuid",
+				Description: "UUID of a function",
+			},
+			"state": {
+				Type:        "string",
+				Enum:        []string{"firing", "resolved"},
+				Description: "Alert state to filter by",
+			},
+		}),
+		endpoint.Response(http.StatusOK, types.AlertLogsResponse{}, "success"),
+		endpoint.Tags("Alerts"),
+	)
+
+	firingAlerts := endpoint.New("GET", "/alerts/firing", "Get firing alerts",
+		endpoint.Description("Get the alerts of all users whose latest state is firing, operator only"),
+		endpoint.Handler(controllers.GetFiringAlerts),
+		endpoint.Response(http.StatusOK, types.FiringAlertsResponse{}, "success"),
+		endpoint.Tags("Alerts"),
+	)
+
 	return []*swagger.Endpoint{
 		timelineList,
 		timelineDetails,
 		eventLogsQuery,
+		alertLogs,
+		firingAlerts,
 	}
 }
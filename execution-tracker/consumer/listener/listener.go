@@ -66,6 +66,7 @@ func (l *Listener) Process() {
 func (l *Listener) batchInsert(batch []*stan.Msg) {
 	timelineLogs := []types.TimelineLog{}
 	eventLogs := []types.EventLog{}
+	alertLogs := []types.AlertLog{}
 
 	for _, v := range batch {
 		var msg broker.Log
@@ -89,6 +90,10 @@ func (l *Listener) batchInsert(batch []*stan.Msg) {
 				FunctionName: el.FunctionName,
 				FunctionID:   el.FunctionID,
 				Message:      el.Message,
+				Level:        el.Level,
+				Fields:       el.Fields,
+				CPUSeconds:   el.CPUSeconds,
+				MemoryBytes:  el.MemoryBytes,
 				Payload:      el.Payload,
 				IsError:      el.IsError,
 				Timestamp:    el.CreatedAt,
@@ -116,11 +121,31 @@ func (l *Listener) batchInsert(batch []*stan.Msg) {
 				Response:   tl.Response,
 				Method:     tl.Method,
 				Duration:   tl.Duration,
+				TraceID:    tl.TraceID,
 				Timestamp:  tl.CreatedAt,
 				ExpiresAt:  expiresAt,
 			}
 			timelineLogs = append(timelineLogs, logEntry)
 		}
+
+		if msg.AlertLog != nil {
+			al := msg.AlertLog
+			logEntry := types.AlertLog{
+				UserID:       msg.UserID,
+				FunctionID:   al.FunctionID,
+				FunctionName: al.FunctionName,
+				RuleName:     al.RuleName,
+				Metric:       al.Metric,
+				Threshold:    al.Threshold,
+				Value:        al.Value,
+				State:        al.State,
+				Channel:      al.Channel,
+				NotifyError:  al.NotifyError,
+				Timestamp:    al.CreatedAt,
+				ExpiresAt:    expiresAt,
+			}
+			alertLogs = append(alertLogs, logEntry)
+		}
 	}
 
 	if len(timelineLogs) > 0 {
@@ -140,4 +165,13 @@ func (l *Listener) batchInsert(batch []*stan.Msg) {
 		}
 		log.Infof("Inserted %d event logs", total)
 	}
+
+	if len(alertLogs) > 0 {
+		total, dbErr := l.db.BulkInsertAlertLogs(alertLogs)
+		if dbErr != nil {
+			log.Errorf("Error bulk inserting alert logs: %s", dbErr)
+			return
+		}
+		log.Infof("Inserted %d alert logs", total)
+	}
 }
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"github.com/lib/pq"
+	"xorm.io/builder"
+
+	"eywa/execution-tracker/types"
+)
+
+// GetAlertLogs returns the alert history ordered from the newest
+func (c *Client) GetAlertLogs(criteria types.AlertLogsQuery, pageNumber, perPage int) ([]types.AlertLog, int, error) {
+	query := c.Builder().
+		Select(`al.*`).
+		From("alert_logs al").
+		Where(builder.Eq{"al.user_id": criteria.UserID})
+
+	if criteria.FunctionID != "" {
+		query = query.And(builder.Eq{"al.function_id": criteria.FunctionID})
+	}
+	if criteria.State != "" {
+		query = query.And(builder.Eq{"al.state": criteria.State})
+	}
+	query = query.OrderBy("al.timestamp DESC")
+
+	alertLogs := []types.AlertLog{}
+	total, err := c.SelectWithCount(&alertLogs, query, pageNumber, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return alertLogs, total, nil
+}
+
+// GetFiringAlerts returns the latest alert log of every rule of all users that is still firing
+func (c *Client) GetFiringAlerts() ([]types.AlertLog, error) {
+	query := c.Builder().
+		Select(`DISTINCT ON (al.function_id, al.rule_name) al.*`).
+		From("alert_logs al").
+		OrderBy("al.function_id, al.rule_name, al.timestamp DESC")
+
+	latest := []types.AlertLog{}
+	if err := c.Select(&latest, query); err != nil {
+		return nil, err
+	}
+
+	firing := []types.AlertLog{}
+	for _, alertLog := range latest {
+		if alertLog.State == types.AlertStateFiring {
+			firing = append(firing, alertLog)
+		}
+	}
+
+	return firing, nil
+}
+
+// BulkInsertAlertLogs ...
+func (c *Client) BulkInsertAlertLogs(records []types.AlertLog) (int, error) {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmnt, err := tx.Preparex(pq.CopyIn("alert_logs",
+		"user_id", "function_id", "function_name", "rule_name", "metric",
+		"threshold", "value", "state", "channel", "notify_error", "timestamp", "expires_at"))
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, record := range records {
+		_, err = stmnt.Exec(record.UserID, record.FunctionID, record.FunctionName,
+			record.RuleName, record.Metric, record.Threshold, record.Value,
+			record.State, record.Channel, record.NotifyError, record.Timestamp,
+			record.ExpiresAt)
+		if err != nil {
+			return 0, err
+		}
+		total++
+	}
+
+	_, err = stmnt.Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	err = stmnt.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
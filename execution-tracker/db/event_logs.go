@@ -12,7 +12,10 @@ func (c *Client) GetEventLogs(criteria types.EventLogsQuery, pageNumber, perPage
 	query := c.Builder().
 		Select(`el.*`).
 		From("event_logs el")
-	query = applyEventLogFilter(query, "el", criteria)
+	query, err := applyEventLogFilter(query, "el", criteria)
+	if err != nil {
+		return nil, 0, err
+	}
 	query = query.OrderBy("el.timestamp DESC")
 
 	eventLogs := []types.EventLog{}
@@ -34,7 +37,7 @@ func (c *Client) BulkInsertEventLogs(records []types.EventLog) (int, error) {
 
 	stmnt, err := tx.Preparex(pq.CopyIn("event_logs",
 		"request_id", "user_id", "type", "function_name", "function_id",
-		"message", "payload", "is_error", "timestamp", "expires_at"))
+		"message", "level", "fields", "cpu_seconds", "memory_bytes", "payload", "is_error", "timestamp", "expires_at"))
 	if err != nil {
 		return 0, err
 	}
@@ -43,7 +46,7 @@ func (c *Client) BulkInsertEventLogs(records []types.EventLog) (int, error) {
 	for _, record := range records {
 		_, err = stmnt.Exec(record.RequestID, record.UserID,
 			record.Type, record.FunctionName, record.FunctionID,
-			record.Message, record.Payload, record.IsError, record.Timestamp,
+			record.Message, record.Level, record.Fields, record.CPUSeconds, record.MemoryBytes, record.Payload, record.IsError, record.Timestamp,
 			record.ExpiresAt)
 		if err != nil {
 			return 0, err
@@ -69,7 +72,7 @@ func (c *Client) BulkInsertEventLogs(records []types.EventLog) (int, error) {
 	return total, nil
 }
 
-func applyEventLogFilter(query *builder.Builder, name string, filter types.EventLogsQuery) *builder.Builder {
+func applyEventLogFilter(query *builder.Builder, name string, filter types.EventLogsQuery) (*builder.Builder, error) {
 
 	if filter.UserID != "" {
 		query = query.And(builder.Eq{name + ".user_id": filter.UserID})
@@ -100,9 +103,21 @@ func applyEventLogFilter(query *builder.Builder, name string, filter types.Event
 		query = query.And(builder.Eq{name + ".type": filter.Level})
 	}
 
+	if filter.LogLevel != "" {
+		query = query.And(builder.Eq{name + ".level": filter.LogLevel})
+	}
+
+	if len(filter.Fields) > 0 {
+		fields, err := types.EventLogFields(filter.Fields).Value()
+		if err != nil {
+			return nil, err
+		}
+		query = query.And(builder.Expr(name+".fields @> ?::jsonb", fields))
+	}
+
 	if filter.OnlyErrors {
 		query = query.And(builder.Eq{name + ".is_error": true})
 	}
 
-	return query
+	return query, nil
 }
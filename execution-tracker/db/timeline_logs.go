@@ -121,7 +121,7 @@ func (c *Client) BulkInsertTimelineLogs(records []types.TimelineLog) (int, error
 		"request_id", "user_id", "function_id",
 		"event_name", "event_type", "response",
 		"method", "duration", "timestamp",
-		"expires_at"))
+		"expires_at", "trace_id"))
 	if err != nil {
 		return 0, err
 	}
@@ -131,7 +131,7 @@ func (c *Client) BulkInsertTimelineLogs(records []types.TimelineLog) (int, error
 		_, err = stmnt.Exec(record.RequestID, record.UserID,
 			record.FunctionID, record.EventName, record.EventType,
 			record.Response, record.Method, record.Duration, record.Timestamp,
-			record.ExpiresAt)
+			record.ExpiresAt, record.TraceID)
 		if err != nil {
 			return 0, err
 		}
@@ -167,6 +167,7 @@ func applyTimelineLogFilter(query *builder.Builder, name string, functionID, fil
 			ILike{name + ".function_id::text", filter},
 			ILike{name + ".event_name", filter},
 			ILike{name + ".response::text", filter},
+			ILike{name + ".trace_id", filter},
 		))
 	}
 
@@ -68,6 +68,7 @@ type TimelineLog struct {
 	Response   int       `json:"response" db:"response"`
 	Method     string    `json:"method" db:"method"`
 	Duration   int64     `json:"duration" db:"duration"`
+	TraceID    string    `json:"trace_id" db:"trace_id"`
 	Timestamp  time.Time `json:"created_at" db:"timestamp"`
 	ExpiresAt  time.Time `json:"-" db:"expires_at"`
 }
@@ -98,6 +99,33 @@ func (elp *EventLogPayload) Scan(value interface{}) error {
 	return json.Unmarshal(b, &elp)
 }
 
+// EventLogFields custom type to be stored/scaned as jsonb
+type EventLogFields map[string]interface{}
+
+// Value returns marshaled fields as text accepted by a jsonb column
+func (elf EventLogFields) Value() (driver.Value, error) {
+	if elf == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(elf)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan unmarshals jsonb in postgres to map[string]interface
+func (elf *EventLogFields) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &elf)
+}
+
 // EventLog represents event log entry
 type EventLog struct {
 	ID           string          `json:"id" db:"id"`
@@ -107,6 +135,10 @@ type EventLog struct {
 	FunctionName string          `json:"function_name" db:"function_name"`
 	FunctionID   string          `json:"function_id" db:"function_id"`
 	Message      string          `json:"message" db:"message"`
+	Level        string          `json:"level" db:"level"`
+	Fields       EventLogFields  `json:"fields" db:"fields"`
+	CPUSeconds   *float64        `json:"cpu_seconds,omitempty" db:"cpu_seconds"`
+	MemoryBytes  int64           `json:"memory_bytes,omitempty" db:"memory_bytes"`
 	IsError      bool            `json:"is_error" db:"is_error"`
 	Timestamp    time.Time       `json:"created_at" db:"timestamp"`
 	ExpiresAt    time.Time       `json:"-" db:"expires_at"`
@@ -130,6 +162,7 @@ type TimelineBrief struct {
 	Age          time.Time `json:"age"`
 	Duration     int64     `json:"duration"`
 	IsError      bool      `json:"is_error"`
+	TraceID      string    `json:"trace_id"`
 }
 
 // TimelineDetails returns details about the timeline
@@ -139,6 +172,7 @@ type TimelineDetails struct {
 	Response  int            `json:"response"`
 	Duration  int64          `json:"duration"`
 	Age       time.Time      `json:"age"`
+	TraceID   string         `json:"trace_id"`
 	Events    []EventDetails `json:"events"`
 }
 
@@ -153,12 +187,55 @@ type EventDetails struct {
 
 // EventLogsQuery API request payload for filtering search
 type EventLogsQuery struct {
-	UserID       string    `json:"-"`
-	FunctionID   string    `json:"function_id"`
-	RequestID    string    `json:"request_id"`
-	Query        string    `json:"query"`
-	OnlyErrors   bool      `json:"only_errors"`
-	Level        string    `json:"level" enum:"all,user,system"`
-	TimestampMin time.Time `json:"timestamp_min,omitempty"`
-	TimestampMax time.Time `json:"timestamp_max,omitempty"`
+	UserID     string `json:"-"`
+	FunctionID string `json:"function_id"`
+	RequestID  string `json:"request_id"`
+	Query      string `json:"query"`
+	OnlyErrors bool   `json:"only_errors"`
+	// Level filters by event type, LogLevel by the level of structured function logs
+	Level        string                 `json:"level" enum:"all,user,system"`
+	LogLevel     string                 `json:"log_level" enum:"debug,info,warn,error,fatal"`
+	Fields       map[string]interface{} `json:"fields"`
+	TimestampMin time.Time              `json:"timestamp_min,omitempty"`
+	TimestampMax time.Time              `json:"timestamp_max,omitempty"`
+}
+
+// AlertLog represents a firing or resolved alert of a function
+type AlertLog struct {
+	ID           string    `json:"id" db:"id"`
+	UserID       string    `json:"-" db:"user_id"`
+	FunctionID   string    `json:"function_id" db:"function_id"`
+	FunctionName string    `json:"function_name" db:"function_name"`
+	RuleName     string    `json:"rule_name" db:"rule_name"`
+	Metric       string    `json:"metric" db:"metric"`
+	Threshold    float64   `json:"threshold" db:"threshold"`
+	Value        float64   `json:"value" db:"value"`
+	State        string    `json:"state" db:"state"`
+	Channel      string    `json:"channel" db:"channel"`
+	NotifyError  string    `json:"notify_error" db:"notify_error"`
+	Timestamp    time.Time `json:"created_at" db:"timestamp"`
+	ExpiresAt    time.Time `json:"-" db:"expires_at"`
+}
+
+// AlertStateFiring is the state of an alert log of a rule that was breached
+const AlertStateFiring = "firing"
+
+// AlertLogsQuery filters alert history
+type AlertLogsQuery struct {
+	UserID     string
+	FunctionID string
+	State      string
+}
+
+// AlertLogsResponse represents response when multiple alerts are returned
+type AlertLogsResponse struct {
+	Page       int        `json:"page"`
+	PerPage    int        `json:"per_page"`
+	TotalCount int        `json:"total_count"`
+	Objects    []AlertLog `json:"objects"`
+}
+
+// FiringAlertsResponse represents the alerts that are firing
+type FiringAlertsResponse struct {
+	Objects []AlertLog `json:"objects"`
 }
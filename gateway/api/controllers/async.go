@@ -7,12 +7,14 @@ import (
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 
 	ett "eywa/execution-tracker/types"
 	"eywa/gateway/clients/k8s"
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
 	"eywa/go-libs/broker"
+	"eywa/go-libs/tracing"
 	"eywa/go-libs/trigger"
 )
 
@@ -46,7 +48,16 @@ func AsyncInvocation(c echo.Context) error {
 		functionName = val
 	}
 
+	// Affinity can be keyed on a cookie which does not survive header stripping
+	affinityKey := fs.LoadBalancing.AffinityKey(c.Request())
+
 	stripHeaders(c.Request().Header)
+
+	// The consumer continues the trace from the headers carried by the queued request
+	ctx, span := tracing.Start(c.Request().Context(), "publish", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+	tracing.Inject(ctx, c.Request().Header)
+
 	requestID := c.Request().Header.Get("X-Request-Id")
 	payload := broker.QueueRequestMessage{
 		Payload: broker.QueueRequest{
@@ -59,6 +70,7 @@ func AsyncInvocation(c echo.Context) error {
 			FunctionID:   fs.Name,
 			FunctionName: functionName,
 			CallbackURL:  c.Request().Header.Get("X-Callback-Url"),
+			AffinityKey:  affinityKey,
 			QueuedAt:     time.Now(),
 		},
 	}
@@ -68,6 +80,7 @@ func AsyncInvocation(c echo.Context) error {
 	eventMessage := types.QueuedMessage(requestID, functionID, functionName)
 	if err := bc.ProduceAsync(types.AsyncExecSubject, payload); err != nil {
 		log.Errorf("Failed to produce queue request: %s", err)
+		tracing.RecordError(span, err)
 		status = http.StatusServiceUnavailable
 		eventType = ett.TimelineEventTypeFailed
 		eventMessage = types.ServerErrorMessage()
@@ -80,6 +93,7 @@ func AsyncInvocation(c echo.Context) error {
 		"event_name":  functionName,
 		"event_type":  eventType,
 		"response":    status,
+		"trace_id":    tracing.TraceID(ctx),
 	}).Fire(types.TimelineHookType)
 
 	trigger.WithFields(trigger.Fields{
@@ -15,6 +15,13 @@ import (
 	"eywa/gateway/clients/registry"
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
+	"eywa/go-libs/semver"
+	rt "eywa/registry/types"
+)
+
+const (
+	maxAlertRules      = 10
+	defaultAlertWindow = "5m"
 )
 
 // GetFunctions returns list of functions scoped to the user
@@ -147,6 +154,19 @@ func DeployFunction(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, "Image Not Found")
 	}
 
+	violations, err := deployViolations(rc, image, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get image vulnerabilities from registry: %s", err)
+		return err
+	}
+
+	if len(violations) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "Image can not be deployed",
+			"details": violations,
+		})
+	}
+
 	parseEnvVars(&dr.FunctionRequest)
 
 	fr := &k8s.DeployFunctionRequest{
@@ -157,6 +177,9 @@ func DeployFunction(c echo.Context) error {
 		MinReplicas:   dr.MinReplicas,
 		MaxReplicas:   dr.MaxReplicas,
 		ScalingFactor: dr.ScalingFactor,
+		LoadBalancing: makeLoadBalancing(dr.LoadBalancing),
+		Alerts:        makeAlertRules(dr.Alerts),
+		AutoDeploy:    makeAutoDeploy(dr.AutoDeploy),
 		Labels: map[string]string{
 			types.UserIDLabel:          auth.UserID,
 			types.ImageIDLabel:         image.ID,
@@ -236,7 +259,21 @@ func UpdateFunction(c echo.Context) error {
 	if image == nil {
 		return c.JSON(http.StatusNotFound, "Image Not Found")
 	}
+
+	violations, err := deployViolations(rc, image, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get image vulnerabilities from registry: %s", err)
+		return err
+	}
+
+	if len(violations) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "Image can not be deployed",
+			"details": violations,
+		})
+	}
 	fs.Labels["image_id"] = image.ID
+	fs.Labels[types.ImageNameLabel] = image.Name
 
 	parseEnvVars(&ur.FunctionRequest)
 
@@ -248,6 +285,10 @@ func UpdateFunction(c echo.Context) error {
 		MinReplicas:   ur.MinReplicas,
 		MaxReplicas:   ur.MaxReplicas,
 		ScalingFactor: ur.ScalingFactor,
+		LoadBalancing: makeLoadBalancing(ur.LoadBalancing),
+		Alerts:        makeAlertRules(ur.Alerts),
+		AutoDeploy:    makeAutoDeploy(ur.AutoDeploy),
+		Rollouts:      fs.Rollouts,
 		Labels:        fs.Labels,
 	}
 
@@ -289,6 +330,29 @@ func DeleteFunction(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// deployViolations returns why the image can not be deployed, none when it can. Only images
+// that were built successfully and comply with the vulnerability policy of the registry can.
+func deployViolations(rc *registry.Client, image *rt.Image, userID string) ([]string, error) {
+	if image.State != rt.ImageStateSuccess {
+		return []string{fmt.Sprintf("Image is in state %s, only successfully built images can be deployed", image.State)}, nil
+	}
+
+	vulnerabilities, err := rc.GetImageVulnerabilities(image.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if vulnerabilities == nil {
+		return []string{"Image vulnerabilities are unavailable"}, nil
+	}
+
+	if vulnerabilities.Deployable {
+		return nil, nil
+	}
+
+	return vulnerabilities.Violations, nil
+}
+
 func buildK8sName(name, userID string) string {
 	userUUID := uuid.FromStringOrNil(userID)
 	functionID := uuid.NewV5(userUUID, name).String()
@@ -344,9 +408,89 @@ func validateK8sParams(dr *types.FunctionRequest, l *k8s.ResourceLimits) map[str
 		errors["max_replicas"] = append(errors["max_replicas"], "value must be at least equal to min_replicas")
 	}
 
+	if lb := dr.LoadBalancing; lb != nil && lb.Strategy == string(k8s.ConsistentHash) {
+		if lb.HashHeader == "" && lb.HashCookie == "" {
+			errors["load_balancing"] = append(errors["load_balancing"], "hash_header or hash_cookie is required for consistent_hash")
+		}
+	}
+
+	if len(dr.Alerts) > maxAlertRules {
+		errors["alerts"] = append(errors["alerts"], fmt.Sprintf("at most %d alert rules are allowed", maxAlertRules))
+	}
+
+	alertNames := map[string]struct{}{}
+	for _, rule := range dr.Alerts {
+		if _, exists := alertNames[rule.Name]; exists {
+			errors["alerts"] = append(errors["alerts"], fmt.Sprintf("alert rule name %q is not unique", rule.Name))
+		}
+		alertNames[rule.Name] = struct{}{}
+
+		if rule.Channel.Type == types.AlertChannelWebhook && rule.Channel.URL == "" {
+			errors["alerts"] = append(errors["alerts"], fmt.Sprintf("alert rule %q: url is required for webhook channel", rule.Name))
+		}
+
+		if rule.Channel.Type == types.AlertChannelEmail && rule.Channel.Email == "" {
+			errors["alerts"] = append(errors["alerts"], fmt.Sprintf("alert rule %q: email is required for email channel", rule.Name))
+		}
+
+		if rule.Metric == types.AlertMetricErrorRate && rule.Threshold > 100 {
+			errors["alerts"] = append(errors["alerts"], fmt.Sprintf("alert rule %q: error_rate threshold is a percentage", rule.Name))
+		}
+	}
+
+	if dr.AutoDeploy != nil {
+		if _, err := semver.ParseRange(dr.AutoDeploy.VersionRange); err != nil {
+			errors["auto_deploy"] = append(errors["auto_deploy"], err.Error())
+		}
+	}
+
 	return errors
 }
 
+func makeAlertRules(rules []types.AlertRule) []k8s.AlertRule {
+	alerts := []k8s.AlertRule{}
+	for _, rule := range rules {
+		window := rule.Window
+		if window == "" {
+			window = defaultAlertWindow
+		}
+
+		alerts = append(alerts, k8s.AlertRule{
+			Name:      rule.Name,
+			Metric:    rule.Metric,
+			Threshold: rule.Threshold,
+			Window:    window,
+			Channel: k8s.AlertChannel{
+				Type:  rule.Channel.Type,
+				URL:   rule.Channel.URL,
+				Email: rule.Channel.Email,
+			},
+		})
+	}
+
+	return alerts
+}
+
+func makeAutoDeploy(ad *types.AutoDeploy) *k8s.AutoDeploy {
+	if ad == nil {
+		return nil
+	}
+
+	return &k8s.AutoDeploy{VersionRange: ad.VersionRange}
+}
+
+func makeLoadBalancing(lb *types.LoadBalancing) *k8s.LoadBalancing {
+	if lb == nil {
+		return nil
+	}
+
+	return &k8s.LoadBalancing{
+		Strategy:   k8s.BalancingStrategy(lb.Strategy),
+		HashHeader: lb.HashHeader,
+		HashCookie: lb.HashCookie,
+	}
+}
+
 func gt(a, b string) bool {
 	return cmpLimitStr(a, b)
 }
@@ -377,6 +521,44 @@ func makeFunctionStatusResponse(fs *k8s.FunctionStatus, secrets []k8s.Secret) (r
 		DeletedAt:         fs.DeletedAt,
 	}
 
+	if fs.LoadBalancing != nil {
+		r.LoadBalancing = &types.LoadBalancing{
+			Strategy:   string(fs.LoadBalancing.Strategy),
+			HashHeader: fs.LoadBalancing.HashHeader,
+			HashCookie: fs.LoadBalancing.HashCookie,
+		}
+	}
+
+	r.Alerts = []types.AlertRule{}
+	for _, rule := range fs.Alerts {
+		r.Alerts = append(r.Alerts, types.AlertRule{
+			Name:      rule.Name,
+			Metric:    rule.Metric,
+			Threshold: rule.Threshold,
+			Window:    rule.Window,
+			Channel: types.AlertChannel{
+				Type:  rule.Channel.Type,
+				URL:   rule.Channel.URL,
+				Email: rule.Channel.Email,
+			},
+		})
+	}
+
+	if fs.AutoDeploy != nil {
+		r.AutoDeploy = &types.AutoDeploy{VersionRange: fs.AutoDeploy.VersionRange}
+	}
+
+	r.Rollouts = []types.Rollout{}
+	for _, rollout := range fs.Rollouts {
+		r.Rollouts = append(r.Rollouts, types.Rollout{
+			ImageID:     rollout.ImageID,
+			Version:     rollout.Version,
+			FromImageID: rollout.FromImageID,
+			FromVersion: rollout.FromVersion,
+			RolledOutAt: rollout.RolledOutAt,
+		})
+	}
+
 	for _, secret := range secrets {
 		r.Secrets = append(r.Secrets, makeSecretResponse(&secret, nil))
 	}
@@ -385,6 +567,8 @@ func makeFunctionStatusResponse(fs *k8s.FunctionStatus, secrets []k8s.Secret) (r
 		switch k {
 		case types.FunctionIDLabel:
 			r.ID = v
+		case types.UserIDLabel:
+			r.UserID = v
 		case types.ImageIDLabel:
 			r.ImageID = v
 		case types.ImageNameLabel:
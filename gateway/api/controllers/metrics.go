@@ -4,22 +4,30 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 
+	"eywa/gateway/clients/k8s"
 	"eywa/gateway/metrics"
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
 )
 
-// QueryMetrics returns prometheus metrics
+// QueryMetrics returns prometheus metrics of a raw query, the query is not scoped to a user
+// so only operators can run it. Users get their metrics from GetMetrics and GetFunctionMetrics.
 func QueryMetrics(c echo.Context) error {
 	auth := c.Get("auth").(*auth.Auth)
 	mc := c.Get("metrics").(*metrics.Client)
 
+	if !auth.IsOperator() {
+		return c.JSON(http.StatusForbidden, "Forbidden")
+	}
+
 	var qr types.QueryRequest
 	if err := c.Bind(&qr); err != nil {
 		return err
@@ -51,18 +59,6 @@ func QueryMetrics(c echo.Context) error {
 		})
 	}
 
-	if strings.Contains(qr.LabelMatchers, "user_id") {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"message": "Validation error",
-			"details": map[string]string{
-				"label_matchers": "user_id not allowed as label matcher",
-			},
-		})
-	}
-
-	// Scope to user only
-	qr.LabelMatchers += fmt.Sprintf(`,user_id="%s"`, auth.UserID)
-
 	tmpl, err := template.New("test").Delims("<<", ">>").Parse(qr.Query)
 	if err != nil {
 		log.Errorf("Failed to parse metrics template: %s", err)
@@ -89,7 +85,6 @@ func QueryMetrics(c echo.Context) error {
 		}
 	}
 
-	fmt.Println(query)
 	result, qErr := mc.QueryMetrics(endpoint, query)
 	if qErr != nil {
 		if qErr.Type == types.ErrTypeBadRequest {
@@ -102,3 +97,154 @@ func QueryMetrics(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, result)
 }
+
+const (
+	defaultMetricsRange = time.Hour
+	defaultMetricsStep  = time.Minute
+	maxMetricsRange     = 30 * 24 * time.Hour
+	// Prometheus refuses range queries resolving to more points than this
+	maxMetricsPoints = 11000
+)
+
+// GetMetrics returns curated metrics series of all the functions of the user
+func GetMetrics(c echo.Context) error {
+	auth := c.Get("auth").(*auth.Auth)
+	mc := c.Get("metrics").(*metrics.Client)
+
+	series, start, end, step, validationErrors := parseMetricsParams(c)
+	if len(validationErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "Validation error",
+			"details": validationErrors,
+		})
+	}
+
+	result, qErr := mc.FunctionSeries(auth.UserID, "", series, start, end, step)
+	if qErr != nil {
+		if qErr.Type == types.ErrTypeBadRequest {
+			return echo.NewHTTPError(http.StatusBadRequest, qErr.String())
+		}
+
+		log.Errorf("Failed to get metrics from prometheus: %s", qErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, types.FunctionMetricsResponse{
+		Start:  start,
+		End:    end,
+		Step:   int64(step.Seconds()),
+		Series: result,
+	})
+}
+
+// GetFunctionMetrics returns curated metrics series of a function
+func GetFunctionMetrics(c echo.Context) error {
+	auth := c.Get("auth").(*auth.Auth)
+	k8sClient := c.Get("k8s").(*k8s.Client)
+	mc := c.Get("metrics").(*metrics.Client)
+	functionID := c.Param("function_id")
+
+	series, start, end, step, validationErrors := parseMetricsParams(c)
+	if len(validationErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"message": "Validation error",
+			"details": validationErrors,
+		})
+	}
+
+	filter := k8s.LabelSelector().
+		Equals(types.FunctionIDLabel, functionID).
+		Equals(types.UserIDLabel, auth.UserID)
+	fs, err := k8sClient.GetFunctionStatusFiltered(filter)
+	if err != nil {
+		log.Errorf("Failed to get functions from k8s: %s", err)
+		return err
+	}
+
+	if fs == nil {
+		return c.JSON(http.StatusNotFound, "Function Not Found")
+	}
+
+	result, qErr := mc.FunctionSeries(auth.UserID, functionID, series, start, end, step)
+	if qErr != nil {
+		if qErr.Type == types.ErrTypeBadRequest {
+			return echo.NewHTTPError(http.StatusBadRequest, qErr.String())
+		}
+
+		log.Errorf("Failed to get function metrics from prometheus: %s", qErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, types.FunctionMetricsResponse{
+		FunctionID: functionID,
+		Start:      start,
+		End:        end,
+		Step:       int64(step.Seconds()),
+		Series:     result,
+	})
+}
+
+// parseMetricsParams parses the series, range, step and end query params of a curated metrics request
+func parseMetricsParams(c echo.Context) ([]string, time.Time, time.Time, time.Duration, map[string][]string) {
+	validationErrors := map[string][]string{}
+
+	series := metrics.FunctionSeriesNames()
+	if val := c.QueryParam("series"); val != "" {
+		series = strings.Split(val, ",")
+	}
+
+	for _, name := range series {
+		if !metrics.IsFunctionSeries(name) {
+			validationErrors["series"] = append(validationErrors["series"], fmt.Sprintf("unknown series %q", name))
+		}
+	}
+
+	queryRange, err := parseMetricsDuration(c.QueryParam("range"), defaultMetricsRange)
+	if err != nil || queryRange <= 0 {
+		validationErrors["range"] = append(validationErrors["range"], "must be a positive duration, e.g. 1h")
+	} else if queryRange > maxMetricsRange {
+		validationErrors["range"] = append(validationErrors["range"], fmt.Sprintf("must not exceed %s", maxMetricsRange))
+	}
+
+	step, err := parseMetricsDuration(c.QueryParam("step"), defaultMetricsStep)
+	if err != nil || step < time.Second {
+		validationErrors["step"] = append(validationErrors["step"], "must be a duration of at least 1s, e.g. 1m")
+	} else if queryRange > 0 && int64(queryRange/step) > maxMetricsPoints {
+		validationErrors["step"] = append(validationErrors["step"], fmt.Sprintf("must yield at most %d points for the range", maxMetricsPoints))
+	}
+
+	// without an explicit end the points are aligned to the step so that
+	// refreshing dashboards keep hitting the same evaluation timestamps
+	end := time.Now().Truncate(step)
+	if val := c.QueryParam("end"); val != "" {
+		seconds, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || seconds <= 0 {
+			validationErrors["end"] = append(validationErrors["end"], "must be a unix timestamp in seconds")
+		} else {
+			end = time.Unix(seconds, 0)
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return nil, time.Time{}, time.Time{}, 0, validationErrors
+	}
+
+	return series, end.Add(-queryRange), end, step, nil
+}
+
+// parseMetricsDuration parses durations the way prometheus does for the units we allow (s, m, h, d)
+func parseMetricsDuration(val string, fallback time.Duration) (time.Duration, error) {
+	if val == "" {
+		return fallback, nil
+	}
+
+	if strings.HasSuffix(val, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(val, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(val)
+}
@@ -8,17 +8,23 @@ import (
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/resty.v1"
 
 	ett "eywa/execution-tracker/types"
 	"eywa/gateway/clients/k8s"
+	"eywa/gateway/hooks"
 	"eywa/gateway/metrics"
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
+	"eywa/go-libs/tracing"
 	"eywa/go-libs/trigger"
 	wet "eywa/watchdog/executor"
 )
 
+// maxProxyAttempts is the number of endpoints tried when function replicas are at their concurrency limit
+const maxProxyAttempts = 3
+
 // Proxy proxies requests from outside to the corret internal pod
 func Proxy(c echo.Context) error {
 	switch c.Request().Method {
@@ -37,16 +43,22 @@ func Proxy(c echo.Context) error {
 func proxyRequest(c echo.Context) error {
 	auth := c.Get("auth").(*auth.Auth)
 	proxyClient := c.Get("proxy").(*resty.Client)
-	k8s := c.Get("k8s").(*k8s.Client)
-	metrics := c.Get("metrics").(*metrics.Client)
+	k8sClient := c.Get("k8s").(*k8s.Client)
+	mc := c.Get("metrics").(*metrics.Client)
 	functionName := c.Get("function_name").(string)
+	loadBalancing, _ := c.Get("load_balancing").(*k8s.LoadBalancing)
 
 	functionID := c.Param("function_id")
 	if functionID == "" {
 		return c.JSON(http.StatusBadRequest, "Missing function id")
 	}
 
+	ctx, span := tracing.Start(c.Request().Context(), "proxy")
+	defer span.End()
+
 	requestID := c.Request().Header.Get("X-Request-ID")
+	span.SetAttributes(attribute.String("request_id", requestID))
+
 	defaultEventFields := trigger.Fields{
 		"user_id":       auth.UserID,
 		"request_id":    requestID,
@@ -60,6 +72,7 @@ func proxyRequest(c echo.Context) error {
 		"request_id":  requestID,
 		"function_id": functionID,
 		"method":      c.Request().Method,
+		"trace_id":    tracing.TraceID(ctx),
 	}
 
 	requestBody, err := ioutil.ReadAll(c.Request().Body)
@@ -67,6 +80,9 @@ func proxyRequest(c echo.Context) error {
 		return err
 	}
 
+	// Affinity can be keyed on a cookie which does not survive header stripping
+	affinityKey := loadBalancing.AffinityKey(c.Request())
+
 	stripHeaders(c.Request().Header)
 	path := "/" + c.Param("*")
 	trigger.WithFields(defaultEventFields).WithFields(trigger.Fields{
@@ -77,46 +93,86 @@ func proxyRequest(c echo.Context) error {
 		"message":      types.SyncExecutionStartMessage(functionName),
 	}).Fire(types.EventHookType)
 
-	metrics.ObserveInvocationStarted(functionID, functionName,
+	mc.ObserveInvocationStarted(functionID, functionName,
 		auth.UserID, path, c.Request().Method)
 
 	fullChainStart := time.Now()
 
-	functionAddr, err := k8s.Resolve(functionID)
-	if err != nil {
-		log.Errorf("k8s error: cannot find %s: %s\n", functionID, err)
-
-		trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
-			"event_name": functionName,
-			"event_type": ett.TimelineEventTypeSystemError,
-			"response":   http.StatusServiceUnavailable,
-			"duration":   time.Since(fullChainStart).Milliseconds(),
-		}).Fire(types.TimelineHookType)
-
-		trigger.WithFields(defaultEventFields).WithFields(trigger.Fields{
-			"is_error": true,
-			"message":  types.ServerErrorMessage(),
-		}).Fire(types.EventHookType)
-
-		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	resolveRequest := &k8s.ResolveRequest{
+		LoadBalancing: loadBalancing,
+		AffinityKey:   affinityKey,
 	}
 
-	url := fmt.Sprintf("%s%s", functionAddr, path)
-	proxyRequest := proxyClient.R().SetQueryString(c.QueryString())
-
-	if len(requestBody) > 0 {
-		proxyRequest.SetBody(requestBody)
+	var url string
+	var result *wet.FunctionResponse
+	var response *resty.Response
+	var proxyErr error
+	var proxyStart time.Time
+	for attempt := 1; attempt <= maxProxyAttempts; attempt++ {
+		endpoint, err := k8sClient.Resolve(ctx, functionID, resolveRequest)
+		if err != nil && response != nil {
+			// Every remaining endpoint rejected the request, report the last rejection
+			break
+		}
+
+		if err != nil {
+			log.Errorf("k8s error: cannot find %s: %s\n", functionID, err)
+			mc.ObserveResolveFailure(functionID, functionName, auth.UserID)
+
+			trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
+				"event_name": functionName,
+				"event_type": ett.TimelineEventTypeSystemError,
+				"response":   http.StatusServiceUnavailable,
+				"duration":   time.Since(fullChainStart).Milliseconds(),
+			}).Fire(types.TimelineHookType)
+
+			trigger.WithFields(defaultEventFields).WithFields(trigger.Fields{
+				"is_error": true,
+				"message":  types.ServerErrorMessage(),
+			}).Fire(types.EventHookType)
+
+			return echo.NewHTTPError(http.StatusServiceUnavailable)
+		}
+
+		url = fmt.Sprintf("%s%s", endpoint.URL, path)
+		proxyRequest := proxyClient.R().SetQueryString(c.QueryString())
+
+		if len(requestBody) > 0 {
+			proxyRequest.SetBody(requestBody)
+		}
+
+		copyHeaders(proxyRequest.Header, &c.Request().Header)
+		tracing.Inject(ctx, proxyRequest.Header)
+
+		proxyStart = time.Now()
+		response, proxyErr = proxyRequest.Execute(c.Request().Method, url)
+		endpoint.Release()
+
+		if proxyErr == nil {
+			result, proxyErr = wet.DecodeFunctionResponse(response.RawResponse, response.Body())
+		}
+
+		if proxyErr != nil || result.Error != wet.ErrorConcurrencyLimit {
+			break
+		}
+
+		log.Debugf("Function %s endpoint %s is at its concurrency limit, retrying", functionID, endpoint.Address)
+		resolveRequest.Exclude = append(resolveRequest.Exclude, endpoint.Address)
 	}
 
-	copyHeaders(proxyRequest.Header, &c.Request().Header)
+	if proxyErr != nil || result.Error != "" {
+		log.Errorf("Error with proxy request to: %s, %s\n", url, proxyErr)
+		if proxyErr != nil {
+			tracing.RecordError(span, proxyErr)
+		} else {
+			tracing.RecordError(span, fmt.Errorf("watchdog responded with %d: %s", result.Status, result.Error))
+		}
 
-	proxyStart := time.Now()
-	var result wet.FunctionResponse
-	response, err := proxyRequest.
-		SetResult(&result).
-		Execute(c.Request().Method, url)
-	if err != nil || response.IsError() {
-		log.Errorf("Error with proxy request to: %s, %s\n", proxyRequest.URL, err)
+		statusCode := 0
+		if response != nil {
+			statusCode = response.StatusCode()
+		}
+		mc.ObserveProxyError(functionID, functionName, auth.UserID, metrics.ProxyErrorCause(proxyErr, statusCode))
 
 		trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
 			"event_name": functionName,
@@ -134,9 +190,14 @@ func proxyRequest(c echo.Context) error {
 	}
 
 	proxyFinish := time.Since(proxyStart)
+	span.SetAttributes(attribute.Int("http.status_code", result.Status))
 	log.Infof("%s took %f seconds\n", functionID, proxyFinish.Seconds())
 
-	metrics.ObserveInvocationComplete(functionID, functionName, auth.UserID, path, result.Status, proxyFinish)
+	mc.ObserveInvocationComplete(functionID, functionName, auth.UserID, path, result.Status, proxyFinish)
+	mc.ObserveTransferredBytes(functionID, functionName, auth.UserID, len(requestBody), len(result.Body))
+	if result.Usage != nil {
+		mc.ObserveResourceUsage(functionID, functionName, auth.UserID, result.Usage.CPUSeconds, result.Usage.MemoryBytes)
+	}
 
 	eventType := ett.TimelineEventTypeFinished
 	if result.Status >= 400 {
@@ -169,7 +230,11 @@ func proxyRequest(c echo.Context) error {
 		"stdout":   result.Stdout,
 		"stderr":   result.Stderr,
 		"message":  types.SyncExecutionFinishMessage(functionName, result.Status, proxyFinish),
-	}).Fire(types.EventHookType)
+	}).WithFields(hooks.UsageFields(result.Usage)).Fire(types.EventHookType)
+
+	for _, entry := range result.Logs {
+		trigger.WithFields(defaultEventFields).WithFields(hooks.FunctionLogFields(entry)).Fire(types.EventHookType)
+	}
 
 	return copyResponse(c, result.Status, result.Headers, result.Body)
 }
@@ -3,15 +3,21 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 
 	"eywa/gateway/clients/k8s"
+	"eywa/gateway/clients/registry"
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
+	"eywa/go-libs/semver"
 )
 
+// maxRollouts is the number of past rollouts kept with a function
+const maxRollouts = 10
+
 // SystemGetFunctions returns list of functions
 func SystemGetFunctions(c echo.Context) error {
 	auth := c.Get("auth").(*auth.Auth)
@@ -72,3 +78,156 @@ func SystemScaleFunction(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// SystemRolloutImage rolls the functions of the image owner tracking the image name forward to a
+// newly built image version, functions only move to versions within their auto deploy range and
+// above the version they run. A function failing to roll out does not stop the others, the
+// failures are returned along with the rolled out functions.
+func SystemRolloutImage(c echo.Context) error {
+	auth := c.Get("auth").(*auth.Auth)
+	k8sClient := c.Get("k8s").(*k8s.Client)
+	rc := c.Get("registry").(*registry.Client)
+	imageID := c.Param("image_id")
+
+	if !auth.IsOperator() {
+		return c.JSON(http.StatusForbidden, "Forbidden")
+	}
+
+	image, err := rc.GetImage(imageID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get image from registry: %s", err)
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if image == nil {
+		return c.JSON(http.StatusNotFound, "Image Not Found")
+	}
+
+	version, err := semver.Parse(image.Version)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Image version is not a semantic version")
+	}
+
+	violations, err := deployViolations(rc, image, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get image vulnerabilities from registry: %s", err)
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	// Functions keep running their current image
+	if len(violations) > 0 {
+		log.WithFields(log.Fields{
+			"image_id":   image.ID,
+			"version":    image.Version,
+			"violations": violations,
+		}).Info("Not rolling out image that can not be deployed")
+
+		return c.JSON(http.StatusOK, types.RolloutResponse{
+			Objects: []types.FunctionStatusResponse{},
+			Total:   0,
+			Errors:  []types.RolloutError{},
+		})
+	}
+
+	filter := k8s.LabelSelector().
+		Equals(types.UserIDLabel, auth.UserID).
+		Equals(types.ImageNameLabel, image.Name)
+	fss, err := k8sClient.GetFunctionsStatusFiltered(filter)
+	if err != nil {
+		log.Errorf("Failed to get functions from k8s: %s", err)
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	sfss := []types.FunctionStatusResponse{}
+	rolloutErrors := []types.RolloutError{}
+	failed := func(fs k8s.FunctionStatus, message string) {
+		rolloutErrors = append(rolloutErrors, types.RolloutError{
+			FunctionID:   fs.Labels[types.FunctionIDLabel],
+			FunctionName: fs.Labels[types.UserDefinedNameLabel],
+			Error:        message,
+		})
+	}
+
+	for _, fs := range fss {
+		if fs.AutoDeploy == nil || fs.DeletedAt != nil || fs.Labels[types.ImageIDLabel] == image.ID {
+			continue
+		}
+
+		versionRange, err := semver.ParseRange(fs.AutoDeploy.VersionRange)
+		if err != nil {
+			log.Errorf("Function %q has invalid version range: %s", fs.Name, err)
+			failed(fs, "Invalid auto deploy version range")
+			continue
+		}
+
+		if !versionRange.Contains(version) {
+			continue
+		}
+
+		rollout := k8s.Rollout{
+			ImageID:     image.ID,
+			Version:     image.Version,
+			FromImageID: fs.Labels[types.ImageIDLabel],
+			RolledOutAt: time.Now(),
+		}
+
+		current, err := rc.GetImage(rollout.FromImageID, auth.UserID)
+		if err != nil {
+			log.Errorf("Failed to get image from registry: %s", err)
+			failed(fs, "Failed to get the current image")
+			continue
+		}
+
+		// Never roll back, images of older versions can finish building after newer ones
+		if current != nil {
+			rollout.FromVersion = current.Version
+			if currentVersion, err := semver.Parse(current.Version); err == nil && currentVersion.Compare(version) >= 0 {
+				continue
+			}
+		}
+
+		var secrets []k8s.Secret
+		if len(fs.MountedSecrets) > 0 {
+			filter = k8s.LabelSelector().
+				In(types.SecretNameLabel, fs.MountedSecrets).
+				Equals(types.UserIDLabel, auth.UserID)
+			secrets, err = k8sClient.GetSecretsFiltered(filter)
+			if err != nil {
+				log.Errorf("Failed to get secrets from k8s: %s", err)
+				failed(fs, "Failed to get the mounted secrets")
+				continue
+			}
+		}
+
+		fr := fs.DeployRequest(secrets)
+		fr.Image = image.TaggedRegistry
+		fr.Labels[types.ImageIDLabel] = image.ID
+		fr.Rollouts = append(fr.Rollouts, rollout)
+		if len(fr.Rollouts) > maxRollouts {
+			fr.Rollouts = fr.Rollouts[len(fr.Rollouts)-maxRollouts:]
+		}
+
+		updated, err := k8sClient.UpdateFunction(fs.Name, fr)
+		if err != nil {
+			log.Errorf("Failed to roll out function %q: %s", fs.Name, err)
+			failed(fs, "Failed to update the function")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"function_id":   fs.Labels[types.FunctionIDLabel],
+			"image_id":      rollout.ImageID,
+			"version":       rollout.Version,
+			"from_image_id": rollout.FromImageID,
+			"from_version":  rollout.FromVersion,
+		}).Info("Rolled out function")
+
+		sfss = append(sfss, makeFunctionStatusResponse(updated, secrets))
+	}
+
+	return c.JSON(http.StatusOK, types.RolloutResponse{
+		Objects: sfss,
+		Total:   len(sfss),
+		Errors:  rolloutErrors,
+	})
+}
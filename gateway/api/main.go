@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"eywa/gateway/metrics"
 	"eywa/gateway/types"
 	"eywa/go-libs/broker"
+	"eywa/go-libs/tracing"
 	"eywa/go-libs/trigger"
 )
 
@@ -31,6 +33,8 @@ type Config struct {
 	LimitMemMin         string        `envconfig:"limit_mem_min" default:"20Mi"`
 	LimitMemMax         string        `envconfig:"limit_mem_max" default:"500Mi"`
 	MongoDBHost         string        `envconfig:"mongodb_host" default:"mongodb.mongodb:27017"`
+	OTLPEndpoint        string        `envconfig:"otlp_endpoint"`
+	TraceSampleRatio    float64       `envconfig:"trace_sample_ratio" default:"1"`
 }
 
 func main() {
@@ -49,9 +53,20 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	shutdownTracing, err := tracing.Setup(&tracing.Config{
+		ServiceName: "gateway",
+		Endpoint:    conf.OTLPEndpoint,
+		Insecure:    true,
+		SampleRatio: conf.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup tracing: %s", err)
+	}
+
 	k8s, err := k8s.Setup(&k8s.Config{
 		InCluster:           *inCluster,
 		MongoDBHost:         conf.MongoDBHost,
+		OTLPEndpoint:        conf.OTLPEndpoint,
 		CacheExpiryDuration: conf.CacheExpiryDuration,
 		LimitCPUMin:         conf.LimitCPUMin,
 		LimitCPUMax:         conf.LimitCPUMax,
@@ -94,5 +109,9 @@ func main() {
 
 	server.Run(params)
 
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Errorf("Failed to flush traces: %s", err)
+	}
+
 	log.Exit(0)
 }
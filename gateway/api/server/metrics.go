@@ -12,14 +12,58 @@ import (
 
 func metricsAPI() []*swagger.Endpoint {
 	getMetrics := endpoint.New("POST", "/metrics/query", "Query metrics",
-		endpoint.Description("Query prometheus metrics"),
+		endpoint.Description("Query prometheus metrics with a raw query, operator only"),
 		endpoint.Handler(controllers.QueryMetrics),
 		endpoint.Body(types.QueryRequest{}, "Query payload", true),
 		endpoint.Response(http.StatusOK, types.QueryResponse{}, "Success"),
 		endpoint.Tags("Metrics"),
 	)
 
+	getFunctionMetrics := endpoint.New("GET", "/functions/{function_id}/metrics", "Get function metrics",
+		endpoint.Description("Get curated metrics series of a function"),
+		endpoint.Handler(controllers.GetFunctionMetrics),
+		endpoint.Path("function_id", "string", "uuid", "UUID of a function"),
+		endpoint.QueryMap(metricsParams()),
+		endpoint.Response(http.StatusOK, types.FunctionMetricsResponse{}, "Success"),
+		endpoint.Tags("Metrics"),
+	)
+
+	getUserMetrics := endpoint.New("GET", "/metrics", "Get metrics",
+		endpoint.Description("Get curated metrics series of all the functions of the user"),
+		endpoint.Handler(controllers.GetMetrics),
+		endpoint.QueryMap(metricsParams()),
+		endpoint.Response(http.StatusOK, types.FunctionMetricsResponse{}, "Success"),
+		endpoint.Tags("Metrics"),
+	)
+
 	return []*swagger.Endpoint{
 		getMetrics,
+		getUserMetrics,
+		getFunctionMetrics,
+	}
+}
+
+func metricsParams() map[string]swagger.Parameter {
+	return map[string]swagger.Parameter{
+		"series": {
+			Type: "string",
+			Description: "Comma separated series to return: invocations,invocations_total,errors,error_percent," +
+				"request_rate,status_rate,method_rate,top_paths,p50,p95,p99,duration_buckets,dwell,dwell_buckets," +
+				"cpu,memory,replicas. status_rate, method_rate, top_paths and the _buckets series are split by a label.",
+		},
+		"range": {
+			Type:        "string",
+			Pattern:     "^[1-9]\\d*[smhd]$",
+			Description: "How far back to query, e.g. 1h (default 1h)",
+		},
+		"step": {
+			Type:        "string",
+			Pattern:     "^[1-9]\\d*[smhd]$",
+			Description: "Resolution of the series, e.g. 1m (default 1m)",
+		},
+		"end": {
+			Type:        "integer",
+			Description: "Unix timestamp in seconds the series end at (default now)",
+		},
 	}
 }
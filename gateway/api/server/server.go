@@ -13,6 +13,8 @@ import (
 	"github.com/miketonks/swag/swagger"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/resty.v1"
 
 	"eywa/gateway/api/controllers"
@@ -22,6 +24,7 @@ import (
 	"eywa/gateway/types"
 	"eywa/go-libs/auth"
 	"eywa/go-libs/broker"
+	"eywa/go-libs/tracing"
 )
 
 // ContextParams holds the objects required to initialise the server.
@@ -80,40 +83,72 @@ func checkAuth() echo.MiddlewareFunc {
 	}
 }
 
+// traceRequest starts the server span of a function invocation continuing any incoming trace context
+func traceRequest(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, span := tracing.Start(tracing.Extract(c.Request().Context(), c.Request().Header), name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("function_id", c.Param("function_id")),
+					attribute.String("http.method", c.Request().Method),
+				))
+			defer span.End()
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
 func zeroScale() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 
 			auth := c.Get("auth").(*auth.Auth)
 			k8sClient := c.Get("k8s").(*k8s.Client)
+			mc := c.Get("metrics").(*metrics.Client)
 			functionID := c.Param("function_id")
 
 			filter := k8s.LabelSelector().
 				Equals(types.FunctionIDLabel, functionID).
 				Equals(types.UserIDLabel, auth.UserID)
 
+			_, span := tracing.Start(c.Request().Context(), "scale from zero")
 			scaleResult, err := k8sClient.ScaleFromZero(filter)
 			if err != nil {
 				log.Errorf("Error scaling function from zero: %s", err)
+				tracing.RecordError(span, err)
+				span.End()
 				return c.JSON(http.StatusInternalServerError, "Internal Server Error")
 			}
 
+			span.SetAttributes(
+				attribute.Bool("cold_start", scaleResult.ColdStart),
+				attribute.Bool("available", scaleResult.Available),
+			)
+			span.End()
+
 			if !scaleResult.Found {
 				log.Debugf("Function %q deployment not found")
 				return c.JSON(http.StatusNotFound, "Function not found")
 			}
 
-			if !scaleResult.Available {
-				log.Errorf("Function %q scale request timed-out after %fs", functionID, scaleResult.Duration)
-			}
-
 			// Should always exist
 			functionName := "UNKNOWN"
 			if val, exists := scaleResult.FunctionStatus.Labels[types.UserDefinedNameLabel]; exists {
 				functionName = val
 			}
 
+			if !scaleResult.Available {
+				log.Errorf("Function %q scale request timed-out after %fs", functionID, scaleResult.Duration.Seconds())
+				mc.ObserveColdStartTimeout(functionID, functionName, auth.UserID)
+			} else if scaleResult.ColdStart {
+				mc.ObserveColdStart(functionID, functionName, auth.UserID, scaleResult.Duration)
+			}
+
 			c.Set("function_name", functionName)
+			c.Set("load_balancing", scaleResult.FunctionStatus.LoadBalancing)
 			return next(c)
 		}
 	}
@@ -144,8 +179,10 @@ func createRouter(params *ContextParams) *echo.Echo {
 
 	// Proxy direct function calls
 	syncMethods := []string{"POST", "PUT", "PATCH", "DELETE", "GET"}
-	e.Match(syncMethods, "/eywa/api/functions/sync/:function_id/*path", controllers.Proxy, checkAuth(), zeroScale())
-	e.POST("/eywa/api/functions/async/:function_id/*path", controllers.AsyncInvocation, checkAuth())
+	e.Match(syncMethods, "/eywa/api/functions/sync/:function_id/*path", controllers.Proxy,
+		traceRequest("sync invocation"), checkAuth(), zeroScale())
+	e.POST("/eywa/api/functions/async/:function_id/*path", controllers.AsyncInvocation,
+		traceRequest("async invocation"), checkAuth())
 
 	enableCors := true
 	gatewayAPI := createGatewayAPI()
@@ -35,8 +35,18 @@ func systemAPI() []*swagger.Endpoint {
 		endpoint.Tags("System"),
 	)
 
+	rolloutImage := endpoint.New("POST", "/system/images/{image_id}/rollout", "Roll out a built image",
+		endpoint.Description("Roll the functions with auto deploy enabled forward to a newly built image "+
+			"when its version is within their version range and above the version they run"),
+		endpoint.Handler(controllers.SystemRolloutImage),
+		endpoint.Path("image_id", "string", "uuid", "UUID of an image"),
+		endpoint.Response(http.StatusOK, types.RolloutResponse{}, "Success"),
+		endpoint.Tags("System"),
+	)
+
 	return []*swagger.Endpoint{
 		getFunctions,
 		scaleFunction,
+		rolloutImage,
 	}
 }
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync"
+)
+
+// BalancingStrategy represents the way a function endpoint is picked
+type BalancingStrategy string
+
+const (
+	// LeastOutstanding picks the endpoint with the least in-flight requests tracked by the gateway
+	LeastOutstanding BalancingStrategy = "least_outstanding"
+	// RoundRobin picks endpoints in turn
+	RoundRobin BalancingStrategy = "round_robin"
+	// ConsistentHash picks the same endpoint for the same affinity key
+	ConsistentHash BalancingStrategy = "consistent_hash"
+
+	defaultBalancingStrategy = LeastOutstanding
+)
+
+// AffinityKey returns the value used by consistent hashing to pin the request to an endpoint
+func (lb *LoadBalancing) AffinityKey(r *http.Request) string {
+	if lb == nil || lb.Strategy != ConsistentHash {
+		return ""
+	}
+
+	if lb.HashHeader != "" {
+		if val := r.Header.Get(lb.HashHeader); val != "" {
+			return val
+		}
+	}
+
+	if lb.HashCookie != "" {
+		if cookie, err := r.Cookie(lb.HashCookie); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+type balancer struct {
+	outstanding map[string]int
+	counters    map[string]uint64
+
+	lock sync.Mutex
+}
+
+func newBalancer() *balancer {
+	return &balancer{
+		outstanding: map[string]int{},
+		counters:    map[string]uint64{},
+	}
+}
+
+// pick selects one of the addresses and marks it as having one more outstanding request
+func (b *balancer) pick(fnName string, addresses []string, lb *LoadBalancing, affinityKey string) string {
+	strategy := defaultBalancingStrategy
+	if lb != nil && lb.Strategy != "" {
+		strategy = lb.Strategy
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var picked string
+	switch {
+	case strategy == ConsistentHash && affinityKey != "":
+		picked = rendezvous(affinityKey, addresses)
+	case strategy == RoundRobin:
+		picked = addresses[b.counters[fnName]%uint64(len(addresses))]
+		b.counters[fnName]++
+	default:
+		picked = addresses[0]
+		for _, addr := range addresses[1:] {
+			if b.outstanding[addr] < b.outstanding[picked] {
+				picked = addr
+			}
+		}
+	}
+
+	b.outstanding[picked]++
+	return picked
+}
+
+func (b *balancer) release(address string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.outstanding[address]--
+	if b.outstanding[address] <= 0 {
+		delete(b.outstanding, address)
+	}
+}
+
+// rendezvous returns the address with the highest hash weight for the key.
+// Removing an address only remaps the keys that were pinned to it.
+func rendezvous(key string, addresses []string) string {
+	var picked string
+	var max uint64
+	for _, addr := range addresses {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte(addr))
+		if weight := h.Sum64(); picked == "" || weight > max {
+			picked, max = addr, weight
+		}
+	}
+
+	return picked
+}
@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -151,6 +152,12 @@ func (c *Client) buildDeployment(request *DeployFunctionRequest) (*appsv1.Deploy
 		Name:  "mongodb_host",
 		Value: c.mongoDBHost,
 	}}
+	if c.otlpEndpoint != "" {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "otlp_endpoint",
+			Value: c.otlpEndpoint,
+		})
+	}
 	for k, v := range request.EnvVars {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  k,
@@ -184,6 +191,19 @@ func (c *Client) buildDeployment(request *DeployFunctionRequest) (*appsv1.Deploy
 	labels[faasMaxReplicasIDLabel] = strconv.Itoa(request.MaxReplicas)
 	labels[faasScaleFactorIDLabel] = strconv.Itoa(request.ScalingFactor)
 
+	delete(labels, faasBalancingStrategyLabel)
+	delete(labels, faasBalancingHashHeaderLabel)
+	delete(labels, faasBalancingHashCookieLabel)
+	if request.LoadBalancing != nil {
+		labels[faasBalancingStrategyLabel] = string(request.LoadBalancing.Strategy)
+		if request.LoadBalancing.HashHeader != "" {
+			labels[faasBalancingHashHeaderLabel] = request.LoadBalancing.HashHeader
+		}
+		if request.LoadBalancing.HashCookie != "" {
+			labels[faasBalancingHashCookieLabel] = request.LoadBalancing.HashCookie
+		}
+	}
+
 	replicaCount := int32(request.MinReplicas)
 	resources := &apiv1.ResourceRequirements{
 		Limits:   apiv1.ResourceList{},
@@ -231,6 +251,35 @@ func (c *Client) buildDeployment(request *DeployFunctionRequest) (*appsv1.Deploy
 		annotations = request.Annotations
 	}
 
+	deploymentAnnotations := map[string]string{}
+	for k, v := range annotations {
+		deploymentAnnotations[k] = v
+	}
+
+	if len(request.Alerts) > 0 {
+		alerts, err := json.Marshal(request.Alerts)
+		if err != nil {
+			return nil, err
+		}
+		deploymentAnnotations[faasAlertsAnnotation] = string(alerts)
+	}
+
+	if request.AutoDeploy != nil {
+		autoDeploy, err := json.Marshal(request.AutoDeploy)
+		if err != nil {
+			return nil, err
+		}
+		deploymentAnnotations[faasAutoDeployAnnotation] = string(autoDeploy)
+	}
+
+	if len(request.Rollouts) > 0 {
+		rollouts, err := json.Marshal(request.Rollouts)
+		if err != nil {
+			return nil, err
+		}
+		deploymentAnnotations[faasRolloutsAnnotation] = string(rollouts)
+	}
+
 	var handler corev1.Handler
 	initialDelaySeconds := initialDelaySeconds
 
@@ -253,10 +302,15 @@ func (c *Client) buildDeployment(request *DeployFunctionRequest) (*appsv1.Deploy
 		FailureThreshold:    3,
 	}
 
+	// The watchdog reports not ready until the function answers, the liveness
+	// probe must keep passing meanwhile so slow starting functions are not killed
+	livenessProbe := probe.DeepCopy()
+	livenessProbe.Handler.HTTPGet.Path = probePathValue + "?probe=liveness"
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        request.Service,
-			Annotations: annotations,
+			Annotations: deploymentAnnotations,
 			Labels:      labels,
 		},
 		Spec: appsv1.DeploymentSpec{
@@ -305,7 +359,7 @@ func (c *Client) buildDeployment(request *DeployFunctionRequest) (*appsv1.Deploy
 							Env:             envVars,
 							Resources:       *resources,
 							ImagePullPolicy: imagePullPolicy,
-							LivenessProbe:   probe,
+							LivenessProbe:   livenessProbe,
 							ReadinessProbe:  probe,
 						},
 					},
@@ -534,8 +588,15 @@ func deploymentToFunction(deployment *appsv1.Deployment) (*FunctionStatus, error
 		Memory: c.Resources.Requests.Memory().String(),
 	}
 
+	function.LoadBalancing = &LoadBalancing{Strategy: defaultBalancingStrategy}
 	for k, v := range deployment.Spec.Template.Labels {
 		switch k {
+		case faasBalancingStrategyLabel:
+			function.LoadBalancing.Strategy = BalancingStrategy(v)
+		case faasBalancingHashHeaderLabel:
+			function.LoadBalancing.HashHeader = v
+		case faasBalancingHashCookieLabel:
+			function.LoadBalancing.HashCookie = v
 		case faasMinReplicasIDLabel, faasMaxReplicasIDLabel, faasScaleFactorIDLabel:
 			i64, err := strconv.ParseInt(v, 10, 32)
 			if err != nil {
@@ -560,6 +621,24 @@ func deploymentToFunction(deployment *appsv1.Deployment) (*FunctionStatus, error
 		}
 	}
 
+	if val, exists := deployment.Annotations[faasAlertsAnnotation]; exists {
+		if err := json.Unmarshal([]byte(val), &function.Alerts); err != nil {
+			return nil, err
+		}
+	}
+
+	if val, exists := deployment.Annotations[faasAutoDeployAnnotation]; exists {
+		if err := json.Unmarshal([]byte(val), &function.AutoDeploy); err != nil {
+			return nil, err
+		}
+	}
+
+	if val, exists := deployment.Annotations[faasRolloutsAnnotation]; exists {
+		if err := json.Unmarshal([]byte(val), &function.Rollouts); err != nil {
+			return nil, err
+		}
+	}
+
 	function.Available = true
 	if deployment.Status.ReadyReplicas == 0 {
 		if function.MinReplicas > 0 || deployment.Status.UnavailableReplicas > 0 {
@@ -570,6 +649,39 @@ func deploymentToFunction(deployment *appsv1.Deployment) (*FunctionStatus, error
 	return function, nil
 }
 
+// DeployRequest returns the request the function is deployed with, secrets are the
+// secrets mounted to the function
+func (fs *FunctionStatus) DeployRequest(secrets []Secret) *DeployFunctionRequest {
+	envVars := map[string]string{}
+	for k, v := range fs.Env {
+		envVars[k] = v
+	}
+
+	// Set for every function by buildDeployment
+	delete(envVars, "mongodb_host")
+	delete(envVars, "otlp_endpoint")
+
+	labels := map[string]string{}
+	for k, v := range fs.Labels {
+		labels[k] = v
+	}
+
+	return &DeployFunctionRequest{
+		Image:         fs.Image,
+		Service:       fs.Name,
+		EnvVars:       envVars,
+		Secrets:       secrets,
+		MinReplicas:   fs.MinReplicas,
+		MaxReplicas:   fs.MaxReplicas,
+		ScalingFactor: fs.ScalingFactor,
+		Labels:        labels,
+		LoadBalancing: fs.LoadBalancing,
+		Alerts:        fs.Alerts,
+		AutoDeploy:    fs.AutoDeploy,
+		Rollouts:      fs.Rollouts,
+	}
+}
+
 // ScaleFunction scales the function to specified replicas
 func (c *Client) ScaleFunction(filter Selector, replicas int) error {
 	deployments, err := c.listDeployments(filter)
@@ -667,6 +779,7 @@ func (c *Client) ScaleFromZero(filter Selector) (*FunctionZeroScaleResult, error
 			return &FunctionZeroScaleResult{
 				Available: false,
 				Found:     true,
+				ColdStart: true,
 				Duration:  time.Since(start),
 			}, err
 		}
@@ -680,6 +793,7 @@ func (c *Client) ScaleFromZero(filter Selector) (*FunctionZeroScaleResult, error
 				return &FunctionZeroScaleResult{
 					Available: false,
 					Found:     true,
+					ColdStart: true,
 					Duration:  time.Since(start),
 				}, err
 			}
@@ -694,6 +808,7 @@ func (c *Client) ScaleFromZero(filter Selector) (*FunctionZeroScaleResult, error
 				return &FunctionZeroScaleResult{
 					Available:      true,
 					Found:          true,
+					ColdStart:      true,
 					Duration:       totalTime,
 					FunctionStatus: functionStatus,
 				}, nil
@@ -701,6 +816,14 @@ func (c *Client) ScaleFromZero(filter Selector) (*FunctionZeroScaleResult, error
 
 			time.Sleep(interval)
 		}
+
+		return &FunctionZeroScaleResult{
+			Available:      false,
+			Found:          true,
+			ColdStart:      true,
+			Duration:       time.Since(start),
+			FunctionStatus: functionStatus,
+		}, nil
 	}
 
 	return &FunctionZeroScaleResult{
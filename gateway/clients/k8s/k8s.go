@@ -28,6 +28,16 @@ const (
 	faasMaxReplicasIDLabel = "faas.replicas.max"
 	faasScaleFactorIDLabel = "faas.scale.factor"
 
+	faasBalancingStrategyLabel   = "faas.lb.strategy"
+	faasBalancingHashHeaderLabel = "faas.lb.hash.header"
+	faasBalancingHashCookieLabel = "faas.lb.hash.cookie"
+
+	// Alert rules do not fit label value restrictions so they are kept as a deployment annotation
+	faasAlertsAnnotation = "faas.alerts"
+	// The image versions a function is rolled forward to and its past rollouts are kept as deployment annotations
+	faasAutoDeployAnnotation = "faas.autodeploy"
+	faasRolloutsAnnotation   = "faas.rollouts"
+
 	updatedAtLabel = "updated_at"
 
 	defaultMinReplicas   = 0
@@ -48,6 +58,7 @@ const (
 type Config struct {
 	InCluster           bool
 	MongoDBHost         string
+	OTLPEndpoint        string
 	CacheExpiryDuration time.Duration
 	LimitCPUMin         string
 	LimitMemMin         string
@@ -58,10 +69,12 @@ type Config struct {
 // Client represents the k8s client
 type Client struct {
 	mongoDBHost    string
+	otlpEndpoint   string
 	clientset      *kubernetes.Clientset
 	endpointLister corelister.EndpointsNamespaceLister
 	limitRange     ResourceLimits
 	cache          *cache.Cache
+	balancer       *balancer
 }
 
 type functionLookup struct {
@@ -102,9 +115,11 @@ func Setup(conf *Config) (*Client, error) {
 
 	return &Client{
 		mongoDBHost:    conf.MongoDBHost,
+		otlpEndpoint:   conf.OTLPEndpoint,
 		clientset:      clientset,
 		endpointLister: endpointsLister.Endpoints(faasNamespace),
 		cache:          cache.New(conf.CacheExpiryDuration, conf.CacheExpiryDuration),
+		balancer:       newBalancer(),
 		limitRange: ResourceLimits{
 			MinCPU: conf.LimitCPUMin,
 			MaxCPU: conf.LimitCPUMax,
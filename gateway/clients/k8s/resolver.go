@@ -1,35 +1,79 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
 	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"eywa/go-libs/tracing"
 )
 
-// Resolve resolves from function name to internal address
-func (c *Client) Resolve(fnName string) (string, error) {
+const defaultFunctionPort = 8080
+
+// Resolve resolves from function name to an internal endpoint picked by the load balancing strategy.
+// The returned endpoint must be released once the request to it has finished.
+func (c *Client) Resolve(ctx context.Context, fnName string, request *ResolveRequest) (*Endpoint, error) {
+	_, span := tracing.Start(ctx, "resolve")
+	defer span.End()
+
 	if strings.Contains(fnName, ".") {
 		fnName = strings.TrimSuffix(fnName, "."+faasNamespace)
 	}
 
+	if request == nil {
+		request = &ResolveRequest{}
+	}
+
 	fnName = "s-" + fnName
 	svc, err := c.endpointLister.Get(fnName)
 	if err != nil {
-		return "", fmt.Errorf("Error listing \"%s.%s\": %s", fnName, faasNamespace, err)
+		err = fmt.Errorf("Error listing \"%s.%s\": %s", fnName, faasNamespace, err)
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	if len(svc.Subsets) == 0 {
-		return "", fmt.Errorf("No subsets available for \"%s.%s\"", fnName, faasNamespace)
+		err = fmt.Errorf("No subsets available for \"%s.%s\"", fnName, faasNamespace)
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
-	all := len(svc.Subsets[0].Addresses)
-	if len(svc.Subsets[0].Addresses) == 0 {
-		return "", fmt.Errorf("No addresses in subset for \"%s.%s\"", fnName, faasNamespace)
+	excluded := map[string]struct{}{}
+	for _, addr := range request.Exclude {
+		excluded[addr] = struct{}{}
 	}
 
-	target := rand.Intn(all)
+	addresses := []string{}
+	for _, subset := range svc.Subsets {
+		port := int32(defaultFunctionPort)
+		for _, p := range subset.Ports {
+			if p.Name == "http" {
+				port = p.Port
+			}
+		}
+
+		for _, a := range subset.Addresses {
+			addr := fmt.Sprintf("%s:%d", a.IP, port)
+			if _, skip := excluded[addr]; !skip {
+				addresses = append(addresses, addr)
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		err = fmt.Errorf("No addresses in subsets for \"%s.%s\"", fnName, faasNamespace)
+		tracing.RecordError(span, err)
+		return nil, err
+	}
 
-	serviceIP := svc.Subsets[0].Addresses[target].IP
+	addr := c.balancer.pick(fnName, addresses, request.LoadBalancing, request.AffinityKey)
+	span.SetAttributes(attribute.String("net.peer.name", addr))
 
-	return fmt.Sprintf("http://%s:%d", serviceIP, 8080), nil
+	return &Endpoint{
+		URL:     "http://" + addr,
+		Address: addr,
+		release: func() { c.balancer.release(addr) },
+	}, nil
 }
@@ -1,6 +1,7 @@
 package k8s
 
 import (
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,10 @@ type DeployFunctionRequest struct {
 	Annotations   map[string]string
 	Limits        *FunctionResources
 	Requests      *FunctionResources
+	LoadBalancing *LoadBalancing
+	Alerts        []AlertRule
+	AutoDeploy    *AutoDeploy
+	Rollouts      []Rollout
 }
 
 // FunctionStatus represents the deployed function status in k8s
@@ -36,6 +41,10 @@ type FunctionStatus struct {
 	Labels            map[string]string
 	Limits            *FunctionResources
 	Requests          *FunctionResources
+	LoadBalancing     *LoadBalancing
+	Alerts            []AlertRule
+	AutoDeploy        *AutoDeploy
+	Rollouts          []Rollout
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 	DeletedAt         *time.Time
@@ -51,6 +60,7 @@ type FunctionResources struct {
 type FunctionZeroScaleResult struct {
 	Found          bool
 	Available      bool
+	ColdStart      bool
 	Duration       time.Duration
 	FunctionStatus *FunctionStatus
 }
@@ -80,3 +90,60 @@ type Secret struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
+
+// LoadBalancing represents function load balancing configuration
+type LoadBalancing struct {
+	Strategy   BalancingStrategy
+	HashHeader string
+	HashCookie string
+}
+
+// AlertRule represents a function alert rule stored with the deployment
+type AlertRule struct {
+	Name      string       `json:"name"`
+	Metric    string       `json:"metric"`
+	Threshold float64      `json:"threshold"`
+	Window    string       `json:"window"`
+	Channel   AlertChannel `json:"channel"`
+}
+
+// AlertChannel represents where alert notifications are delivered
+type AlertChannel struct {
+	Type  string `json:"type"`
+	URL   string `json:"url,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// AutoDeploy represents the image versions a function is rolled forward to once they are built
+type AutoDeploy struct {
+	VersionRange string `json:"version_range"`
+}
+
+// Rollout represents an automatic update of a function to a newly built image version
+type Rollout struct {
+	ImageID     string    `json:"image_id"`
+	Version     string    `json:"version"`
+	FromImageID string    `json:"from_image_id"`
+	FromVersion string    `json:"from_version"`
+	RolledOutAt time.Time `json:"rolled_out_at"`
+}
+
+// ResolveRequest represents the parameters used to pick a function endpoint
+type ResolveRequest struct {
+	LoadBalancing *LoadBalancing
+	AffinityKey   string
+	Exclude       []string
+}
+
+// Endpoint represents a resolved function pod address
+type Endpoint struct {
+	URL     string
+	Address string
+	release func()
+	once    sync.Once
+}
+
+// Release marks the request sent to the endpoint as finished
+func (e *Endpoint) Release() {
+	e.once.Do(e.release)
+}
@@ -3,6 +3,7 @@ package registry
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -40,6 +41,35 @@ func (c *Client) GetImage(imageID, userID string) (*rt.Image, error) {
 		return nil, err
 	}
 
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.IsError() {
+		log.Errorf(string(resp.Body()))
+		return nil, fmt.Errorf("Registry responded with unexpected status: %s", resp.Status())
+	}
+
+	return &result, nil
+}
+
+// GetImageVulnerabilities retrieves the vulnerabilities of an image filtered by user and the id,
+// along with whether the vulnerability policy allows to deploy it
+func (c *Client) GetImageVulnerabilities(imageID, userID string) (*rt.GetImageVulnerabilitiesResponse, error) {
+	var result rt.GetImageVulnerabilitiesResponse
+	resp, err := c.rc.R().
+		SetResult(&result).
+		SetHeader("X-Eywa-User-Id", userID).
+		SetHeader("X-Eywa-Real-User-Id", auth.OperatorUserID).
+		Get("/eywa/api/images/" + imageID + "/vulnerabilities")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+
 	if resp.IsError() {
 		log.Errorf(string(resp.Body()))
 		return nil, fmt.Errorf("Registry responded with unexpected status: %s", resp.Status())
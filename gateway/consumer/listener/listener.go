@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/nats-io/stan.go"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/resty.v1"
 
 	ett "eywa/execution-tracker/types"
@@ -18,6 +21,7 @@ import (
 	"eywa/gateway/metrics"
 	"eywa/gateway/types"
 	"eywa/go-libs/broker"
+	"eywa/go-libs/tracing"
 	"eywa/go-libs/trigger"
 	wet "eywa/watchdog/executor"
 )
@@ -114,10 +118,19 @@ func (l *Listener) process(msg *stan.Msg) {
 		return
 	}
 
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), req.Headers), "dequeue",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("function_id", req.FunctionID),
+			attribute.String("request_id", req.RequestID),
+		))
+	defer span.End()
+
 	defaultTimelineFields := trigger.Fields{
 		"user_id":     req.UserID,
 		"request_id":  req.RequestID,
 		"function_id": req.FunctionID,
+		"trace_id":    tracing.TraceID(ctx),
 	}
 
 	defaultEventFields := trigger.Fields{
@@ -137,11 +150,26 @@ func (l *Listener) process(msg *stan.Msg) {
 
 	l.metrics.ObserveDwellTime(req.FunctionID, req.FunctionName, req.UserID, time.Since(req.QueuedAt))
 
+	// Every attempt is traced as a child of the dequeue span, ended before the next attempt sleeps
+	var attemptSpan trace.Span
+	defer func() {
+		if attemptSpan != nil {
+			attemptSpan.End()
+		}
+	}()
+
 	sleepDuration := time.Minute * 0
 	for attempt := 1; attempt <= 3; attempt++ {
+		if attemptSpan != nil {
+			attemptSpan.End()
+		}
+
 		time.Sleep(sleepDuration)
 		sleepDuration = sleepDuration + time.Minute*3
 
+		var attemptCtx context.Context
+		attemptCtx, attemptSpan = tracing.Start(ctx, fmt.Sprintf("attempt #%d", attempt))
+
 		started := time.Now()
 
 		trigger.WithFields(defaultEventFields).WithFields(trigger.Fields{
@@ -198,7 +226,8 @@ func (l *Listener) process(msg *stan.Msg) {
 		}
 
 		if !scaleResult.Available {
-			log.Errorf("Function %q scale request timed-out after %fs", req.FunctionID, scaleResult.Duration)
+			log.Errorf("Function %q scale request timed-out after %fs", req.FunctionID, scaleResult.Duration.Seconds())
+			l.metrics.ObserveColdStartTimeout(req.FunctionID, req.FunctionName, req.UserID)
 
 			trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
 				"event_name": fmt.Sprintf("Attempt #%d", attempt),
@@ -216,10 +245,19 @@ func (l *Listener) process(msg *stan.Msg) {
 			continue
 		}
 
-		start := time.Now()
-		functionAddr, err := l.k8s.Resolve(req.FunctionID)
+		if scaleResult.ColdStart {
+			l.metrics.ObserveColdStart(req.FunctionID, req.FunctionName, req.UserID, scaleResult.Duration)
+		}
+
+		resolveRequest := &k8s.ResolveRequest{
+			LoadBalancing: scaleResult.FunctionStatus.LoadBalancing,
+			AffinityKey:   req.AffinityKey,
+		}
+
+		endpoint, err := l.k8s.Resolve(attemptCtx, req.FunctionID, resolveRequest)
 		if err != nil {
 			log.Errorf("k8s error: cannot find %s: %s\n", req.FunctionID, err)
+			l.metrics.ObserveResolveFailure(req.FunctionID, req.FunctionName, req.UserID)
 
 			trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
 				"event_name": fmt.Sprintf("Attempt #%d", attempt),
@@ -244,21 +282,61 @@ func (l *Listener) process(msg *stan.Msg) {
 			"duration":   int64(0),
 		}).Fire(types.TimelineHookType)
 
+		traceHeaders := http.Header{}
+		tracing.Inject(attemptCtx, traceHeaders)
+
 		headers := map[string]string{}
 		for k, h := range req.Headers {
 			headers[k] = strings.Join(h, ",")
 		}
+		for k := range traceHeaders {
+			headers[k] = traceHeaders.Get(k)
+		}
+
+		var url string
+		var start time.Time
+		var result *wet.FunctionResponse
+		var functionRes *resty.Response
+		for {
+			url = endpoint.URL + req.Path
+			start = time.Now()
+			functionRes, err = l.rc.R().
+				SetBody(req.Body).
+				SetHeaders(headers).
+				SetQueryString(req.QueryParams).
+				Post(url)
+			endpoint.Release()
+
+			if err == nil {
+				result, err = wet.DecodeFunctionResponse(functionRes.RawResponse, functionRes.Body())
+			}
+
+			if err != nil || result.Error != wet.ErrorConcurrencyLimit {
+				break
+			}
+
+			// The replica is at its concurrency limit, try another one before giving up on the attempt
+			resolveRequest.Exclude = append(resolveRequest.Exclude, endpoint.Address)
+			next, resolveErr := l.k8s.Resolve(attemptCtx, req.FunctionID, resolveRequest)
+			if resolveErr != nil {
+				break
+			}
+			endpoint = next
+		}
 
-		url := functionAddr + req.Path
-		var result wet.FunctionResponse
-		functionRes, err := l.rc.R().
-			SetBody(req.Body).
-			SetResult(&result).
-			SetHeaders(headers).
-			SetQueryString(req.QueryParams).
-			Post(url)
-		if err != nil || functionRes.IsError() {
+		if err != nil || result.Error != "" {
 			log.Errorf("Failed to execute function request [%s] %q: %s", http.MethodPost, url, err)
+			if err != nil {
+				tracing.RecordError(attemptSpan, err)
+			} else {
+				tracing.RecordError(attemptSpan, fmt.Errorf("watchdog responded with %d: %s", result.Status, result.Error))
+			}
+
+			statusCode := 0
+			if functionRes != nil {
+				statusCode = functionRes.StatusCode()
+			}
+			l.metrics.ObserveProxyError(req.FunctionID, req.FunctionName, req.UserID, metrics.ProxyErrorCause(err, statusCode))
 
 			trigger.WithFields(defaultTimelineFields).WithFields(trigger.Fields{
 				"event_name": fmt.Sprintf("Attempt #%d", attempt),
@@ -278,6 +356,10 @@ func (l *Listener) process(msg *stan.Msg) {
 
 		duration := time.Since(start)
 		l.metrics.ObserveInvocationComplete(req.FunctionID, req.FunctionName, req.UserID, req.Path, result.Status, duration)
+		l.metrics.ObserveTransferredBytes(req.FunctionID, req.FunctionName, req.UserID, len(req.Body), len(result.Body))
+		if result.Usage != nil {
+			l.metrics.ObserveResourceUsage(req.FunctionID, req.FunctionName, req.UserID, result.Usage.CPUSeconds, result.Usage.MemoryBytes)
+		}
 
 		log.Infof("[Attempt: #%s] Invoked: %s-%s [%d] in %fs", attempt, req.FunctionID,
 			req.FunctionName, result.Status, duration.Seconds())
@@ -313,7 +395,11 @@ func (l *Listener) process(msg *stan.Msg) {
 			"stdout":   result.Stdout,
 			"stderr":   result.Stderr,
 			"message":  types.AsyncExecutionFinishMessage(req.FunctionName, attempt, result.Status, duration),
-		}).Fire(types.EventHookType)
+		}).WithFields(hooks.UsageFields(result.Usage)).Fire(types.EventHookType)
+
+		for _, entry := range result.Logs {
+			trigger.WithFields(defaultEventFields).WithFields(hooks.FunctionLogFields(entry)).Fire(types.EventHookType)
+		}
 
 		if req.CallbackURL != "" {
 			log.Infof("Sending callback to: %s\n", req.CallbackURL)
@@ -323,7 +409,7 @@ func (l *Listener) process(msg *stan.Msg) {
 					"X-Function-Id":     req.FunctionID,
 					"X-Function-Status": fmt.Sprint(result.Status),
 				}).
-				SetBody(functionRes.Body()).
+				SetBody(result.Body).
 				Post(req.CallbackURL)
 			if err != nil {
 				log.Warnf("Failed call callback url %q: %s", req.CallbackURL, err)
@@ -340,6 +426,9 @@ func (l *Listener) process(msg *stan.Msg) {
 
 		return
 	}
+
+	log.Errorf("Async invocation %s of %q exhausted all attempts", req.RequestID, req.FunctionID)
+	l.metrics.ObserveDeadLetter(req.FunctionID, req.FunctionName, req.UserID)
 }
 
 func validateMessage(req broker.QueueRequest) error {
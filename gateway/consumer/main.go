@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"eywa/gateway/metrics"
 	"eywa/gateway/types"
 	"eywa/go-libs/broker"
+	"eywa/go-libs/tracing"
 )
 
 // Config represents gateway-queue configuration
@@ -27,6 +29,7 @@ type Config struct {
 	MaxInflight   int    `envconfig:"max_inflight" default:"100"`
 	RetryCount    int    `envconfig:"retry_count" default:"3"`
 	RetrySleep    int    `envconfig:"retry_sleep" default:"3"`
+	OTLPEndpoint  string `envconfig:"otlp_endpoint"`
 }
 
 func main() {
@@ -44,6 +47,17 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	shutdownTracing, err := tracing.Setup(&tracing.Config{
+		ServiceName: "gateway-consumer",
+		Endpoint:    conf.OTLPEndpoint,
+		Insecure:    true,
+		SampleRatio: 1,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup tracing: %s", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	k8s, err := k8s.Setup(&k8s.Config{
 		InCluster:           *inCluster,
 		CacheExpiryDuration: time.Second * 5,
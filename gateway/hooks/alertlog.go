@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"time"
+
+	"eywa/go-libs/broker"
+	"eywa/go-libs/trigger"
+)
+
+// AlertHook represents processing function for alert trigger
+func AlertHook(entry *trigger.Entry) broker.MessageInterface {
+	alm := broker.Log{
+		Message: broker.Message{
+			Timestamp: entry.Time,
+		},
+		AlertLog: &broker.AlertLog{
+			CreatedAt: entry.Time,
+		},
+	}
+
+	for k, v := range entry.Data {
+		switch k {
+		case "user_id":
+			alm.Message.UserID = v.(string)
+		case "function_id":
+			alm.AlertLog.FunctionID = v.(string)
+		case "function_name":
+			alm.AlertLog.FunctionName = v.(string)
+		case "rule_name":
+			alm.AlertLog.RuleName = v.(string)
+		case "metric":
+			alm.AlertLog.Metric = v.(string)
+		case "threshold":
+			alm.AlertLog.Threshold = v.(float64)
+		case "value":
+			alm.AlertLog.Value = v.(float64)
+		case "state":
+			alm.AlertLog.State = v.(string)
+		case "channel":
+			alm.AlertLog.Channel = v.(string)
+		case "notify_error":
+			alm.AlertLog.NotifyError = v.(string)
+		case "created_at":
+			alm.AlertLog.CreatedAt = v.(time.Time)
+		}
+	}
+
+	return alm
+}
@@ -6,8 +6,10 @@ import (
 	"time"
 	"unicode/utf8"
 
+	ett "eywa/execution-tracker/types"
 	"eywa/go-libs/broker"
 	"eywa/go-libs/trigger"
+	wet "eywa/watchdog/executor"
 )
 
 const maxFieldSize = 4 * 2000 // 4 bytes of 2000 chars is the limit
@@ -42,6 +44,15 @@ func EventHook(entry *trigger.Entry) broker.MessageInterface {
 			elm.EventLog.IsError = v.(bool)
 		case "message":
 			elm.EventLog.Message = v.(string)
+		case "level":
+			elm.EventLog.Level = v.(string)
+		case "fields":
+			elm.EventLog.Fields = v.(map[string]interface{})
+		case "cpu_seconds":
+			cpuSeconds := v.(float64)
+			elm.EventLog.CPUSeconds = &cpuSeconds
+		case "memory_bytes":
+			elm.EventLog.MemoryBytes = v.(int64)
 		case "body", "stdout", "stderr":
 			switch t := v.(type) {
 			case []uint8:
@@ -63,3 +74,31 @@ func EventHook(entry *trigger.Entry) broker.MessageInterface {
 
 	return elm
 }
+
+// FunctionLogFields returns the event fields of a structured log line printed by a function
+func FunctionLogFields(entry wet.LogEntry) trigger.Fields {
+	return trigger.Fields{
+		"type":     ett.EventTypeUser,
+		"is_error": entry.Level == "error" || entry.Level == "fatal",
+		"message":  entry.Message,
+		"level":    entry.Level,
+		"fields":   entry.Fields,
+		"stream":   entry.Stream,
+	}
+}
+
+// UsageFields returns the event fields of the resources a function used to serve a request
+func UsageFields(usage *wet.ResourceUsage) trigger.Fields {
+	if usage == nil {
+		return trigger.Fields{}
+	}
+
+	fields := trigger.Fields{
+		"memory_bytes": int64(usage.MemoryBytes),
+	}
+	if usage.CPUSeconds != nil {
+		fields["cpu_seconds"] = *usage.CPUSeconds
+	}
+
+	return fields
+}
@@ -54,6 +54,10 @@ func TimelineHook(entry *trigger.Entry) broker.MessageInterface {
 		tlm.TimelineLog.Duration = val.(int64)
 	}
 
+	if val, exists := entry.Data["trace_id"]; exists {
+		tlm.TimelineLog.TraceID = val.(string)
+	}
+
 	if val, exists := entry.Data["created_at"]; exists {
 		tlm.TimelineLog.CreatedAt = val.(time.Time)
 	} else {
@@ -1,10 +1,12 @@
 package metrics
 
 import (
-	"fmt"
+	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,6 +15,7 @@ import (
 	"gopkg.in/resty.v1"
 
 	"eywa/gateway/clients/k8s"
+	"eywa/gateway/types"
 )
 
 // Client metrics client
@@ -22,6 +25,8 @@ type Client struct {
 	watchInterval time.Duration
 	k8sClient     *k8s.Client
 	promrc        *resty.Client
+
+	lock sync.RWMutex
 }
 
 type metrics struct {
@@ -30,8 +35,25 @@ type metrics struct {
 	functionInvocation        *prometheus.CounterVec
 	functionInvocationStarted *prometheus.CounterVec
 	serviceReplicasGauge      *prometheus.GaugeVec
+	coldStartHistogram        *prometheus.HistogramVec
+	coldStartTimeouts         *prometheus.CounterVec
+	resolveFailures           *prometheus.CounterVec
+	proxyErrors               *prometheus.CounterVec
+	requestBytes              *prometheus.CounterVec
+	responseBytes             *prometheus.CounterVec
+	deadLetters               *prometheus.CounterVec
+	cpuHistogram              *prometheus.HistogramVec
+	memoryHistogram           *prometheus.HistogramVec
 }
 
+// Causes of failed proxy requests
+const (
+	ProxyErrorDial    = "dial"
+	ProxyErrorTimeout = "timeout"
+	ProxyError5xx     = "5xx"
+	ProxyErrorOther   = "other"
+)
+
 // Setup sets up prometheus counters and histograms
 func Setup(k8sClient *k8s.Client, prometheusURL *string, watchInterval time.Duration) *Client {
 	client := &Client{
@@ -80,7 +102,7 @@ func setupMetrics() *metrics {
 			Name:      "service_count",
 			Help:      "Service replicas",
 		},
-		[]string{"function_name"},
+		[]string{"function_id", "user_id"},
 	)
 
 	gatewayFunctionInvocationStarted := prometheus.NewCounterVec(
@@ -93,12 +115,101 @@ func setupMetrics() *metrics {
 		[]string{"function_id", "function_name", "user_id", "path", "method"},
 	)
 
+	functionLabels := []string{"function_id", "function_name", "user_id"}
+
+	coldStartHistogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_function_cold_start_duration_milliseconds",
+		Help:    "Time taken to scale a function from zero replicas",
+		Buckets: []float64{100, 250, 500, 1000, 2500, 5000, 10000, 25000, 50000},
+	}, functionLabels)
+
+	coldStartTimeouts := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "function",
+			Name:      "cold_start_timeouts_total",
+			Help:      "The total number of scale from zero requests that timed out.",
+		},
+		functionLabels,
+	)
+
+	resolveFailures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "function",
+			Name:      "resolve_failures_total",
+			Help:      "The total number of failures to resolve a function endpoint.",
+		},
+		functionLabels,
+	)
+
+	proxyErrors := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "function",
+			Name:      "proxy_errors_total",
+			Help:      "The total number of failed requests to function endpoints by cause.",
+		},
+		append(functionLabels, "cause"),
+	)
+
+	requestBytes := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "function",
+			Name:      "request_bytes_total",
+			Help:      "The total number of request body bytes sent to functions.",
+		},
+		functionLabels,
+	)
+
+	responseBytes := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "function",
+			Name:      "response_bytes_total",
+			Help:      "The total number of response body bytes received from functions.",
+		},
+		functionLabels,
+	)
+
+	deadLetters := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "async",
+			Name:      "dead_letters_total",
+			Help:      "The total number of async invocations that exhausted all attempts.",
+		},
+		functionLabels,
+	)
+
+	cpuHistogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_function_cpu_seconds",
+		Help:    "CPU time the function process used to serve a request",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+	}, functionLabels)
+
+	memoryHistogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_function_memory_bytes",
+		Help:    "Resident memory of the function process serving a request",
+		Buckets: prometheus.ExponentialBuckets(4*1024*1024, 2, 10),
+	}, functionLabels)
+
 	return &metrics{
 		functionsHistogram:        gatewayFunctionsHistogram,
 		queueHistogram:            gatewayAsyncQueueHistogram,
 		functionInvocation:        gatewayFunctionInvocation,
 		functionInvocationStarted: gatewayFunctionInvocationStarted,
 		serviceReplicasGauge:      serviceReplicas,
+		coldStartHistogram:        coldStartHistogram,
+		coldStartTimeouts:         coldStartTimeouts,
+		resolveFailures:           resolveFailures,
+		proxyErrors:               proxyErrors,
+		requestBytes:              requestBytes,
+		responseBytes:             responseBytes,
+		deadLetters:               deadLetters,
+		cpuHistogram:              cpuHistogram,
+		memoryHistogram:           memoryHistogram,
 	}
 }
 
@@ -148,17 +259,95 @@ func (c *Client) ObserveDwellTime(fnID, fnName, userID string, duration time.Dur
 		Observe(float64(milliseconds))
 }
 
+// ObserveColdStart records the time taken to scale a function from zero in Prometheus
+func (c *Client) ObserveColdStart(fnID, fnName, userID string, duration time.Duration) {
+	c.metrics.coldStartHistogram.
+		With(functionLabels(fnID, fnName, userID)).
+		Observe(float64(duration.Milliseconds()))
+}
+
+// ObserveColdStartTimeout records a scale from zero that timed out in Prometheus
+func (c *Client) ObserveColdStartTimeout(fnID, fnName, userID string) {
+	c.metrics.coldStartTimeouts.With(functionLabels(fnID, fnName, userID)).Inc()
+}
+
+// ObserveResolveFailure records a failure to resolve function endpoint in Prometheus
+func (c *Client) ObserveResolveFailure(fnID, fnName, userID string) {
+	c.metrics.resolveFailures.With(functionLabels(fnID, fnName, userID)).Inc()
+}
+
+// ObserveProxyError records a failed request to a function endpoint in Prometheus
+func (c *Client) ObserveProxyError(fnID, fnName, userID, cause string) {
+	labels := functionLabels(fnID, fnName, userID)
+	labels["cause"] = cause
+	c.metrics.proxyErrors.With(labels).Inc()
+}
+
+// ObserveTransferredBytes records request and response body sizes in Prometheus
+func (c *Client) ObserveTransferredBytes(fnID, fnName, userID string, in, out int) {
+	labels := functionLabels(fnID, fnName, userID)
+	c.metrics.requestBytes.With(labels).Add(float64(in))
+	c.metrics.responseBytes.With(labels).Add(float64(out))
+}
+
+// ObserveDeadLetter records an async invocation that exhausted all attempts in Prometheus
+func (c *Client) ObserveDeadLetter(fnID, fnName, userID string) {
+	c.metrics.deadLetters.With(functionLabels(fnID, fnName, userID)).Inc()
+}
+
+// ObserveResourceUsage records the CPU time and memory a function used to serve a request in Prometheus,
+// the CPU time is nil for functions serving requests concurrently
+func (c *Client) ObserveResourceUsage(fnID, fnName, userID string, cpuSeconds *float64, memoryBytes uint64) {
+	labels := functionLabels(fnID, fnName, userID)
+	if cpuSeconds != nil {
+		c.metrics.cpuHistogram.With(labels).Observe(*cpuSeconds)
+	}
+	c.metrics.memoryHistogram.With(labels).Observe(float64(memoryBytes))
+}
+
+// ProxyErrorCause classifies a failed request to a function endpoint
+func ProxyErrorCause(err error, statusCode int) string {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return ProxyErrorTimeout
+		}
+
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return ProxyErrorDial
+		}
+
+		return ProxyErrorOther
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return ProxyError5xx
+	}
+
+	return ProxyErrorOther
+}
+
+func functionLabels(fnID, fnName, userID string) prometheus.Labels {
+	return prometheus.Labels{
+		"function_id":   fnID,
+		"function_name": fnName,
+		"user_id":       userID,
+	}
+}
+
 // FunctionWatcher watches currently deployed functions and stores them for metrics
 func (c *Client) FunctionWatcher() {
 	for {
 		functions, err := c.k8sClient.GetFunctionsStatus()
 		if err != nil {
 			log.Errorf("Failed to list current functions: %s", err)
-			continue
+		} else {
+			c.lock.Lock()
+			c.services = functions
+			c.lock.Unlock()
 		}
 
-		c.services = functions
-
 		time.Sleep(c.watchInterval)
 	}
 }
@@ -169,16 +358,24 @@ func (c *Client) Collect(ch chan<- prometheus.Metric) {
 	c.metrics.functionsHistogram.Collect(ch)
 	c.metrics.queueHistogram.Collect(ch)
 	c.metrics.functionInvocationStarted.Collect(ch)
+	c.metrics.coldStartHistogram.Collect(ch)
+	c.metrics.coldStartTimeouts.Collect(ch)
+	c.metrics.resolveFailures.Collect(ch)
+	c.metrics.proxyErrors.Collect(ch)
+	c.metrics.requestBytes.Collect(ch)
+	c.metrics.responseBytes.Collect(ch)
+	c.metrics.deadLetters.Collect(ch)
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	c.metrics.serviceReplicasGauge.Reset()
 	for _, service := range c.services {
-		var serviceName string
-		if len(service.Namespace) > 0 {
-			serviceName = fmt.Sprintf("%s.%s", service.Name, service.Namespace)
-		} else {
-			serviceName = service.Name
-		}
 		c.metrics.serviceReplicasGauge.
-			WithLabelValues(serviceName).
+			With(prometheus.Labels{
+				"function_id": service.Name,
+				"user_id":     service.Labels[types.UserIDLabel],
+			}).
 			Set(float64(service.Replicas))
 	}
 	c.metrics.serviceReplicasGauge.Collect(ch)
@@ -191,6 +388,13 @@ func (c *Client) Describe(ch chan<- *prometheus.Desc) {
 	c.metrics.functionsHistogram.Describe(ch)
 	c.metrics.serviceReplicasGauge.Describe(ch)
 	c.metrics.functionInvocationStarted.Describe(ch)
+	c.metrics.coldStartHistogram.Describe(ch)
+	c.metrics.coldStartTimeouts.Describe(ch)
+	c.metrics.resolveFailures.Describe(ch)
+	c.metrics.proxyErrors.Describe(ch)
+	c.metrics.requestBytes.Describe(ch)
+	c.metrics.responseBytes.Describe(ch)
+	c.metrics.deadLetters.Describe(ch)
 }
 
 // PrometheusHandler returns prometheus handler
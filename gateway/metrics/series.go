@@ -0,0 +1,273 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"eywa/gateway/types"
+)
+
+// minSeriesWindow is the smallest window rates are calculated over so that
+// every window contains at least a couple of prometheus scrapes
+const minSeriesWindow = time.Minute
+
+// seriesScope holds the label matchers every series query is restricted to
+type seriesScope struct {
+	selector string
+	window   string
+}
+
+type seriesDefinition struct {
+	unit string
+	// groupBy is the label the series is split by, the series aggregates to a single one when empty
+	groupBy string
+	query   func(s seriesScope) string
+}
+
+var functionSeries = map[string]seriesDefinition{
+	"invocations": {
+		unit: "requests",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(increase(gateway_function_invocation_total{%s}[%s]))`, s.selector, s.window)
+		},
+	},
+	"invocations_total": {
+		unit: "requests",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(gateway_function_invocation_total{%s})`, s.selector)
+		},
+	},
+	"errors": {
+		unit: "requests",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(increase(gateway_function_invocation_total{%s,code=~"4..|5.."}[%s]))`, s.selector, s.window)
+		},
+	},
+	"error_percent": {
+		unit: "percent",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(rate(gateway_function_invocation_total{%[1]s,code=~"4..|5.."}[%[2]s])) / sum(rate(gateway_function_invocation_total{%[1]s}[%[2]s])) * 100`,
+				s.selector, s.window)
+		},
+	},
+	"request_rate": {
+		unit: "requests/second",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(rate(gateway_function_invocation_total{%s}[%s]))`, s.selector, s.window)
+		},
+	},
+	"status_rate": {
+		unit:    "requests/second",
+		groupBy: "status",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum by (status) (label_replace(rate(gateway_function_invocation_total{%s}[%s]), "status", "${1}xx", "code", "(.).."))`,
+				s.selector, s.window)
+		},
+	},
+	"method_rate": {
+		unit:    "requests/second",
+		groupBy: "method",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum by (method) (rate(gateway_function_invocation_started{%s}[%s]))`, s.selector, s.window)
+		},
+	},
+	"top_paths": {
+		unit:    "requests/second",
+		groupBy: "path",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`topk(3, sum by (path) (rate(gateway_function_invocation_total{%s}[%s])))`, s.selector, s.window)
+		},
+	},
+	"p50": {
+		unit:  "milliseconds",
+		query: durationQuantile(0.50),
+	},
+	"p95": {
+		unit:  "milliseconds",
+		query: durationQuantile(0.95),
+	},
+	"p99": {
+		unit:  "milliseconds",
+		query: durationQuantile(0.99),
+	},
+	"duration_buckets": {
+		unit:    "percent",
+		groupBy: "le",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum by (le) (rate(gateway_function_duration_milliseconds_bucket{%[1]s}[%[2]s])) / ignoring (le) group_left sum(rate(gateway_function_duration_milliseconds_count{%[1]s}[%[2]s])) * 100`,
+				s.selector, s.window)
+		},
+	},
+	"dwell": {
+		unit: "milliseconds",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(rate(gateway_queue_dwell_duration_milliseconds_sum{%[1]s}[%[2]s])) / sum(rate(gateway_queue_dwell_duration_milliseconds_count{%[1]s}[%[2]s]))`,
+				s.selector, s.window)
+		},
+	},
+	"dwell_buckets": {
+		unit:    "requests/second",
+		groupBy: "le",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum by (le) (rate(gateway_queue_dwell_duration_milliseconds_bucket{%s}[%s]))`, s.selector, s.window)
+		},
+	},
+	"cpu": {
+		unit: "seconds",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`sum(rate(gateway_function_cpu_seconds_sum{%[1]s}[%[2]s])) / sum(rate(gateway_function_cpu_seconds_count{%[1]s}[%[2]s]))`,
+				s.selector, s.window)
+		},
+	},
+	"memory": {
+		unit: "bytes",
+		query: func(s seriesScope) string {
+			return fmt.Sprintf(`histogram_quantile(0.95, sum(rate(gateway_function_memory_bytes_bucket{%s}[%s])) by (le))`,
+				s.selector, s.window)
+		},
+	},
+	"replicas": {
+		unit: "replicas",
+		query: func(s seriesScope) string {
+			// Every gateway exports the replicas of every function, a function is counted once
+			return fmt.Sprintf(`sum(max by (function_id) (gateway_service_count{%s}))`, s.selector)
+		},
+	},
+}
+
+func durationQuantile(q float64) func(s seriesScope) string {
+	return func(s seriesScope) string {
+		return fmt.Sprintf(`histogram_quantile(%.2f, sum(rate(gateway_function_duration_milliseconds_bucket{%s}[%s])) by (le))`,
+			q, s.selector, s.window)
+	}
+}
+
+// FunctionSeriesNames returns the names of all the series available through FunctionSeries
+func FunctionSeriesNames() []string {
+	return []string{"invocations", "invocations_total", "errors", "error_percent", "request_rate", "status_rate",
+		"method_rate", "top_paths", "p50", "p95", "p99", "duration_buckets", "dwell", "dwell_buckets", "cpu", "memory", "replicas"}
+}
+
+// IsFunctionSeries checks if the series is available through FunctionSeries
+func IsFunctionSeries(name string) bool {
+	_, exists := functionSeries[name]
+	return exists
+}
+
+// FunctionSeries queries prometheus for curated series scoped to the user, restricted to a
+// single function unless functionID is empty
+func (c *Client) FunctionSeries(userID, functionID string, series []string, start, end time.Time, step time.Duration) ([]types.MetricSeries, *types.Error) {
+	window := step
+	if window < minSeriesWindow {
+		window = minSeriesWindow
+	}
+
+	selector := fmt.Sprintf(`user_id=%s`, strconv.Quote(userID))
+	if functionID != "" {
+		selector += fmt.Sprintf(`,function_id=%s`, strconv.Quote(functionID))
+	}
+
+	scope := seriesScope{
+		selector: selector,
+		window:   fmt.Sprintf("%ds", int64(window.Seconds())),
+	}
+
+	result := []types.MetricSeries{}
+	for _, name := range series {
+		definition, exists := functionSeries[name]
+		if !exists {
+			return nil, types.BadRequestError(fmt.Sprintf("unknown series %q", name))
+		}
+
+		query := url.Values{
+			"query": []string{definition.query(scope)},
+			"start": []string{strconv.FormatInt(start.Unix(), 10)},
+			"end":   []string{strconv.FormatInt(end.Unix(), 10)},
+			"step":  []string{strconv.FormatInt(int64(step.Seconds()), 10)},
+		}
+
+		response, err := c.QueryMetrics("/query_range", query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		matrix, pErr := parseMatrix(response.Data.Result)
+		if pErr != nil {
+			return nil, types.SystemError(pErr.Error())
+		}
+
+		// Aggregated series have a single result, there is none when prometheus had no samples
+		if definition.groupBy == "" {
+			points := []types.MetricPoint{}
+			if len(matrix) > 0 {
+				points = matrix[0].points
+			}
+
+			result = append(result, types.MetricSeries{
+				Name:   name,
+				Unit:   definition.unit,
+				Points: points,
+			})
+			continue
+		}
+
+		for _, m := range matrix {
+			result = append(result, types.MetricSeries{
+				Name:   name,
+				Unit:   definition.unit,
+				Labels: map[string]string{definition.groupBy: m.labels[definition.groupBy]},
+				Points: m.points,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+type matrixSeries struct {
+	labels map[string]string
+	points []types.MetricPoint
+}
+
+// parseMatrix parses the series of a prometheus matrix result
+func parseMatrix(raw json.RawMessage) ([]matrixSeries, error) {
+	var matrix []struct {
+		Metric map[string]string `json:"metric"`
+		Values [][2]interface{}  `json:"values"`
+	}
+
+	if err := json.Unmarshal(raw, &matrix); err != nil {
+		return nil, err
+	}
+
+	result := []matrixSeries{}
+	for _, series := range matrix {
+		points := []types.MetricPoint{}
+		for _, v := range series.Values {
+			ts, ok := v[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("unexpected sample timestamp %v", v[0])
+			}
+
+			str, ok := v[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected sample value %v", v[1])
+			}
+
+			point := types.MetricPoint{Timestamp: int64(ts)}
+			if f, err := strconv.ParseFloat(str, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				point.Value = &f
+			}
+
+			points = append(points, point)
+		}
+
+		result = append(result, matrixSeries{labels: series.Metric, points: points})
+	}
+
+	return result, nil
+}
@@ -25,4 +25,24 @@ const (
 	EventHookType = 1
 	// TimelineHookType represents timeline hook type
 	TimelineHookType = 2
+	// AlertHookType represents alert hook type
+	AlertHookType = 3
+
+	// AlertMetricErrorRate is the percentage of invocations responding with an error
+	AlertMetricErrorRate = "error_rate"
+	// AlertMetricP95Latency is the 95th percentile of invocation duration in milliseconds
+	AlertMetricP95Latency = "p95_latency"
+	// AlertMetricDeadLetters is the number of async invocations that exhausted their retries
+	// within the window, not the backlog of dead letters
+	AlertMetricDeadLetters = "dead_letters"
+
+	// AlertChannelWebhook delivers alert notifications as a JSON POST request
+	AlertChannelWebhook = "webhook"
+	// AlertChannelEmail delivers alert notifications by email
+	AlertChannelEmail = "email"
+
+	// AlertStateFiring is the state of an alert whose rule is breached
+	AlertStateFiring = "firing"
+	// AlertStateResolved is the state of an alert whose rule is no longer breached
+	AlertStateResolved = "resolved"
 )
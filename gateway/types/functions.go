@@ -16,6 +16,49 @@ type FunctionRequest struct {
 	WriteDebug    bool              `json:"write_debug"`
 	ReadTimeout   string            `json:"read_timeout" pattern:"^[1-9]{1}\\d{0,}s$"`
 	WriteTimeout  string            `json:"write_timeout" pattern:"^[1-9]{1}\\d{0,}s$"`
+	LoadBalancing *LoadBalancing    `json:"load_balancing"`
+	Alerts        []AlertRule       `json:"alerts"`
+	AutoDeploy    *AutoDeploy       `json:"auto_deploy"`
+}
+
+// AlertRule represents a condition on the function metrics users are notified about.
+// Threshold is a percentage for error_rate, milliseconds for p95_latency and the number of
+// async invocations that exhausted their retries for dead_letters, all measured over Window.
+type AlertRule struct {
+	Name      string       `json:"name" pattern:"^[A-Za-z0-9][-A-Za-z0-9_. ]{0,62}$" binding:"required"`
+	Metric    string       `json:"metric" enum:"error_rate,p95_latency,dead_letters" binding:"required"`
+	Threshold float64      `json:"threshold" minimum:"0" binding:"required"`
+	Window    string       `json:"window" pattern:"^[1-9]\\d{0,}[mh]$"`
+	Channel   AlertChannel `json:"channel" binding:"required"`
+}
+
+// AlertChannel represents where alert notifications are delivered
+type AlertChannel struct {
+	Type  string `json:"type" enum:"webhook,email" binding:"required"`
+	URL   string `json:"url" pattern:"^https://"`
+	Email string `json:"email" pattern:"^[^@\\s]+@[^@\\s]+$"`
+}
+
+// AutoDeploy represents the image versions a function is rolled forward to once they are built.
+// VersionRange is a semver range of versions of the image name of the function, e.g. ^1.2.0.
+type AutoDeploy struct {
+	VersionRange string `json:"version_range" binding:"required"`
+}
+
+// Rollout represents an automatic update of a function to a newly built image version
+type Rollout struct {
+	ImageID     string    `json:"image_id"`
+	Version     string    `json:"version"`
+	FromImageID string    `json:"from_image_id"`
+	FromVersion string    `json:"from_version"`
+	RolledOutAt time.Time `json:"rolled_out_at"`
+}
+
+// LoadBalancing represents how requests are spread across function replicas
+type LoadBalancing struct {
+	Strategy   string `json:"strategy" enum:"least_outstanding,round_robin,consistent_hash" binding:"required"`
+	HashHeader string `json:"hash_header" pattern:"^[A-Za-z0-9][-A-Za-z0-9_.]{0,61}[A-Za-z0-9]$"`
+	HashCookie string `json:"hash_cookie" pattern:"^[A-Za-z0-9][-A-Za-z0-9_.]{0,61}[A-Za-z0-9]$"`
 }
 
 // DeployFunctionRequest represents a request payload for function deployment
@@ -35,9 +78,24 @@ type MultiFunctionStatusResponse struct {
 	Total   int                      `json:"total_count"`
 }
 
+// RolloutResponse represents the functions rolled out to an image and those that failed to
+type RolloutResponse struct {
+	Objects []FunctionStatusResponse `json:"objects"`
+	Total   int                      `json:"total_count"`
+	Errors  []RolloutError           `json:"errors"`
+}
+
+// RolloutError represents a function that could not be rolled out
+type RolloutError struct {
+	FunctionID   string `json:"function_id"`
+	FunctionName string `json:"function_name"`
+	Error        string `json:"error"`
+}
+
 // FunctionStatusResponse represents a function status response that has been sanitized
 type FunctionStatusResponse struct {
 	ID                string            `json:"id"`
+	UserID            string            `json:"user_id"`
 	ImageID           string            `json:"image_id"`
 	ImageName         string            `json:"image_name"`
 	Name              string            `json:"short_name"`
@@ -52,6 +110,10 @@ type FunctionStatusResponse struct {
 	WriteDebug        bool              `json:"write_debug"`
 	ReadTimeout       string            `json:"read_timeout" pattern:"^[1-9]{1}\\d{0,}s$"`
 	WriteTimeout      string            `json:"write_timeout" pattern:"^[1-9]{1}\\d{0,}s$"`
+	LoadBalancing     *LoadBalancing    `json:"load_balancing"`
+	Alerts            []AlertRule       `json:"alerts"`
+	AutoDeploy        *AutoDeploy       `json:"auto_deploy"`
+	Rollouts          []Rollout         `json:"rollouts"`
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 	DeletedAt         *time.Time        `json:"deleted_at,omitempty"`
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // QueryRequest represents prometheus query request
@@ -24,3 +25,28 @@ type QueryResponse struct {
 		Result     json.RawMessage `json:"result"`
 	}
 }
+
+// FunctionMetricsResponse represents curated metrics series of a function
+type FunctionMetricsResponse struct {
+	FunctionID string         `json:"function_id,omitempty"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Step       int64          `json:"step_seconds"`
+	Series     []MetricSeries `json:"series"`
+}
+
+// MetricSeries represents a single named metrics series. Series split by a label, e.g. the
+// request rate by method, are returned once per label value with the value in Labels.
+type MetricSeries struct {
+	Name   string            `json:"name"`
+	Unit   string            `json:"unit"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []MetricPoint     `json:"points"`
+}
+
+// MetricPoint represents a value of a series at a point in time.
+// Value is null when prometheus had no samples to compute it from.
+type MetricPoint struct {
+	Timestamp int64    `json:"timestamp"`
+	Value     *float64 `json:"value"`
+}
@@ -20,6 +20,9 @@ const (
 
 	// EventLogType represents event log type
 	EventLogType = "event"
+
+	// AlertLogType represents alert log type
+	AlertLogType = "alert"
 )
 
 // Message represents message sent over nats
@@ -46,6 +49,7 @@ type Log struct {
 	Message
 	TimelineLog *TimelineLog `json:"timeline_log,omitempty"`
 	EventLog    *EventLog    `json:"event_log,omitempty"`
+	AlertLog    *AlertLog    `json:"alert_log,omitempty"`
 }
 
 // TimelineLog represents timeline log message payload
@@ -56,6 +60,7 @@ type TimelineLog struct {
 	Response   int       `json:"response"`
 	Method     string    `json:"method"`
 	Duration   int64     `json:"duration"`
+	TraceID    string    `json:"trace_id,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
@@ -66,10 +71,28 @@ type EventLog struct {
 	FunctionName string                 `json:"function_name"`
 	FunctionID   string                 `json:"function_id"`
 	Message      string                 `json:"message"`
+	Level        string                 `json:"level,omitempty"`
+	Fields       map[string]interface{} `json:"fields,omitempty"`
+	CPUSeconds   *float64               `json:"cpu_seconds,omitempty"`
+	MemoryBytes  int64                  `json:"memory_bytes,omitempty"`
 	Payload      map[string]interface{} `json:"payload,omitempty"`
 	CreatedAt    time.Time              `json:"generated_at"`
 }
 
+// AlertLog represents a state change of a function alert rule
+type AlertLog struct {
+	FunctionID   string    `json:"function_id"`
+	FunctionName string    `json:"function_name"`
+	RuleName     string    `json:"rule_name"`
+	Metric       string    `json:"metric"`
+	Threshold    float64   `json:"threshold"`
+	Value        float64   `json:"value"`
+	State        string    `json:"state"`
+	Channel      string    `json:"channel"`
+	NotifyError  string    `json:"notify_error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // QueueRequestMessage for sending async request message via stan
 type QueueRequestMessage struct {
 	Message
@@ -87,5 +110,6 @@ type QueueRequest struct {
 	FunctionID   string      `json:"function_id"`
 	FunctionName string      `json:"function_name"`
 	CallbackURL  string      `json:"callback_url"`
+	AffinityKey  string      `json:"affinity_key"`
 	QueuedAt     time.Time   `json:"queued_at"`
 }
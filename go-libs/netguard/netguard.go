@@ -0,0 +1,91 @@
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// blockedNetworks are not reachable from the internet, connecting to them on behalf of users
+// would let them reach the services of the cluster and the cloud metadata endpoints
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link local, cloud metadata
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved, broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // IPv4 translation
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link local
+	"ff00::/8",       // multicast
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// IsPublic reports whether ip is a public unicast address
+func IsPublic(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckHost resolves host and fails when any of its addresses is not public
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !IsPublic(addr.IP) {
+			return fmt.Errorf("%s resolves to the non public address %s", host, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to addresses that are not
+// public. It runs after the host was resolved, so a host resolving to a public address
+// when validated can not be rebound to a private one when connected to.
+func Control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublic(ip) {
+		return fmt.Errorf("connecting to the non public address %s is not allowed", host)
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version represents a major.minor.patch version with optional prerelease identifiers,
+// e.g. 1.2.3-rc.1
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease []string
+}
+
+// Parse parses a major.minor.patch version optionally followed by -prerelease identifiers,
+// build metadata after a plus sign is ignored
+func Parse(s string) (Version, error) {
+	core, prerelease, err := splitPrerelease(s)
+	if err != nil {
+		return Version{}, err
+	}
+
+	parts, n, err := parsePartial(core)
+	if err != nil {
+		return Version{}, err
+	}
+
+	if n != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2], Prerelease: prerelease}, nil
+}
+
+// splitPrerelease splits a version into its core and prerelease identifiers, dropping build metadata
+func splitPrerelease(s string) (string, []string, error) {
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return s, nil, nil
+	}
+
+	prerelease := strings.Split(s[i+1:], ".")
+	for _, identifier := range prerelease {
+		if identifier == "" || strings.Trim(identifier, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-") != "" {
+			return "", nil, fmt.Errorf("invalid prerelease %q", s[i+1:])
+		}
+
+		if isNumeric(identifier) && len(identifier) > 1 && identifier[0] == '0' {
+			return "", nil, fmt.Errorf("invalid prerelease %q", s[i+1:])
+		}
+	}
+
+	return s[:i], prerelease, nil
+}
+
+func isNumeric(identifier string) bool {
+	return strings.Trim(identifier, "0123456789") == ""
+}
+
+// Compare returns a negative number when v is lower than o, zero when they are equal
+// and a positive number when v is greater than o. A prerelease is lower than its release.
+func (v Version) Compare(o Version) int {
+	if v.Major != o.Major {
+		return v.Major - o.Major
+	}
+
+	if v.Minor != o.Minor {
+		return v.Minor - o.Minor
+	}
+
+	if v.Patch != o.Patch {
+		return v.Patch - o.Patch
+	}
+
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease orders prerelease identifiers, numeric ones numerically and lower than
+// alphanumeric ones, those lexically. A shorter list of otherwise equal identifiers is lower.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+
+		aNumeric, bNumeric := isNumeric(a[i]), isNumeric(b[i])
+		switch {
+		case aNumeric && bNumeric:
+			x, _ := strconv.Atoi(a[i])
+			y, _ := strconv.Atoi(b[i])
+			return x - y
+		case aNumeric:
+			return -1
+		case bNumeric:
+			return 1
+		case a[i] < b[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	return len(a) - len(b)
+}
+
+// NextPatch returns the release following v, the release of its core version when v is a
+// prerelease, e.g. 1.2.3 for 1.2.3-rc.1, and the next patch version otherwise
+func (v Version) NextPatch() Version {
+	next := Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	if len(v.Prerelease) == 0 {
+		next.Patch++
+	}
+
+	return next
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+
+	return s
+}
+
+// Range represents the versions satisfying all of its comparators
+type Range []comparator
+
+type comparator struct {
+	op      string
+	version Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	return cmp == 0
+}
+
+// ParseRange parses a space separated list of comparators a version must all satisfy.
+// A comparator is a version optionally prefixed with =, >, >=, < or <=, a caret range
+// (^1.2.3 allows changes not modifying the left-most non-zero part), a tilde range
+// (~1.2.3 allows patch changes) or a partial version with the missing parts or x as
+// wildcards (1.2.x). An empty range or * matches every version.
+func ParseRange(s string) (Range, error) {
+	r := Range{}
+	for _, field := range strings.Fields(s) {
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %s", s, err)
+		}
+		r = append(r, comparators...)
+	}
+
+	return r, nil
+}
+
+// Contains reports whether the version satisfies the range. A prerelease only satisfies a
+// range with a prerelease of the same major.minor.patch, so that 1.3.0-rc.1 is not
+// deployed to functions tracking ^1.2.0.
+func (r Range) Contains(v Version) bool {
+	for _, c := range r {
+		if !c.matches(v) {
+			return false
+		}
+	}
+
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+
+	for _, c := range r {
+		if len(c.version.Prerelease) > 0 && c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			v, err := Parse(strings.TrimPrefix(s, op))
+			if err != nil {
+				return nil, err
+			}
+			return []comparator{{op, v}}, nil
+		}
+	}
+
+	prefix := ""
+	if strings.HasPrefix(s, "^") || strings.HasPrefix(s, "~") {
+		prefix, s = s[:1], s[1:]
+	}
+
+	core, prerelease, err := splitPrerelease(s)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, n, err := parsePartial(core)
+	if err != nil {
+		return nil, err
+	}
+
+	if prerelease != nil && n != 3 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+
+	lower := Version{Major: parts[0], Minor: parts[1], Patch: parts[2], Prerelease: prerelease}
+	var upper Version
+	switch {
+	case n == 0:
+		return nil, nil
+	case prefix == "" && n == 3:
+		return []comparator{{"=", lower}}, nil
+	case prefix == "^" && (lower.Major > 0 || n == 1):
+		upper = Version{Major: lower.Major + 1}
+	case prefix == "^" && (lower.Minor > 0 || n == 2):
+		upper = Version{Minor: lower.Minor + 1}
+	case prefix == "^":
+		upper = Version{Patch: lower.Patch + 1}
+	case n == 1:
+		upper = Version{Major: lower.Major + 1}
+	default:
+		upper = Version{Major: lower.Major, Minor: lower.Minor + 1}
+	}
+
+	return []comparator{{">=", lower}, {"<", upper}}, nil
+}
+
+// parsePartial parses up to three dot separated numbers, it returns them with the missing
+// ones set to zero and the number of leading parts that were given
+func parsePartial(s string) ([3]int, int, error) {
+	var parts [3]int
+	if s == "*" {
+		return parts, 0, nil
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return parts, 0, fmt.Errorf("invalid version %q", s)
+	}
+
+	n := 0
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			break
+		}
+
+		value, err := strconv.Atoi(field)
+		if err != nil || value < 0 {
+			return parts, 0, fmt.Errorf("invalid version %q", s)
+		}
+
+		parts[i] = value
+		n++
+	}
+
+	return parts, n, nil
+}
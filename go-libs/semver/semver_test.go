@@ -0,0 +1,266 @@
+package semver
+
+import (
+	"sort"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		valid   bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"0.0.0", "0.0.0", true},
+		{"1.2.3-rc.1", "1.2.3-rc.1", true},
+		{"1.2.3-alpha-1.0", "1.2.3-alpha-1.0", true},
+		{"1.2.3+build.5", "1.2.3", true},
+		{"1.2.3-beta+build.5", "1.2.3-beta", true},
+		{"1.2", "", false},
+		{"1.2.3.4", "", false},
+		{"1.2.x", "", false},
+		{"v1.2.3", "", false},
+		{"1.2.3-", "", false},
+		{"1.2.3-rc..1", "", false},
+		{"1.2.3-01", "", false},
+		{"1.2.3-rc_1", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		v, err := Parse(test.version)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Parse(%q): want an error, got %s", test.version, v)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Parse(%q): want %s, got error %s", test.version, test.want, err)
+			continue
+		}
+		if v.String() != test.want {
+			t.Errorf("Parse(%q): want %s, got %s", test.version, test.want, v)
+		}
+	}
+}
+
+func Test_Compare_PrereleaseOrdering(t *testing.T) {
+	// Ordered as in https://semver.org/#spec-item-11
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1-0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+
+	versions := make([]Version, len(ordered))
+	for i, s := range ordered {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", s, err)
+		}
+		versions[i] = v
+	}
+
+	for i := range versions {
+		for j := range versions {
+			cmp := versions[i].Compare(versions[j])
+			if (i < j && cmp >= 0) || (i > j && cmp <= 0) || (i == j && cmp != 0) {
+				t.Errorf("Compare(%s, %s): got %d", versions[i], versions[j], cmp)
+			}
+		}
+	}
+
+	shuffled := []Version{versions[7], versions[0], versions[5], versions[11], versions[2], versions[4]}
+	sort.Slice(shuffled, func(i, j int) bool { return shuffled[i].Compare(shuffled[j]) < 0 })
+	want := []string{"1.0.0-alpha", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "2.0.0"}
+	for i, v := range shuffled {
+		if v.String() != want[i] {
+			t.Errorf("Want sorted %v, got %v", want, shuffled)
+			break
+		}
+	}
+}
+
+func Test_Range_Contains(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "caret major",
+			r:        "^1.2.3",
+			contains: []string{"1.2.3", "1.2.10", "1.9.0"},
+			excludes: []string{"1.2.2", "2.0.0", "0.9.9"},
+		},
+		{
+			name:     "caret zero major",
+			r:        "^0.2.3",
+			contains: []string{"0.2.3", "0.2.9"},
+			excludes: []string{"0.2.2", "0.3.0", "1.0.0"},
+		},
+		{
+			name:     "caret zero minor",
+			r:        "^0.0.3",
+			contains: []string{"0.0.3"},
+			excludes: []string{"0.0.4", "0.1.0"},
+		},
+		{
+			name:     "caret partial",
+			r:        "^1",
+			contains: []string{"1.0.0", "1.99.99"},
+			excludes: []string{"0.9.0", "2.0.0"},
+		},
+		{
+			name:     "caret zero partial",
+			r:        "^0.2",
+			contains: []string{"0.2.0", "0.2.7"},
+			excludes: []string{"0.1.9", "0.3.0"},
+		},
+		{
+			name:     "tilde",
+			r:        "~1.2.3",
+			contains: []string{"1.2.3", "1.2.99"},
+			excludes: []string{"1.2.2", "1.3.0"},
+		},
+		{
+			name:     "tilde major only",
+			r:        "~1",
+			contains: []string{"1.0.0", "1.5.0"},
+			excludes: []string{"2.0.0"},
+		},
+		{
+			name:     "x minor",
+			r:        "1.x",
+			contains: []string{"1.0.0", "1.9.9"},
+			excludes: []string{"0.9.9", "2.0.0"},
+		},
+		{
+			name:     "x patch",
+			r:        "1.2.x",
+			contains: []string{"1.2.0", "1.2.9"},
+			excludes: []string{"1.1.9", "1.3.0"},
+		},
+		{
+			name:     "partial",
+			r:        "1.2",
+			contains: []string{"1.2.0", "1.2.5"},
+			excludes: []string{"1.3.0"},
+		},
+		{
+			name:     "any",
+			r:        "*",
+			contains: []string{"0.0.0", "9.9.9"},
+		},
+		{
+			name:     "empty",
+			r:        "",
+			contains: []string{"0.0.1", "3.2.1"},
+		},
+		{
+			name:     "exact",
+			r:        "1.2.3",
+			contains: []string{"1.2.3"},
+			excludes: []string{"1.2.4"},
+		},
+		{
+			name:     "comparator set",
+			r:        ">=1.2.0 <1.5.0",
+			contains: []string{"1.2.0", "1.4.99"},
+			excludes: []string{"1.1.9", "1.5.0"},
+		},
+		{
+			name:     "exclusive and inclusive bounds",
+			r:        ">1.2.0 <=1.3.0",
+			contains: []string{"1.2.1", "1.3.0"},
+			excludes: []string{"1.2.0", "1.3.1"},
+		},
+		{
+			name:     "prerelease excluded from release ranges",
+			r:        "^1.2.0",
+			contains: []string{"1.3.0"},
+			excludes: []string{"1.3.0-rc.1", "2.0.0-rc.1"},
+		},
+		{
+			name:     "prerelease of the same version",
+			r:        "^1.2.3-beta.2",
+			contains: []string{"1.2.3-beta.2", "1.2.3-beta.10", "1.2.3-rc.1", "1.2.3", "1.4.0"},
+			excludes: []string{"1.2.3-beta.1", "1.2.3-alpha", "1.2.4-rc.1"},
+		},
+		{
+			name:     "prerelease comparator",
+			r:        ">=1.0.0-rc.1 <2.0.0",
+			contains: []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0"},
+			excludes: []string{"1.0.0-beta", "1.1.0-rc.1"},
+		},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRange(test.r)
+		if err != nil {
+			t.Errorf("%s: ParseRange(%q): %s", test.name, test.r, err)
+			continue
+		}
+
+		for _, s := range test.contains {
+			if !r.Contains(mustParse(t, s)) {
+				t.Errorf("%s: want %q to contain %s", test.name, test.r, s)
+			}
+		}
+
+		for _, s := range test.excludes {
+			if r.Contains(mustParse(t, s)) {
+				t.Errorf("%s: want %q to exclude %s", test.name, test.r, s)
+			}
+		}
+	}
+}
+
+func Test_ParseRange_Invalid(t *testing.T) {
+	for _, r := range []string{"^a.b.c", ">=1.2", "~1.2.3.4", "1.2.x-rc.1", "^1.2.3-", "=>1.2.3", "!=1.4.0"} {
+		if _, err := ParseRange(r); err == nil {
+			t.Errorf("ParseRange(%q): want an error", r)
+		}
+	}
+}
+
+func Test_NextPatch(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.2.3", "1.2.4"},
+		{"0.0.0", "0.0.1"},
+		{"1.2.3-rc.1", "1.2.3"},
+		{"1.2.3-alpha-1.0", "1.2.3"},
+		{"1.2.3+build.5", "1.2.4"},
+	}
+
+	for _, test := range tests {
+		if next := mustParse(t, test.version).NextPatch(); next.String() != test.want {
+			t.Errorf("NextPatch(%s): want %s, got %s", test.version, test.want, next)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %s", s, err)
+	}
+
+	return v
+}
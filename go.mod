@@ -47,6 +47,10 @@ require (
 	github.com/sirupsen/logrus v1.7.0
 	github.com/ugorji/go v1.1.5-pre // indirect
 	go.mongodb.org/mongo-driver v1.5.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
 	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b // indirect
 	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4 // indirect
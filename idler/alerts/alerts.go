@@ -0,0 +1,167 @@
+package alerts
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	gwt "eywa/gateway/types"
+	"eywa/go-libs/trigger"
+	"eywa/idler/clients/prometheus"
+	"eywa/idler/clients/tracker"
+)
+
+const defaultWindow = "5m"
+
+// Evaluator evaluates function alert rules against prometheus and notifies on state changes.
+// Every state change is stored in the alert history of the execution tracker, the firing
+// alerts are restored from it so a restart neither fires them again nor loses their resolve.
+type Evaluator struct {
+	prometheus *prometheus.Client
+	tracker    *tracker.Client
+	notifier   *Notifier
+	firing     map[string]bool
+	restored   bool
+}
+
+// New returns a new alert evaluator
+func New(prometheus *prometheus.Client, tracker *tracker.Client, notifier *Notifier) *Evaluator {
+	return &Evaluator{
+		prometheus: prometheus,
+		tracker:    tracker,
+		notifier:   notifier,
+		firing:     make(map[string]bool),
+	}
+}
+
+// restore loads the alerts that were firing before the evaluator started
+func (e *Evaluator) restore() error {
+	alerts, err := e.tracker.GetFiringAlerts()
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		e.firing[alert.FunctionID+"/"+alert.RuleName] = true
+	}
+
+	log.Infof("Restored %d firing alerts", len(alerts))
+	e.restored = true
+	return nil
+}
+
+// Evaluate checks every alert rule of the functions once. Nothing is evaluated until the
+// firing alerts were restored, notifications would be sent again otherwise.
+func (e *Evaluator) Evaluate(functions []gwt.FunctionStatusResponse) {
+	if !e.restored {
+		if err := e.restore(); err != nil {
+			log.Errorf("Failed to restore firing alerts: %s", err)
+			return
+		}
+	}
+
+	evaluated := make(map[string]struct{})
+	for _, fn := range functions {
+		for _, rule := range fn.Alerts {
+			key := fn.ID + "/" + rule.Name
+			evaluated[key] = struct{}{}
+
+			query, err := ruleQuery(fn.ID, rule)
+			if err != nil {
+				log.Errorf("Invalid alert rule %q of %s: %s", rule.Name, fn.Name, err)
+				continue
+			}
+
+			value, found, err := e.prometheus.QueryValue(query)
+			if err != nil {
+				log.Errorf("Failed to evaluate alert rule %q of %s: %s", rule.Name, fn.Name, err)
+				continue
+			}
+
+			breached := found && value > rule.Threshold
+			if breached == e.firing[key] {
+				continue
+			}
+
+			state := gwt.AlertStateResolved
+			if breached {
+				state = gwt.AlertStateFiring
+				e.firing[key] = true
+			} else {
+				delete(e.firing, key)
+			}
+
+			e.transition(fn, rule, state, value)
+		}
+	}
+
+	// Forget rules that were removed or whose function was deleted
+	for key := range e.firing {
+		if _, exists := evaluated[key]; !exists {
+			delete(e.firing, key)
+		}
+	}
+}
+
+func (e *Evaluator) transition(fn gwt.FunctionStatusResponse, rule gwt.AlertRule, state string, value float64) {
+	log.Infof("%s\talert %q %s: %s %f (threshold %f)\n", fn.Name, rule.Name, state, rule.Metric, value, rule.Threshold)
+
+	fields := trigger.Fields{
+		"user_id":       fn.UserID,
+		"function_id":   fn.ID,
+		"function_name": fn.Name,
+		"rule_name":     rule.Name,
+		"metric":        rule.Metric,
+		"threshold":     rule.Threshold,
+		"value":         value,
+		"state":         state,
+		"channel":       rule.Channel.Type,
+	}
+
+	err := e.notifier.Notify(rule.Channel, &Notification{
+		FunctionID:   fn.ID,
+		FunctionName: fn.Name,
+		RuleName:     rule.Name,
+		Metric:       rule.Metric,
+		Threshold:    rule.Threshold,
+		Value:        value,
+		Window:       windowOf(rule),
+		State:        state,
+		Timestamp:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Errorf("Failed to notify %s about alert %q of %s: %s", rule.Channel.Type, rule.Name, fn.Name, err)
+		fields["notify_error"] = err.Error()
+	}
+
+	trigger.WithFields(fields).Fire(gwt.AlertHookType)
+}
+
+func windowOf(rule gwt.AlertRule) string {
+	if rule.Window == "" {
+		return defaultWindow
+	}
+
+	return rule.Window
+}
+
+// ruleQuery builds the PromQL query returning the current value of the rule metric
+func ruleQuery(functionID string, rule gwt.AlertRule) (string, error) {
+	selector := "function_id=" + strconv.Quote(functionID)
+	window := windowOf(rule)
+
+	switch rule.Metric {
+	case gwt.AlertMetricErrorRate:
+		return fmt.Sprintf(`100 * sum(increase(gateway_function_invocation_total{%[1]s,code=~"4..|5.."}[%[2]s])) / sum(increase(gateway_function_invocation_total{%[1]s}[%[2]s]))`,
+			selector, window), nil
+	case gwt.AlertMetricP95Latency:
+		return fmt.Sprintf(`histogram_quantile(0.95, sum(rate(gateway_function_duration_milliseconds_bucket{%s}[%s])) by (le))`,
+			selector, window), nil
+	case gwt.AlertMetricDeadLetters:
+		return fmt.Sprintf(`sum(increase(gateway_async_dead_letters_total{%s}[%s]))`, selector, window), nil
+	}
+
+	return "", fmt.Errorf("unknown metric %q", rule.Metric)
+}
@@ -0,0 +1,137 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/resty.v1"
+
+	gwt "eywa/gateway/types"
+	"eywa/go-libs/netguard"
+)
+
+// Notification is the payload delivered to alert channels
+type Notification struct {
+	FunctionID   string    `json:"function_id"`
+	FunctionName string    `json:"function_name"`
+	RuleName     string    `json:"rule_name"`
+	Metric       string    `json:"metric"`
+	Threshold    float64   `json:"threshold"`
+	Value        float64   `json:"value"`
+	Window       string    `json:"window"`
+	State        string    `json:"state"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// EmailSender sends plain text emails
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// Notifier delivers notifications to webhook and email channels
+type Notifier struct {
+	rc    *resty.Client
+	email EmailSender
+}
+
+// NewNotifier returns a new notifier, email may be nil when email delivery is not configured.
+// Webhooks are user supplied URLs, they are only delivered to public addresses without
+// following redirects.
+func NewNotifier(email EmailSender) *Notifier {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: netguard.Control,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &Notifier{
+		rc: resty.New().
+			SetTransport(transport).
+			SetRedirectPolicy(resty.NoRedirectPolicy()).
+			SetLogger(ioutil.Discard).
+			SetRetryCount(2).
+			SetTimeout(10 * time.Second),
+		email: email,
+	}
+}
+
+// Notify delivers the notification to the channel
+func (n *Notifier) Notify(channel gwt.AlertChannel, notification *Notification) error {
+	switch channel.Type {
+	case gwt.AlertChannelWebhook:
+		webhookURL, err := url.Parse(channel.URL)
+		if err != nil {
+			return err
+		}
+
+		if webhookURL.Scheme != "https" {
+			return errors.New("webhook url must use https")
+		}
+
+		resp, err := n.rc.R().
+			SetHeader("Content-Type", "application/json").
+			SetBody(notification).
+			Post(channel.URL)
+		if err != nil {
+			return err
+		}
+
+		if resp.IsError() {
+			return fmt.Errorf("webhook responded with unexpected status: %s", resp.Status())
+		}
+
+		return nil
+	case gwt.AlertChannelEmail:
+		if n.email == nil {
+			return errors.New("email notifications are not configured")
+		}
+
+		subject := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(notification.State), notification.FunctionName, notification.RuleName)
+		body := fmt.Sprintf("Function: %s (%s)\nRule: %s\nMetric: %s over %s\nValue: %g\nThreshold: %g\nState: %s\nTime: %s\n",
+			notification.FunctionName, notification.FunctionID, notification.RuleName, notification.Metric,
+			notification.Window, notification.Value, notification.Threshold, notification.State,
+			notification.Timestamp.Format(time.RFC3339))
+
+		return n.email.Send(channel.Email, subject, body)
+	}
+
+	return fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send sends a plain text email
+func (s *SMTPSender) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	return smtp.SendMail(addr, auth, s.From, []string{to}, []byte(msg))
+}
@@ -3,6 +3,8 @@ package prometheus
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
+	"strconv"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -43,3 +45,32 @@ func (c *Client) QueryMetrics(query string) (*VectorQueryResponse, error) {
 
 	return &result, nil
 }
+
+// QueryValue queries prometheus for an aggregated metric and returns the value of its
+// first sample. Found is false when the query returned no samples or a NaN.
+func (c *Client) QueryValue(query string) (value float64, found bool, err error) {
+	res, err := c.QueryMetrics(query)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(res.Data.Result) == 0 || len(res.Data.Result[0].Value) < 2 {
+		return 0, false, nil
+	}
+
+	str, ok := res.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected sample value %v", res.Data.Result[0].Value[1])
+	}
+
+	value, err = strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, nil
+	}
+
+	return value, true, nil
+}
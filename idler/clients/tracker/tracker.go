@@ -0,0 +1,49 @@
+package tracker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	ett "eywa/execution-tracker/types"
+	"eywa/go-libs/auth"
+)
+
+// Client represents execution tracker client
+type Client struct {
+	rc *resty.Client
+}
+
+// New returns a new execution tracker client
+func New(trackerURL string) *Client {
+	return &Client{
+		rc: resty.New().
+			SetHostURL(trackerURL).
+			SetLogger(ioutil.Discard).
+			SetRetryCount(3).
+			SetTimeout(10 * time.Second),
+	}
+}
+
+// GetFiringAlerts retrieves the alerts of all users that are firing
+func (c *Client) GetFiringAlerts() ([]ett.AlertLog, error) {
+	var result ett.FiringAlertsResponse
+	resp, err := c.rc.R().
+		SetResult(&result).
+		SetHeader("X-Eywa-User-Id", auth.OperatorUserID).
+		SetHeader("X-Eywa-Real-User-Id", auth.OperatorUserID).
+		Get("/eywa/api/alerts/firing")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		log.Errorf(string(resp.Body()))
+		return nil, fmt.Errorf("execution tracker responded with unexpected status: %s", resp.Status())
+	}
+
+	return result.Objects, nil
+}
@@ -2,28 +2,44 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
 
+	"eywa/gateway/hooks"
 	gwt "eywa/gateway/types"
+	"eywa/go-libs/broker"
+	"eywa/go-libs/trigger"
+	"eywa/idler/alerts"
 	"eywa/idler/clients/gateway"
 	"eywa/idler/clients/prometheus"
+	"eywa/idler/clients/tracker"
 )
 
 // Config represents gateway startup configuration
 type Config struct {
 	GatewayURL         string        `envconfig:"gateway_url" default:"http://gateway-api.faas-system:8080"`
 	PrometheusURL      string        `envconfig:"prometheus_url" default:"http://prometheus-operator-kube-p-prometheus.faas-system:9090"`
+	TrackerURL         string        `envconfig:"tracker_url" default:"http://execution-tracker-api.faas-system:10080"`
 	InactivityDuration time.Duration `envconfig:"inactivity_duration" default:"1m"`
+	NatsURL            string        `envconfig:"nats_url" default:"nats://nats.nats:4222"`
+	StanClusterID      string        `envconfig:"stan_cluster_id" default:"stan"`
+	StanClientID       string        `envconfig:"stan_client_id" default:"idler"`
+	SMTPHost           string        `envconfig:"smtp_host"`
+	SMTPPort           int           `envconfig:"smtp_port" default:"587"`
+	SMTPUsername       string        `envconfig:"smtp_username"`
+	SMTPPassword       string        `envconfig:"smtp_password"`
+	SMTPFrom           string        `envconfig:"smtp_from" default:"alerts@eywa.rekfuki.dev"`
 }
 
 // Idler represents the idler object
 type Idler struct {
 	gateway            *gateway.Client
 	prometheus         *prometheus.Client
+	alerts             *alerts.Evaluator
 	reconcileInterval  time.Duration
 	inactivityDuration time.Duration
 }
@@ -35,9 +51,37 @@ func main() {
 		log.Fatalf("Failed to parse env: %s", err)
 	}
 
+	hostname, _ := os.Hostname()
+	bc, err := broker.Connect(&broker.Config{
+		NatsURL:        conf.NatsURL,
+		ClusterID:      conf.StanClusterID,
+		ClientID:       conf.StanClientID + broker.GetClientID(hostname),
+		MaxReconnect:   100,
+		ReconnectDelay: 5,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup nats-streaming broker: %s", err)
+	}
+
+	alertHook := broker.NewLogHandler(gwt.LogsSubject, bc, hooks.AlertHook, false)
+	trigger.AddHook(alertHook, []trigger.Type{gwt.AlertHookType})
+
+	var email alerts.EmailSender
+	if conf.SMTPHost != "" {
+		email = &alerts.SMTPSender{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.SMTPFrom,
+		}
+	}
+
+	prometheusClient := prometheus.New(conf.PrometheusURL)
 	idler := Idler{
 		gateway:            gateway.New(conf.GatewayURL),
-		prometheus:         prometheus.New(conf.PrometheusURL),
+		prometheus:         prometheusClient,
+		alerts:             alerts.New(prometheusClient, tracker.New(conf.TrackerURL), alerts.NewNotifier(email)),
 		reconcileInterval:  time.Second * 30,
 		inactivityDuration: conf.InactivityDuration,
 	}
@@ -72,6 +116,8 @@ func (i *Idler) Reconcile() {
 				}
 			}
 		}
+
+		i.alerts.Evaluate(functions)
 		time.Sleep(i.reconcileInterval)
 	}
 }
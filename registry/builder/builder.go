@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -11,49 +12,69 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 
+	"eywa/registry/clients/gateway"
+	"eywa/registry/clients/k8s"
 	"eywa/registry/db"
+	"eywa/registry/scanner"
 	"eywa/registry/types"
 )
 
-var (
-	templateLocations = map[string]string{
-		"go":      "./templates/go",
-		"node14":  "./templates/node14",
-		"python3": "./templates/python3",
-		"ruby":    "./templates/ruby",
-		"csharp":  "./templates/csharp",
-		"custom":  "./templates/custom",
-	}
-	requiredFiles = map[string][]string{
-		"go":      {"handler.go"},
-		"node14":  {"handler.js", "package.json"},
-		"python3": {"handler.py", "requirements.txt"},
-		"ruby":    {"handler.rb", "Gemfile"},
-		"csharp":  {"Function.csproj", "FunctionHandler.cs"},
-	}
-)
-
 // Config represents builder config
 type Config struct {
 	Registry         string
 	RegistryUser     string
 	RegistryPassword string
 	DB               *db.Client
+	K8s              *k8s.Client
+	Gateway          *gateway.Client
 	NumWorkers       int
+	// BuildTimeout is how long a build can run before it is stopped
+	BuildTimeout time.Duration
+	// BuildCgroup is the cgroup v2 directory the cgroups limiting every build to BuildCPULimit
+	// cores and BuildMemoryLimit bytes are created in, builds are not limited when it is empty
+	BuildCgroup      string
+	BuildCPULimit    float64
+	BuildMemoryLimit int64
+	// DockerfileTimeout and DockerfileMaxSize limit the builds of dockerfile runtime images
+	DockerfileTimeout time.Duration
+	DockerfileMaxSize int64
+	// CacheDir holds the build caches of every user runtime, caching is disabled when empty.
+	// CacheMaxSize is the size the caches are trimmed to.
+	CacheDir     string
+	CacheMaxSize int64
+	// Scanner scans the pushed images for vulnerabilities, images are not scanned when it is nil
+	Scanner scanner.Scanner
 }
 
 // Client represents the builder client
 type Client struct {
-	registry         string
-	db               *db.Client
-	incomingBuilds   chan BuildRequest
-	numWorkers       int
-	inProgressBuilds map[string]BuildRequest
+	registry          string
+	db                *db.Client
+	k8s               *k8s.Client
+	gateway           *gateway.Client
+	numWorkers        int
+	buildTimeout      time.Duration
+	cgroupDir         string
+	buildCPULimit     float64
+	buildMemoryLimit  int64
+	runtimes          map[string]types.Runtime
+	dockerfileTimeout time.Duration
+	dockerfileMaxSize int64
+	cacheDir          string
+	cacheMaxSize      int64
+	cacheMutex        sync.Mutex
+	cacheLocks        map[string]*cacheLock // cacheLocks are the locks of the caches used by builds
+	scanner           scanner.Scanner
+	runningMutex      sync.Mutex
+	running           map[string]context.CancelFunc // running are the builds run by this replica
+	wakeup            chan struct{}                 // wakeup signals idle workers that a build was queued
 }
 
 // New creates a new builder client
@@ -63,138 +84,244 @@ func New(conf *Config) (*Client, error) {
 		return nil, err
 	}
 
+	runtimes, err := loadRuntimes(templatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cgroupDir := conf.BuildCgroup
+	if cgroupDir != "" {
+		if err := setupCgroup(cgroupDir); err != nil {
+			log.Warnf("Builds run without resource limits, failed to set up cgroup %s: %s", cgroupDir, err)
+			cgroupDir = ""
+		}
+	}
+
 	return &Client{
-		registry:         conf.Registry,
-		db:               conf.DB,
-		incomingBuilds:   make(chan BuildRequest),
-		numWorkers:       conf.NumWorkers,
-		inProgressBuilds: make(map[string]BuildRequest),
+		registry:          conf.Registry,
+		db:                conf.DB,
+		k8s:               conf.K8s,
+		gateway:           conf.Gateway,
+		numWorkers:        conf.NumWorkers,
+		buildTimeout:      conf.BuildTimeout,
+		cgroupDir:         cgroupDir,
+		buildCPULimit:     conf.BuildCPULimit,
+		buildMemoryLimit:  conf.BuildMemoryLimit,
+		runtimes:          runtimes,
+		dockerfileTimeout: conf.DockerfileTimeout,
+		dockerfileMaxSize: conf.DockerfileMaxSize,
+		cacheDir:          conf.CacheDir,
+		cacheMaxSize:      conf.CacheMaxSize,
+		cacheLocks:        map[string]*cacheLock{},
+		scanner:           conf.Scanner,
+		running:           map[string]context.CancelFunc{},
+		wakeup:            make(chan struct{}, conf.NumWorkers),
 	}, nil
 }
 
-// Start starts the builder
+// Start recovers the builds interrupted by a previous run and starts the workers
+// processing the build queue
 func (c *Client) Start() {
+	c.recoverBuilds()
+	go c.reapBuilds()
+
 	for i := 0; i < c.numWorkers; i++ {
-		go func() {
-			for br := range c.incomingBuilds {
-				logs := []string{}
+		go c.work()
+	}
+}
 
-				state := StateSuccess
-				if err := c.db.UpdateImageState(br.ImageID, StateBuilding); err != nil {
-					log.Errorf("Failed to update image state: %s", err)
-					logs = append(logs, BuildSystemErrorMessage(err.Error()))
-					state = StateFailed
-				} else {
-					buildErr := c.build(br)
-
-					logFile, err := os.OpenFile(br.LogFile, os.O_APPEND|os.O_RDWR, 0666)
-					if err != nil {
-						logs = append(logs, BuildSystemErrorMessage(err.Error()))
-					} else {
-						if buildErr != nil {
-							log.WithFields(log.Fields{
-								"Image ID": br.ImageID,
-								"Runtime":  br.Runtime,
-								"Version":  br.Version,
-							}).Errorf("Failed to build container image", buildErr)
-
-							state = StateFailed
-							errMsg := ""
-							if buildErr.Type == ErrTypeSystemError {
-								errMsg = BuildSystemErrorMessage(ErrInternalError.String())
-							} else if buildErr.Type == ErrTypeUserError {
-								errMsg = BuildUserErrorMessage(buildErr.String())
-							} else if buildErr.Type == ErrTypeBuild {
-								errMsg = BuildErrorMessage(buildErr.String())
-							}
-
-							_, err = logFile.WriteString(errMsg + BuildFailedMessage())
-							if err != nil {
-								logs = append(logs, BuildSystemErrorMessage(err.Error()))
-							}
-						}
-
-						logFile.Seek(0, io.SeekStart)
-
-						scanner := bufio.NewScanner(logFile)
-						scanner.Split(bufio.ScanLines)
-						for scanner.Scan() {
-							logs = append(logs, scanner.Text())
-						}
-					}
-				}
-				if err := c.db.UpdateBuild(br.ImageID, state, logs); err != nil {
-					log.Errorf("Failed to update build: %s", err)
+// run runs a claimed build and stores its outcome
+func (c *Client) run(build *types.Build) {
+	logs := []string{}
+	state := StateSuccess
+
+	br, err := c.request(build)
+	if err != nil {
+		log.Errorf("Failed to prepare build: %s", err)
+		logs = append(build.Logs, BuildSystemErrorMessage(err.Error()), BuildFailedMessage())
+		state = StateFailed
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), c.buildTimeout)
+		defer cancel()
+
+		untrack := c.trackBuild(br.ImageID, cancel)
+		stopHeartbeat := c.heartbeat(br.ImageID, cancel)
+		stopLogs := c.streamLogs(br.ImageID, br.LogFile)
+		buildErr := c.build(ctx, *br)
+		stopLogs()
+		stopHeartbeat()
+		untrack()
+
+		logFile, err := os.OpenFile(br.LogFile, os.O_APPEND|os.O_RDWR, 0666)
+		if err != nil {
+			logs = append(logs, BuildSystemErrorMessage(err.Error()))
+		} else {
+			if buildErr != nil {
+				log.WithFields(log.Fields{
+					"Image ID": br.ImageID,
+					"Runtime":  br.Runtime,
+					"Version":  br.Version,
+				}).Errorf("Failed to build container image", buildErr)
+
+				state = StateFailed
+				errMsg := ""
+				if ctx.Err() == context.Canceled {
+					state = StateCancelled
+					errMsg = BuildCancelledMessage()
+				} else if ctx.Err() == context.DeadlineExceeded {
+					state = StateTimedOut
+					errMsg = BuildTimedOutMessage(c.buildTimeout)
+				} else if buildErr.Type == ErrTypeSystemError {
+					errMsg = BuildSystemErrorMessage(ErrInternalError.String()) + BuildFailedMessage()
+				} else if buildErr.Type == ErrTypeUserError {
+					errMsg = BuildUserErrorMessage(buildErr.String()) + BuildFailedMessage()
+				} else if buildErr.Type == ErrTypeBuild {
+					errMsg = BuildErrorMessage(buildErr.String()) + BuildFailedMessage()
 				}
 
-				if err := c.db.UpdateImageState(br.ImageID, state); err != nil {
-					log.Errorf("Failed to update image state: %s", err)
+				_, err = logFile.WriteString(errMsg)
+				if err != nil {
+					logs = append(logs, BuildSystemErrorMessage(err.Error()))
 				}
+			}
 
-				delete(c.inProgressBuilds, br.ImageID+br.UserID)
+			logFile.Seek(0, io.SeekStart)
 
-				if br.tmpDir != "" {
-					os.RemoveAll(br.tmpDir)
-				}
+			scanner := bufio.NewScanner(logFile)
+			scanner.Split(bufio.ScanLines)
+			for scanner.Scan() {
+				logs = append(logs, scanner.Text())
 			}
-		}()
+			logFile.Close()
+		}
 	}
-}
 
-// GetBuild returns a build in progress
-func (c *Client) GetBuild(buildID, userID string) *BuildRequest {
-	if build, exists := c.inProgressBuilds[buildID+userID]; exists {
-		return &build
+	if err := c.db.FinishBuild(build.ImageID, state, logs, time.Now()); err != nil {
+		log.Errorf("Failed to update build: %s", err)
+	}
+
+	if err := c.db.UpdateImageState(build.ImageID, state); err != nil {
+		log.Errorf("Failed to update image state: %s", err)
+	} else if state == StateSuccess {
+		if err := c.gateway.RolloutImage(build.ImageID, build.UserID); err != nil {
+			log.Errorf("Failed to roll out image %s: %s", build.ImageID, err)
+		}
+	}
+
+	if br != nil {
+		os.RemoveAll(br.tmpDir)
 	}
-	return nil
 }
 
-// Enqueue queues up a new build request
-func (c *Client) Enqueue(br BuildRequest) *Error {
-	tmpDir, err := ioutil.TempDir("", "")
+// request prepares the build request of a claimed build, the build logs so far are
+// written to a new log file
+func (c *Client) request(build *types.Build) (*BuildRequest, error) {
+	image, err := c.db.GetImageWithoutSource(build.ImageID, build.UserID)
 	if err != nil {
-		return SystemError(err.Error())
+		return nil, err
 	}
 
-	logFilePath := filepath.Join(tmpDir, "logs.txt")
-	logFile, err := os.Create(logFilePath)
-	if err != nil {
-		return SystemError(err.Error())
+	if image == nil {
+		return nil, fmt.Errorf("image %s of build not found", build.ImageID)
 	}
-	defer logFile.Close()
 
-	_, err = logFile.WriteString(BuildQueuedMessage(br.ImageID, br.Runtime, br.Version))
+	br := &BuildRequest{
+		ImageID:        image.ID,
+		UserID:         image.UserID,
+		Name:           image.Name,
+		Runtime:        image.Runtime,
+		Version:        image.Version,
+		ExecutablePath: build.ExecutablePath,
+		RunTests:       build.RunTests,
+	}
+
+	if image.SourceType == SourceGit {
+		br.Git = &GitSource{
+			URL:      stringValue(image.GitURL),
+			Ref:      stringValue(image.GitRef),
+			Path:     stringValue(image.GitPath),
+			SecretID: image.GitSecretID,
+		}
+	} else {
+		source, err := c.db.GetImageSource(build.ImageID)
+		if err != nil {
+			return nil, err
+		}
+
+		br.ZippedSource, err = base64.StdEncoding.DecodeString(source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	runtime, ok := c.runtimes[br.Runtime]
+	if !ok {
+		return nil, fmt.Errorf("runtime %s of build %s is not supported", br.Runtime, build.ImageID)
+	}
+
+	br.requiredFiles = runtime.RequiredFiles
+	if runtime.ExecutablePath == types.ExecutablePathRequired {
+		if br.ExecutablePath == nil {
+			return nil, fmt.Errorf("executable path of %s build %s is missing", br.Runtime, build.ImageID)
+		}
+		br.requiredFiles = append(br.requiredFiles[:len(br.requiredFiles):len(br.requiredFiles)], *br.ExecutablePath)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "")
 	if err != nil {
-		return SystemError(err.Error())
+		return nil, err
 	}
 
 	br.tmpDir = tmpDir
-	br.LogFile = logFilePath
+	br.LogFile = filepath.Join(tmpDir, "logs.txt")
 
-	if br.Runtime == "custom" {
-		if br.ExecutablePath == nil {
-			return UserError("Executable path is required when using custom runner")
+	logFile, err := os.Create(br.LogFile)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	defer logFile.Close()
+
+	for _, line := range build.Logs {
+		if _, err := logFile.WriteString(line + "\n"); err != nil {
+			os.RemoveAll(tmpDir)
+			return nil, err
 		}
-		br.requiredFiles = []string{*br.ExecutablePath}
-	} else {
-		br.requiredFiles = requiredFiles[br.Runtime]
 	}
 
-	c.inProgressBuilds[br.ImageID+br.UserID] = br
+	return br, nil
+}
 
-	build := &types.Build{
-		ImageID:   br.ImageID,
-		UserID:    br.UserID,
-		State:     StateQueued,
-		Logs:      pq.StringArray{},
-		CreatedAt: time.Now(),
+// Enqueue queues up a new build request
+func (c *Client) Enqueue(br BuildRequest) *Error {
+	runtime, ok := c.runtimes[br.Runtime]
+	if !ok {
+		return ErrUnsupportedLanguage
 	}
 
-	if err := c.db.CreateBuild(build); err != nil {
-		return SystemError(err.Error())
+	if runtime.ExecutablePath == types.ExecutablePathRequired && br.ExecutablePath == nil {
+		return UserError(fmt.Sprintf("Executable path is required when using %s runtime", br.Runtime))
+	}
+
+	if runtime.ExecutablePath == "" {
+		br.ExecutablePath = nil
+	}
+
+	build := &types.Build{
+		ImageID:        br.ImageID,
+		UserID:         br.UserID,
+		State:          StateQueued,
+		Logs:           strings.Split(strings.TrimSuffix(BuildQueuedMessage(br.ImageID, br.Runtime, br.Version), "\n"), "\n"),
+		CreatedAt:      time.Now(),
+		ExecutablePath: br.ExecutablePath,
+		RunTests:       br.RunTests,
 	}
 
 	taggedRegistry := fmt.Sprintf("%s/%s:%s", c.registry, br.ImageID, br.Version)
+	sourceType := SourceZip
+	if br.Git != nil {
+		sourceType = SourceGit
+	}
 
 	image := &types.Image{
 		ID:             br.ImageID,
@@ -207,20 +334,45 @@ func (c *Client) Enqueue(br BuildRequest) *Error {
 		CreatedAt:      time.Now(),
 		Size:           len(br.ZippedSource),
 		Source:         base64.StdEncoding.EncodeToString(br.ZippedSource), // Only store if successfull ?
+		SourceType:     sourceType,
 	}
 
-	if err := c.db.CreateImage(image); err != nil {
+	if br.Git != nil {
+		image.GitURL = &br.Git.URL
+		image.GitRef = &br.Git.Ref
+		image.GitPath = &br.Git.Path
+		image.GitSecretID = br.Git.SecretID
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return SystemError(err.Error())
+	}
+	defer tx.End()
+
+	if err := tx.CreateBuild(build); err != nil {
+		return SystemError(err.Error())
+	}
+
+	if err := tx.CreateImage(image); err != nil {
 		log.Errorf("Failed to create image in db: %s", err)
 		return SystemError(err.Error())
 	}
 
-	c.incomingBuilds <- br
+	if err := tx.Commit(); err != nil {
+		return SystemError(err.Error())
+	}
+
+	select {
+	case c.wakeup <- struct{}{}:
+	default:
+	}
 
 	return nil
 }
 
 // Build builds the container based on the language
-func (c *Client) build(br BuildRequest) *Error {
+func (c *Client) build(ctx context.Context, br BuildRequest) *Error {
 	logFile, err := os.OpenFile(br.LogFile, os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
 		return SystemError(err.Error())
@@ -232,36 +384,113 @@ func (c *Client) build(br BuildRequest) *Error {
 		return SystemError(err.Error())
 	}
 
-	if _, ok := templateLocations[br.Runtime]; !ok {
+	runtime, ok := c.runtimes[br.Runtime]
+	if !ok {
 		return ErrUnsupportedLanguage
 	}
 
-	if err := extractSource(br.tmpDir, br.ZippedSource); err != nil {
+	if runtime.Deprecated {
+		if _, err := logFile.WriteString(BuildDeprecatedRuntimeMessage(br.Runtime)); err != nil {
+			return SystemError(err.Error())
+		}
+	}
+
+	if br.Git != nil {
+		if err := c.fetchSource(ctx, br, logFile); err != nil {
+			return err
+		}
+	} else if err := extractSource(br.tmpDir, br.ZippedSource); err != nil {
 		return err
 	}
 
-	buildErr := prepareLanguage(br.tmpDir, br.Runtime, br.requiredFiles...)
-	if buildErr != nil {
-		return buildErr
+	cacheDir, unlock := c.lockCache(br.UserID, br.Runtime)
+	defer unlock()
+	br.cacheDir = cacheDir
+
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+			return SystemError(err.Error())
+		}
 	}
 
+	dependencies, err := dependencyHash(filepath.Join(br.tmpDir, "source"), runtime.DependencyFiles)
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	if err := reportCache(cacheDir, dependencies, logFile); err != nil {
+		return SystemError(err.Error())
+	}
+
+	cgroup, release, err := c.buildCgroup(br.ImageID)
+	if err != nil {
+		return SystemError(err.Error())
+	}
+	defer release()
+	br.cgroup = cgroup
+
 	image := fmt.Sprintf("%s/%s:%s", c.registry, br.ImageID, br.Version)
+	state := imgState(cacheDir)
+
+	if br.RunTests && runtime.TestFormat == "" {
+		if _, err := logFile.WriteString(BuildNoTestsMessage(br.Runtime)); err != nil {
+			return SystemError(err.Error())
+		}
+	}
 
-	if br.Runtime == "custom" {
-		err = buildImage(br.tmpDir, image, logFile, "FPROCESS="+*br.ExecutablePath)
+	if br.Runtime == "dockerfile" {
+		if buildErr := c.buildDockerfile(ctx, br, image, logFile); buildErr != nil {
+			return buildErr
+		}
 	} else {
-		err = buildImage(br.tmpDir, image, logFile)
+		buildErr := prepareLanguage(br.tmpDir, br.Runtime, br.requiredFiles...)
+		if buildErr != nil {
+			return buildErr
+		}
+
+		if br.RunTests && runtime.TestFormat != "" {
+			if buildErr := c.runTests(ctx, br, runtime.TestFormat, logFile); buildErr != nil {
+				return buildErr
+			}
+		}
+
+		if br.Runtime == "custom" {
+			err = buildImage(ctx, cgroup, state, br.tmpDir, image, logFile, "FPROCESS="+*br.ExecutablePath)
+		} else {
+			err = buildImage(ctx, cgroup, state, br.tmpDir, image, logFile)
+		}
+		if err != nil {
+			return ImageProcessError(err.Error())
+		}
 	}
-	if err != nil {
+
+	if err := pushImage(ctx, state, image, logFile); err != nil {
 		return ImageProcessError(err.Error())
 	}
 
-	if err := pushImage(image, logFile); err != nil {
-		return ImageProcessError(err.Error())
+	if cacheDir == "" {
+		if err := cleanup(); err != nil {
+			return SystemError(err.Error())
+		}
+	} else {
+		// The layers of the pushed image stay cached for the next builds
+		if err := removeImage(state, image); err != nil {
+			return SystemError(err.Error())
+		}
+
+		if err := storeCacheKey(cacheDir, dependencies); err != nil {
+			return SystemError(err.Error())
+		}
+
+		if err := c.trimCache(); err != nil {
+			log.Errorf("Failed to trim build caches: %s", err)
+		}
 	}
 
-	if err := cleanup(); err != nil {
-		return SystemError(err.Error())
+	if c.scanner != nil {
+		if err := c.scanImage(ctx, br.ImageID, image, logFile); err != nil {
+			return err
+		}
 	}
 
 	_, err = logFile.WriteString(BuildSuccessMessage())
@@ -272,6 +501,42 @@ func (c *Client) build(br BuildRequest) *Error {
 	return nil
 }
 
+// fetchSource clones the git source of the build and records the commit it resolved to
+func (c *Client) fetchSource(ctx context.Context, br BuildRequest, logFile *os.File) *Error {
+	var env []string
+	if br.Git.SecretID != nil {
+		secret, err := c.k8s.GetSecretData(br.UserID, *br.Git.SecretID)
+		if err != nil {
+			return SystemError(err.Error())
+		}
+
+		if secret == nil {
+			return UserError(fmt.Sprintf("Git secret %s not found", *br.Git.SecretID))
+		}
+
+		var credentialsErr *Error
+		env, credentialsErr = gitCredentials(secret)
+		if credentialsErr != nil {
+			return credentialsErr
+		}
+	}
+
+	sha, err := cloneSource(ctx, br.tmpDir, br.Git, env, logFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := logFile.WriteString(BuildCommitMessage(sha)); err != nil {
+		return SystemError(err.Error())
+	}
+
+	if err := c.db.SetImageCommit(br.ImageID, sha); err != nil {
+		return SystemError(err.Error())
+	}
+
+	return nil
+}
+
 func extractSource(baseDir string, source []byte) *Error {
 	if err := os.Mkdir(filepath.Join(baseDir, "source"), os.ModePerm); err != nil {
 		return SystemError(err.Error())
@@ -337,13 +602,17 @@ func prepareLanguage(buildDir, language string, files ...string) *Error {
 		return SystemError(err.Error())
 	}
 
-	templateFiles, err := ioutil.ReadDir(templateLocations[language])
+	templateFiles, err := ioutil.ReadDir(templateLocation(language))
 	if err != nil {
 		return SystemError(err.Error())
 	}
 
 	for _, file := range templateFiles {
-		from, err := os.Open(filepath.Join(templateLocations[language], file.Name()))
+		if file.IsDir() || file.Name() == runtimeManifest {
+			continue
+		}
+
+		from, err := os.Open(filepath.Join(templateLocation(language), file.Name()))
 		if err != nil {
 			return SystemError(err.Error())
 		}
@@ -364,31 +633,73 @@ func prepareLanguage(buildDir, language string, files ...string) *Error {
 	return nil
 }
 
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
 func authenticate(registryURL, user, password string) error {
 	authCMD := exec.Command("img", "login", "-u", user, "-p", password, registryURL)
 	return execCommand(authCMD, nil)
 }
 
-func buildImage(buildDir, image string, logFile *os.File, args ...string) error {
-	buildArgs := []string{"build", "-t", image}
+// buildImage builds the image in the cgroup
+func buildImage(ctx context.Context, cgroup, state, buildDir, image string, logFile *os.File, args ...string) error {
+	return imgBuild(ctx, cgroup, state, []string{"-t", image}, buildDir, logFile, args...)
+}
+
+// buildStage builds the target stage in the cgroup and exports it as specified by output
+func buildStage(ctx context.Context, cgroup, state, buildDir, target, output string, logFile *os.File, args ...string) error {
+	return imgBuild(ctx, cgroup, state, []string{"--target", target, "--output", output}, buildDir, logFile, args...)
+}
+
+// imgBuild runs an img build in the cgroup, the build and the processes it started are killed when ctx is done.
+// img never requests BuildKit entitlements, so BuildKit denies security.insecure and network.host and fails
+// RUN --security=insecure and RUN --network=host however the Dockerfile spells them.
+func imgBuild(ctx context.Context, cgroup, state string, flags []string, buildDir string, logFile *os.File, args ...string) error {
+	buildArgs := append([]string{"build"}, flags...)
 	for _, arg := range args {
 		buildArgs = append(buildArgs, []string{"--build-arg", arg}...)
 	}
 	buildArgs = append(buildArgs, buildDir)
-	buildCMD := exec.Command("img", buildArgs...)
-	return execCommand(buildCMD, logFile)
+	buildCMD := inCgroup(cgroup, imgCommand(ctx, state, buildArgs...))
+	buildCMD.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := execCommand(buildCMD, logFile)
+	if ctx.Err() != nil && buildCMD.Process != nil {
+		// img is killed on its own, the build containers it started are left in its process group
+		syscall.Kill(-buildCMD.Process.Pid, syscall.SIGKILL)
+	}
+
+	return err
 }
 
-func pushImage(image string, logFile *os.File) error {
-	pushCMD := exec.Command("img", "push", image)
+func pushImage(ctx context.Context, state, image string, logFile *os.File) error {
+	pushCMD := imgCommand(ctx, state, "push", image)
 	return execCommand(pushCMD, logFile)
 }
 
+func removeImage(state, image string) error {
+	return imgCommand(context.Background(), state, "rm", image).Run()
+}
+
 func cleanup() error {
 	pushCMD := exec.Command("img", "prune")
 	return execCommand(pushCMD, nil)
 }
 
+// imgCommand returns an img command using the state directory, the default state of img when empty
+func imgCommand(ctx context.Context, state string, args ...string) *exec.Cmd {
+	if state != "" {
+		args = append([]string{"--state", state}, args...)
+	}
+
+	return exec.CommandContext(ctx, "img", args...)
+}
+
 func execCommand(command *exec.Cmd, logFile *os.File) error {
 	stderr, err := command.StderrPipe()
 	if err != nil {
@@ -0,0 +1,226 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// cacheStateDir is the img state directory of a build cache, it holds the layers of previous builds
+	cacheStateDir = "state"
+	// cacheKeyFile holds the dependency hash of the last successful build of a build cache
+	cacheKeyFile = "dependencies.sha256"
+)
+
+// cacheLock serializes the builds of a build cache, img can not share a state directory between builds
+type cacheLock struct {
+	sync.Mutex
+	refs int // refs is the number of builds using or waiting for the cache
+}
+
+// lockCache locks the build cache of a user runtime until unlock is called and returns its directory.
+// The directory is empty when build caching is disabled.
+func (c *Client) lockCache(userID, runtime string) (dir string, unlock func()) {
+	if c.cacheDir == "" {
+		return "", func() {}
+	}
+
+	dir = filepath.Join(c.cacheDir, userID, runtime)
+
+	c.cacheMutex.Lock()
+	lock, ok := c.cacheLocks[dir]
+	if !ok {
+		lock = &cacheLock{}
+		c.cacheLocks[dir] = lock
+	}
+	lock.refs++
+	c.cacheMutex.Unlock()
+
+	lock.Lock()
+
+	return dir, c.unlockCache(dir, lock)
+}
+
+// tryLockCache locks the build cache in dir when no build is using or waiting for it
+func (c *Client) tryLockCache(dir string) (unlock func(), ok bool) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	if _, ok := c.cacheLocks[dir]; ok {
+		return nil, false
+	}
+
+	lock := &cacheLock{refs: 1}
+	lock.Lock()
+	c.cacheLocks[dir] = lock
+
+	return c.unlockCache(dir, lock), true
+}
+
+func (c *Client) unlockCache(dir string, lock *cacheLock) func() {
+	return func() {
+		lock.Unlock()
+
+		c.cacheMutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(c.cacheLocks, dir)
+		}
+		c.cacheMutex.Unlock()
+	}
+}
+
+// reportCache writes whether the dependencies with the hash are cached to the build log
+func reportCache(cacheDir, hash string, logFile *os.File) error {
+	if cacheDir == "" {
+		return nil
+	}
+
+	message := ""
+	if hash != "" {
+		previous, err := ioutil.ReadFile(filepath.Join(cacheDir, cacheKeyFile))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if string(previous) == hash {
+			message = BuildCacheHitMessage(hash)
+		} else {
+			message = BuildCacheMissMessage(hash)
+		}
+	} else if _, err := os.Stat(filepath.Join(cacheDir, cacheStateDir)); err == nil {
+		message = BuildCacheLayersMessage()
+	}
+
+	_, err := logFile.WriteString(message)
+	return err
+}
+
+// storeCacheKey records the dependency hash of a successful build, it also marks the cache as used
+func storeCacheKey(cacheDir, hash string) error {
+	return ioutil.WriteFile(filepath.Join(cacheDir, cacheKeyFile), []byte(hash), 0666)
+}
+
+// dependencyHash returns the sha256 of the dependency files found in sourceDir, empty when there are none
+func dependencyHash(sourceDir string, files []string) (string, error) {
+	hash := sha256.New()
+	found := false
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(sourceDir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		found = true
+		hash.Write([]byte(file + "\n"))
+		hash.Write(content)
+	}
+
+	if !found {
+		return "", nil
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// trimCache removes the least recently used build caches until all of them fit in the cache max size.
+// The sizes are measured without locking, so builds are not blocked during the walk, and a cache is
+// only locked while it is removed. Caches used by a build are kept.
+func (c *Client) trimCache() error {
+	dirs, err := filepath.Glob(filepath.Join(c.cacheDir, "*", "*"))
+	if err != nil {
+		return err
+	}
+
+	type cache struct {
+		dir  string
+		size int64
+		used time.Time
+	}
+
+	caches := []cache{}
+	var total int64
+	for _, dir := range dirs {
+		size, err := dirSize(dir)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(filepath.Join(dir, cacheKeyFile))
+		if os.IsNotExist(err) {
+			info, err = os.Stat(dir)
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		caches = append(caches, cache{dir, size, info.ModTime()})
+		total += size
+	}
+
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].used.Before(caches[j].used)
+	})
+
+	for _, cache := range caches {
+		if total <= c.cacheMaxSize {
+			break
+		}
+
+		unlock, ok := c.tryLockCache(cache.dir)
+		if !ok {
+			continue
+		}
+
+		err := os.RemoveAll(cache.dir)
+		unlock()
+		if err != nil {
+			return err
+		}
+		total -= cache.size
+	}
+
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		// Builds running during the walk may remove files
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// imgState returns the img state directory of a build cache, empty for the default state of img
+func imgState(cacheDir string) string {
+	if cacheDir == "" {
+		return ""
+	}
+
+	return filepath.Join(cacheDir, cacheStateDir)
+}
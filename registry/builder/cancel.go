@@ -0,0 +1,61 @@
+package builder
+
+import (
+	"context"
+	"time"
+
+	"eywa/registry/types"
+)
+
+// ErrBuildNotInProgress is returned when cancelling a finished build
+var ErrBuildNotInProgress = UserError("Build is not in progress")
+
+// trackBuild records the cancel function of a build run by this replica until the returned
+// function is called
+func (c *Client) trackBuild(imageID string, cancel context.CancelFunc) func() {
+	c.runningMutex.Lock()
+	c.running[imageID] = cancel
+	c.runningMutex.Unlock()
+
+	return func() {
+		c.runningMutex.Lock()
+		delete(c.running, imageID)
+		c.runningMutex.Unlock()
+	}
+}
+
+// Cancel cancels a queued or running build. A queued build is cancelled right away, a running
+// build is stopped by the replica running it, within a heartbeat when it is another replica.
+func (c *Client) Cancel(build *types.Build) *Error {
+	if build.State == StateQueued {
+		cancelled, err := c.db.CancelBuild(build.ImageID, StateQueued, StateCancelled,
+			[]string{BuildCancelledMessage()}, time.Now())
+		if err != nil {
+			return SystemError(err.Error())
+		}
+
+		if cancelled {
+			return nil
+		}
+	}
+
+	// The build was claimed by a worker or finished in the meantime
+	requested, err := c.db.RequestBuildCancel(build.ImageID, StateBuilding)
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	if !requested {
+		return ErrBuildNotInProgress
+	}
+
+	c.runningMutex.Lock()
+	cancel, ok := c.running[build.ImageID]
+	c.runningMutex.Unlock()
+
+	if ok {
+		cancel()
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package builder
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// functionStage is the name given to the final stage of a user Dockerfile, the watchdog
+	// stage appended to the Dockerfile builds on it
+	functionStage = "eywa-function"
+	// watchdogStageFile is the template file of the watchdog stage
+	watchdogStageFile = "watchdog.Dockerfile"
+)
+
+// parserDirectiveRegexp matches the parser directives at the top of a Dockerfile, e.g. # syntax=...
+var parserDirectiveRegexp = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.*?)\s*$`)
+
+var imageSizeRegexp = regexp.MustCompile(`^([0-9.]+)([KMGTPEZY]i)?B$`)
+
+// buildDockerfile builds the image of a dockerfile runtime build from the Dockerfile of its source.
+// The build is stopped after the dockerfile timeout and fails when the image exceeds the size limit.
+func (c *Client) buildDockerfile(ctx context.Context, br BuildRequest, image string, logFile *os.File) *Error {
+	sourceDir := filepath.Join(br.tmpDir, "source")
+	for _, file := range br.requiredFiles {
+		if _, err := os.Stat(filepath.Join(sourceDir, file)); os.IsNotExist(err) {
+			return UserError(fmt.Sprintf("Missing top level %s entry file", file))
+		}
+	}
+
+	if err := prepareDockerfile(sourceDir, br.ExecutablePath); err != nil {
+		return err
+	}
+
+	dockerfileCtx, cancel := context.WithTimeout(ctx, c.dockerfileTimeout)
+	defer cancel()
+
+	state := imgState(br.cacheDir)
+	if err := buildImage(dockerfileCtx, br.cgroup, state, sourceDir, image, logFile); err != nil {
+		// The build timeout and cancellations are reported by the caller
+		if ctx.Err() == nil && dockerfileCtx.Err() == context.DeadlineExceeded {
+			return UserError(fmt.Sprintf("Build exceeded the time limit of %s", c.dockerfileTimeout))
+		}
+		return ImageProcessError(err.Error())
+	}
+
+	size, err := imageSize(state, image)
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	if size > c.dockerfileMaxSize {
+		if err := removeImage(state, image); err != nil {
+			log.Errorf("Failed to remove image %s: %s", image, err)
+		}
+		return UserError(fmt.Sprintf("Image size of %d bytes exceeds the limit of %d bytes", size, c.dockerfileMaxSize))
+	}
+
+	return nil
+}
+
+// prepareDockerfile validates the Dockerfile in sourceDir, names its final stage functionStage
+// and appends the watchdog stage running the function process of the final stage
+func prepareDockerfile(sourceDir string, executablePath *string) *Error {
+	path := filepath.Join(sourceDir, "Dockerfile")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	lines := strings.Split(string(content), "\n")
+
+	// A syntax directive would build with a frontend of the user's choice instead of the builtin one
+	for _, line := range lines {
+		match := parserDirectiveRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			break
+		}
+
+		if strings.EqualFold(match[1], "syntax") {
+			return UserError("Dockerfile can not use the syntax parser directive")
+		}
+	}
+
+	// Instructions continue on the next line when a line ends with a backslash
+	finalFrom, finalFromEnd := -1, -1
+	hasFprocess := false
+	for start := 0; start < len(lines); start++ {
+		end := start
+		instruction := strings.TrimSpace(lines[start])
+		for strings.HasSuffix(instruction, "\\") && end+1 < len(lines) {
+			end++
+			instruction = strings.TrimSuffix(instruction, "\\") + " " + strings.TrimSpace(lines[end])
+		}
+
+		fields := strings.Fields(instruction)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			start = end
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			finalFrom, finalFromEnd = start, end
+			hasFprocess = false
+		case "ENV":
+			for _, field := range fields[1:] {
+				if field == "fprocess" || strings.HasPrefix(field, "fprocess=") {
+					hasFprocess = true
+				}
+			}
+		}
+
+		start = end
+	}
+
+	if finalFrom < 0 {
+		return UserError("Dockerfile has no FROM instruction")
+	}
+
+	if executablePath == nil && !hasFprocess {
+		return UserError("The final stage of the Dockerfile must set the fprocess environment variable when no executable path is given")
+	}
+
+	// FROM [--platform=<platform>] <image> [AS <name>]
+	from := []string{}
+	for _, field := range strings.Fields(strings.Join(lines[finalFrom:finalFromEnd+1], " ")) {
+		field = strings.TrimSuffix(field, "\\")
+		if strings.EqualFold(field, "AS") {
+			break
+		}
+		if field != "" {
+			from = append(from, field)
+		}
+	}
+
+	if len(from) < 2 {
+		return UserError("Dockerfile FROM instruction has no image")
+	}
+
+	watchdogStage, err := ioutil.ReadFile(filepath.Join(templateLocation("dockerfile"), watchdogStageFile))
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	dockerfile := append(lines[:finalFrom:finalFrom], strings.Join(from, " ")+" AS "+functionStage)
+	dockerfile = append(dockerfile, lines[finalFromEnd+1:]...)
+	dockerfile = append(dockerfile, "", string(watchdogStage))
+	if executablePath != nil {
+		dockerfile = append(dockerfile, "ENV fprocess="+strconv.Quote(*executablePath))
+	}
+
+	if err := ioutil.WriteFile(path, []byte(strings.Join(dockerfile, "\n")+"\n"), 0666); err != nil {
+		return SystemError(err.Error())
+	}
+
+	return nil
+}
+
+// imageSize returns the size of the content of a built image in bytes
+func imageSize(state, image string) (int64, error) {
+	output, err := imgCommand(context.Background(), state, "ls").Output()
+	if err != nil {
+		return 0, err
+	}
+
+	// NAME SIZE CREATED AT UPDATED AT DIGEST
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != image {
+			continue
+		}
+
+		match := imageSizeRegexp.FindStringSubmatch(fields[1])
+		if match == nil {
+			return 0, fmt.Errorf("invalid size %q of image %s", fields[1], image)
+		}
+
+		size, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, err
+		}
+
+		if match[2] != "" {
+			exponent := strings.Index("KMGTPEZY", match[2][:1]) + 1
+			for i := 0; i < exponent; i++ {
+				size *= 1024
+			}
+		}
+
+		return int64(size), nil
+	}
+
+	return 0, fmt.Errorf("image %s not found", image)
+}
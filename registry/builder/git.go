@@ -0,0 +1,153 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"eywa/go-libs/netguard"
+)
+
+// Sources of an image build
+const (
+	SourceZip = "zip"
+	SourceGit = "git"
+)
+
+// GitSource represents a build source fetched from a git repository
+type GitSource struct {
+	URL      string
+	Ref      string  // Ref is a branch, tag or commit sha
+	Path     string  // Path is the directory of the function source within the repository
+	SecretID *string // SecretID of the user secret holding username and password or token
+}
+
+// ValidateGitRef accepts the branch, tag and commit sha refs git can fetch. A ref starting with
+// a dash would be taken as an option of git.
+func ValidateGitRef(ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "-") || strings.Contains(ref, "..") || strings.IndexFunc(ref, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("Git ref %q is not a valid branch, tag or commit sha", ref)
+	}
+
+	return nil
+}
+
+// ValidateGitHost fails when the host of the repository URL resolves to an address that is not
+// public, the registry would fetch from the services of the cluster otherwise
+func ValidateGitHost(ctx context.Context, gitURL string) error {
+	u, err := url.Parse(gitURL)
+	if err != nil {
+		return err
+	}
+
+	if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("Git URL host is not allowed: %s", err)
+	}
+
+	return nil
+}
+
+// gitCredentials returns the git command environment authenticating with the credentials of
+// the secret, they are sent as a header so they never appear in the repository URL or logs
+func gitCredentials(secret map[string][]byte) ([]string, *Error) {
+	password := secret["password"]
+	if len(password) == 0 {
+		password = secret["token"]
+	}
+
+	if len(password) == 0 {
+		return nil, UserError("Git secret must have a password or token field")
+	}
+
+	username := secret["username"]
+	if len(username) == 0 {
+		username = []byte("git")
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte(string(username) + ":" + string(password)))
+
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}, nil
+}
+
+// gitOptions restrict git to fetching over http and https from the host of the repository URL,
+// other transports could read local files or run commands
+var gitOptions = []string{
+	"-c", "http.followRedirects=false",
+	"-c", "protocol.allow=never",
+	"-c", "protocol.https.allow=always",
+	"-c", "protocol.http.allow=always",
+}
+
+// cloneSource fetches the ref of the repository into baseDir/source and returns the commit
+// sha it resolved to. Only the fetched commit is downloaded.
+func cloneSource(ctx context.Context, baseDir string, source *GitSource, env []string, logFile *os.File) (string, *Error) {
+	if err := ValidateGitRef(source.Ref); err != nil {
+		return "", UserError(err.Error())
+	}
+
+	// The host may resolve differently than when the build was requested, redirects are not
+	// followed so git only fetches from the host that was checked
+	if err := ValidateGitHost(ctx, source.URL); err != nil {
+		return "", UserError(err.Error())
+	}
+
+	repoDir := filepath.Join(baseDir, "repository")
+	env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+
+	commands := [][]string{
+		{"init", "-q", repoDir},
+		{"-C", repoDir, "remote", "add", "origin", source.URL},
+		{"-C", repoDir, "fetch", "-q", "--depth", "1", "--end-of-options", "origin", source.Ref},
+		{"-C", repoDir, "checkout", "-q", "--detach", "FETCH_HEAD"},
+	}
+
+	for _, args := range commands {
+		cmd := exec.CommandContext(ctx, "git", append(gitOptions, args...)...)
+		cmd.Env = env
+		if err := execCommand(cmd, logFile); err != nil {
+			if ctx.Err() != nil {
+				return "", SystemError(ctx.Err().Error())
+			}
+
+			return "", UserError(fmt.Sprintf("Failed to fetch %s of %s", source.Ref, source.URL))
+		}
+	}
+
+	var sha bytes.Buffer
+	cmd := exec.Command("git", "-C", repoDir, "rev-parse", "HEAD")
+	cmd.Stdout = &sha
+	if err := cmd.Run(); err != nil {
+		return "", SystemError(err.Error())
+	}
+
+	if err := os.RemoveAll(filepath.Join(repoDir, ".git")); err != nil {
+		return "", SystemError(err.Error())
+	}
+
+	// Cleaning the path as an absolute one keeps it within the repository
+	sourceDir := filepath.Join(repoDir, filepath.Clean("/"+source.Path))
+	if info, err := os.Stat(sourceDir); err != nil || !info.IsDir() {
+		return "", UserError(fmt.Sprintf("Path %s is not a directory of the repository", source.Path))
+	}
+
+	if err := os.Rename(sourceDir, filepath.Join(baseDir, "source")); err != nil {
+		return "", SystemError(err.Error())
+	}
+
+	if err := os.RemoveAll(repoDir); err != nil {
+		return "", SystemError(err.Error())
+	}
+
+	return strings.TrimSpace(sha.String()), nil
+}
@@ -0,0 +1,94 @@
+package builder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// cgroupCPUPeriod is the period in microseconds the cpu quota of a build applies to
+	cgroupCPUPeriod = 100000
+	// cgroupKillAttempts is how many times the processes left in a build cgroup are killed
+	// before it is removed
+	cgroupKillAttempts = 10
+)
+
+// setupCgroup creates the cgroup v2 directory the build cgroups are created in and enables the
+// cpu and memory controllers for them
+func setupCgroup(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+}
+
+// buildCgroup creates the cgroup limiting the resources of a build, it is empty when builds are
+// not limited. The returned function kills the processes left in the cgroup and removes it.
+func (c *Client) buildCgroup(imageID string) (string, func(), error) {
+	if c.cgroupDir == "" {
+		return "", func() {}, nil
+	}
+
+	dir := filepath.Join(c.cgroupDir, imageID)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", nil, err
+	}
+
+	release := func() {
+		for i := 0; i < cgroupKillAttempts; i++ {
+			procs, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+			if err != nil || len(strings.TrimSpace(string(procs))) == 0 {
+				break
+			}
+
+			for _, proc := range strings.Fields(string(procs)) {
+				if pid, err := strconv.Atoi(proc); err == nil {
+					syscall.Kill(pid, syscall.SIGKILL)
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		os.Remove(dir)
+	}
+
+	if c.buildMemoryLimit > 0 {
+		memory := strconv.FormatInt(c.buildMemoryLimit, 10)
+		if err := ioutil.WriteFile(filepath.Join(dir, "memory.max"), []byte(memory), 0644); err != nil {
+			release()
+			return "", nil, err
+		}
+	}
+
+	if c.buildCPULimit > 0 {
+		quota := fmt.Sprintf("%d %d", int64(c.buildCPULimit*cgroupCPUPeriod), cgroupCPUPeriod)
+		if err := ioutil.WriteFile(filepath.Join(dir, "cpu.max"), []byte(quota), 0644); err != nil {
+			release()
+			return "", nil, err
+		}
+	}
+
+	return dir, release, nil
+}
+
+// inCgroup makes the command join the cgroup before it starts, so every process it starts is
+// limited by the cgroup as well. The command is returned unchanged when the cgroup is empty.
+func inCgroup(cgroup string, cmd *exec.Cmd) *exec.Cmd {
+	if cgroup == "" {
+		return cmd
+	}
+
+	script := `echo $$ > "$0" && exec "$@"`
+	cmd.Args = append([]string{"sh", "-c", script, filepath.Join(cgroup, "cgroup.procs")}, cmd.Args...)
+	cmd.Path = "/bin/sh"
+
+	return cmd
+}
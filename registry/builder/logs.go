@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// logFlushInterval is how often the lines written to the log file of a running build are stored
+const logFlushInterval = time.Second
+
+// streamLogs appends the lines written to the log file of a build from now on to the build logs
+// stored in the db, so they can be followed from any registry replica. The returned function stops
+// streaming once the lines read so far were stored, the last line is stored when the build finishes.
+func (c *Client) streamLogs(imageID, logPath string) func() {
+	logFile, err := os.Open(logPath)
+	if err != nil {
+		log.Errorf("Failed to open build log file: %s", err)
+		return func() {}
+	}
+
+	if _, err := logFile.Seek(0, io.SeekEnd); err != nil {
+		log.Errorf("Failed to seek build log file: %s", err)
+		logFile.Close()
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		defer logFile.Close()
+
+		ticker := time.NewTicker(logFlushInterval)
+		defer ticker.Stop()
+
+		reader := bufio.NewReader(logFile)
+		partial := ""
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			lines := []string{}
+			for {
+				chunk, err := reader.ReadString('\n')
+				partial += chunk
+				if err != nil {
+					// The rest is a line still being written
+					break
+				}
+
+				lines = append(lines, strings.TrimSuffix(partial, "\n"))
+				partial = ""
+			}
+
+			if len(lines) == 0 {
+				continue
+			}
+
+			if err := c.db.AppendBuildLogs(imageID, lines); err != nil {
+				log.Errorf("Failed to store build logs: %s", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
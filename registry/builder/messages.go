@@ -1,6 +1,12 @@
 package builder
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"eywa/registry/types"
+)
 
 // BuildQueuedMessage return build queued message
 func BuildQueuedMessage(id, language, version string) string {
@@ -17,6 +23,76 @@ func BuildStartMessage() string {
 	return "########## BUILD START ##########\n"
 }
 
+// BuildDeprecatedRuntimeMessage returns the warning of a build using a deprecated runtime
+func BuildDeprecatedRuntimeMessage(runtime string) string {
+	return fmt.Sprintf("WARNING: The %s runtime is deprecated and will be removed, see GET /runtimes for its replacement\n", runtime)
+}
+
+// BuildTestStartMessage returns test stage start message
+func BuildTestStartMessage() string {
+	return "########## TESTS START ##########\n"
+}
+
+// BuildTestResultMessage returns the summary of the test results
+func BuildTestResultMessage(result *types.TestResult) string {
+	coverage := ""
+	if result.Coverage != nil {
+		coverage = fmt.Sprintf(", coverage %.1f%%", *result.Coverage)
+	}
+
+	return fmt.Sprintf("TESTS %s: %d passed, %d failed, %d skipped%s\n",
+		strings.ToUpper(result.Status), result.Passed, result.Failed, result.Skipped, coverage) +
+		"########## TESTS FINISHED ##########\n"
+}
+
+// BuildNoTestsMessage returns the message of a build running tests with a runtime without tests
+func BuildNoTestsMessage(runtime string) string {
+	return fmt.Sprintf("TESTS: The %s runtime has no test stage, skipping tests\n", runtime)
+}
+
+// BuildScanStartMessage returns vulnerability scan start message
+func BuildScanStartMessage() string {
+	return "########## SCAN START ##########\n"
+}
+
+// BuildScanResultMessage returns the summary of the vulnerabilities found in the image
+func BuildScanResultMessage(summary types.VulnerabilitySummary) string {
+	return fmt.Sprintf("SCAN: %d critical, %d high, %d medium, %d low, %d unknown vulnerabilities found\n",
+		summary.Critical, summary.High, summary.Medium, summary.Low, summary.Unknown) +
+		"########## SCAN FINISHED ##########\n"
+}
+
+// BuildScanFailedMessage returns the warning of a build whose image could not be scanned
+func BuildScanFailedMessage() string {
+	return "WARNING: The image could not be scanned for vulnerabilities\n" +
+		"########## SCAN FAILED ##########\n"
+}
+
+// BuildCommitMessage returns the message with the commit a build is using
+func BuildCommitMessage(sha string) string {
+	return fmt.Sprintf("COMMIT: %s\n", sha)
+}
+
+// BuildCacheHitMessage returns the message of a build reusing its cached dependencies
+func BuildCacheHitMessage(hash string) string {
+	return fmt.Sprintf("CACHE: HIT dependencies sha256:%s are unchanged, reusing the cached dependency layers\n", hash)
+}
+
+// BuildCacheMissMessage returns the message of a build installing its dependencies
+func BuildCacheMissMessage(hash string) string {
+	return fmt.Sprintf("CACHE: MISS dependencies sha256:%s changed since the last build, installing them\n", hash)
+}
+
+// BuildCacheLayersMessage returns the message of a build without dependency files reusing the layers of previous builds
+func BuildCacheLayersMessage() string {
+	return "CACHE: reusing the layers of previous builds, cached steps are marked CACHED\n"
+}
+
+// BuildInterruptedMessage returns build interrupted message
+func BuildInterruptedMessage() string {
+	return "########## BUILD INTERRUPTED ##########"
+}
+
 // BuildSystemErrorMessage returns system error message
 func BuildSystemErrorMessage(message string) string {
 	return fmt.Sprintf("SYSTEM ERROR: %s\n", message)
@@ -37,6 +113,17 @@ func BuildSuccessMessage() string {
 	return "########## BUILD FINISHED ##########"
 }
 
+// BuildCancelledMessage returns build cancelled message
+func BuildCancelledMessage() string {
+	return "########## BUILD CANCELLED ##########"
+}
+
+// BuildTimedOutMessage returns the message of a build stopped after the build timeout
+func BuildTimedOutMessage(timeout time.Duration) string {
+	return fmt.Sprintf("ERROR: Build exceeded the time limit of %s\n", timeout) +
+		"########## BUILD TIMED OUT ##########"
+}
+
 // BuildFailedMessage return build failed message
 func BuildFailedMessage() string {
 	return "########## BUILD FAILED ##########"
@@ -0,0 +1,169 @@
+package builder
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// queuePollInterval is how often an idle worker checks the queue for builds queued elsewhere
+	queuePollInterval = 5 * time.Second
+	// heartbeatInterval is how often a running build records that its worker is alive
+	heartbeatInterval = 10 * time.Second
+	// staleBuildTimeout is how long after its last heartbeat a running build is considered interrupted
+	staleBuildTimeout = time.Minute
+	// maxBuildAttempts is the number of times a build is started before an interruption fails it
+	maxBuildAttempts = 3
+	// estimateSampleSize is the number of recent builds the duration of a build is estimated from
+	estimateSampleSize = 20
+)
+
+// work runs the queued builds one at a time, oldest first
+func (c *Client) work() {
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		build, err := c.db.ClaimBuild(StateQueued, StateBuilding, time.Now())
+		if err != nil {
+			log.Errorf("Failed to claim queued build: %s", err)
+		}
+
+		if build != nil {
+			c.run(build)
+			continue
+		}
+
+		select {
+		case <-c.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat records that the build is running until the returned function is called, cancel is
+// called once the build was cancelled from any replica
+func (c *Client) heartbeat(imageID string, cancel func()) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cancelRequested, err := c.db.HeartbeatBuild(imageID, time.Now())
+				if err != nil {
+					log.Errorf("Failed to record build heartbeat: %s", err)
+				} else if cancelRequested {
+					cancel()
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// reapBuilds periodically recovers the builds whose worker stopped sending heartbeats
+func (c *Client) reapBuilds() {
+	ticker := time.NewTicker(staleBuildTimeout)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.recoverBuilds()
+	}
+}
+
+// recoverBuilds queues the interrupted builds again, a build already started maxBuildAttempts
+// times is failed instead and a cancelled one is not resumed
+func (c *Client) recoverBuilds() {
+	builds, err := c.db.GetStaleBuilds(StateBuilding, time.Now().Add(-staleBuildTimeout))
+	if err != nil {
+		log.Errorf("Failed to get interrupted builds: %s", err)
+		return
+	}
+
+	for _, build := range builds {
+		logs := append(build.Logs, BuildInterruptedMessage())
+
+		state := StateQueued
+		if build.CancelRequested {
+			state = StateCancelled
+			logs = append(logs, BuildCancelledMessage())
+			err = c.db.FinishBuild(build.ImageID, state, logs, time.Now())
+		} else if build.Attempts >= maxBuildAttempts {
+			state = StateFailed
+			logs = append(logs, BuildSystemErrorMessage(ErrInternalError.String()), BuildFailedMessage())
+			err = c.db.FinishBuild(build.ImageID, state, logs, time.Now())
+		} else {
+			err = c.db.UpdateBuild(build.ImageID, state, logs)
+		}
+
+		if err != nil {
+			log.Errorf("Failed to recover build %s: %s", build.ImageID, err)
+			continue
+		}
+
+		if err := c.db.UpdateImageState(build.ImageID, state); err != nil {
+			log.Errorf("Failed to update image state: %s", err)
+		}
+
+		log.Warnf("Recovered interrupted build %s as %s", build.ImageID, state)
+	}
+
+	if len(builds) > 0 {
+		select {
+		case c.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Estimate returns the position of a queued build in the queue and when a queued or
+// running build is expected to complete, based on the duration of recent builds. The
+// completion is nil when there is no build to estimate it from.
+func (c *Client) Estimate(imageID, userID string) (int, *time.Time, error) {
+	build, err := c.db.GetBuild(imageID, userID)
+	if err != nil || build == nil {
+		return 0, nil, err
+	}
+
+	average, err := c.db.GetAverageBuildDuration(StateSuccess, estimateSampleSize)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch build.State {
+	case StateBuilding:
+		if average == 0 || build.StartedAt == nil {
+			return 0, nil, nil
+		}
+
+		eta := build.StartedAt.Add(average)
+		return 0, &eta, nil
+
+	case StateQueued:
+		position, err := c.db.GetQueuePosition(imageID, StateQueued)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if average == 0 {
+			return position, nil, nil
+		}
+
+		// Builds ahead are run numWorkers at a time
+		rounds := (position-1)/c.numWorkers + 1
+		eta := time.Now().Add(time.Duration(rounds) * average)
+		return position, &eta, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"eywa/registry/types"
+)
+
+const (
+	// templatesDir holds a template directory per runtime
+	templatesDir = "./templates"
+	// runtimeManifest is the file describing the runtime of a template directory
+	runtimeManifest = "runtime.json"
+)
+
+// loadRuntimes reads the runtime manifests of the template directories
+func loadRuntimes(dir string) (map[string]types.Runtime, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimes := map[string]types.Runtime{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, entry.Name(), runtimeManifest))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest of runtime %s: %s", entry.Name(), err)
+		}
+
+		var runtime types.Runtime
+		if err := json.Unmarshal(content, &runtime); err != nil {
+			return nil, fmt.Errorf("invalid manifest of runtime %s: %s", entry.Name(), err)
+		}
+
+		switch runtime.ExecutablePath {
+		case "", types.ExecutablePathRequired, types.ExecutablePathOptional:
+		default:
+			return nil, fmt.Errorf("invalid executable_path %q of runtime %s", runtime.ExecutablePath, entry.Name())
+		}
+
+		if runtime.TestFormat != "" && !contains(supportedTestFormats, runtime.TestFormat) {
+			return nil, fmt.Errorf("invalid test_format %q of runtime %s", runtime.TestFormat, entry.Name())
+		}
+
+		runtime.Name = entry.Name()
+		if runtime.RequiredFiles == nil {
+			runtime.RequiredFiles = []string{}
+		}
+		if runtime.DependencyFiles == nil {
+			runtime.DependencyFiles = []string{}
+		}
+		runtimes[runtime.Name] = runtime
+	}
+
+	return runtimes, nil
+}
+
+// templateLocation returns the template directory of a runtime
+func templateLocation(runtime string) string {
+	return filepath.Join(templatesDir, runtime)
+}
+
+// Runtime returns the runtime with the name, false when images can not be built for it
+func (c *Client) Runtime(name string) (types.Runtime, bool) {
+	runtime, ok := c.runtimes[name]
+	return runtime, ok
+}
+
+// Runtimes returns the runtimes images can be built for ordered by name
+func (c *Client) Runtimes() []types.Runtime {
+	runtimes := []types.Runtime{}
+	for _, runtime := range c.runtimes {
+		runtimes = append(runtimes, runtime)
+	}
+
+	sort.Slice(runtimes, func(i, j int) bool {
+		return runtimes[i].Name < runtimes[j].Name
+	})
+
+	return runtimes
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
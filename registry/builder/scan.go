@@ -0,0 +1,48 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"eywa/registry/types"
+)
+
+// scanImage scans the pushed image for vulnerabilities and stores the findings with the image.
+// A failed scan does not fail the build, the image is marked as failed to scan instead.
+func (c *Client) scanImage(ctx context.Context, imageID, image string, logFile *os.File) *Error {
+	if _, err := logFile.WriteString(BuildScanStartMessage()); err != nil {
+		return SystemError(err.Error())
+	}
+
+	vulnerabilities, err := c.scanner.Scan(ctx, image)
+	if ctx.Err() != nil {
+		return SystemError(ctx.Err().Error())
+	}
+
+	if err != nil {
+		log.Errorf("Failed to scan image %s: %s", imageID, err)
+
+		if err := c.db.SetImageScan(imageID, types.ScanStateFailed, nil, time.Now()); err != nil {
+			return SystemError(err.Error())
+		}
+
+		if _, err := logFile.WriteString(BuildScanFailedMessage()); err != nil {
+			return SystemError(err.Error())
+		}
+
+		return nil
+	}
+
+	if err := c.db.SetImageScan(imageID, types.ScanStateScanned, vulnerabilities, time.Now()); err != nil {
+		return SystemError(err.Error())
+	}
+
+	if _, err := logFile.WriteString(BuildScanResultMessage(types.Summarize(vulnerabilities))); err != nil {
+		return SystemError(err.Error())
+	}
+
+	return nil
+}
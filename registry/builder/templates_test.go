@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Each script serves the requests a and b concurrently, both interleave partial lines on stdout
+// and stderr. The template directory is passed as the first argument.
+const nodeCorrelationScript = `
+const correlation = require(process.argv[1] + '/correlation');
+
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+const handle = (id) => {
+    const req = { headers: { 'x-eywa-correlation-id': id } };
+    return correlation.run(req, async () => {
+        for (let i = 0; i < 3; i++) {
+            process.stdout.write(id + ' ');
+            await sleep(5);
+            console.log('line ' + i);
+            console.error(id + ' error ' + i);
+        }
+        process.stdout.write(id + ' unterminated');
+    }).then(() => correlation.flush(req));
+};
+
+console.log('no request');
+Promise.all([handle('a'), handle('b')]);
+`
+
+const pythonCorrelationScript = `
+import sys
+import threading
+
+sys.path.insert(0, sys.argv[1])
+import correlation
+
+barrier = threading.Barrier(2)
+
+def handle(id):
+    with correlation.request({'X-Eywa-Correlation-Id': id}):
+        for i in range(3):
+            print(id, end=' ')
+            barrier.wait()
+            print('line', i)
+            print(id, 'error', i, file=sys.stderr)
+        print(id, 'unterminated', end='')
+
+print('no request')
+threads = [threading.Thread(target=handle, args=(id,)) for id in ('a', 'b')]
+for thread in threads:
+    thread.start()
+for thread in threads:
+    thread.join()
+`
+
+var correlatedLine = regexp.MustCompile(`^\[(\w+)\] (\w+) `)
+
+func Test_Templates_PrefixOutputWithCorrelationID(t *testing.T) {
+	tests := []struct {
+		runtime string
+		command []string
+	}{
+		{runtime: "node14", command: []string{"node", "-e", nodeCorrelationScript}},
+		{runtime: "node20", command: []string{"node", "-e", nodeCorrelationScript}},
+		{runtime: "python3", command: []string{"python3", "-c", pythonCorrelationScript}},
+		{runtime: "python311", command: []string{"python3", "-c", pythonCorrelationScript}},
+		{runtime: "python312", command: []string{"python3", "-c", pythonCorrelationScript}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.runtime, func(t *testing.T) {
+			if _, err := exec.LookPath(test.command[0]); err != nil {
+				t.Skipf("%s is not installed", test.command[0])
+			}
+
+			dir, err := filepath.Abs(filepath.Join("..", templateLocation(test.runtime)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(test.command[0], append(test.command[1:], dir)...)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("Want script to succeed, got %s: %s", err, stderr.String())
+			}
+
+			checkCorrelatedLines(t, "stdout", stdout.String(), 4)
+			checkCorrelatedLines(t, "stderr", stderr.String(), 3)
+		})
+	}
+}
+
+// checkCorrelatedLines checks every line written for a request is prefixed with its id
+func checkCorrelatedLines(t *testing.T, stream, output string, want int) {
+	t.Helper()
+
+	lines := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if line == "no request" {
+			continue
+		}
+
+		match := correlatedLine.FindStringSubmatch(line)
+		if match == nil || match[1] != match[2] {
+			t.Errorf("Want %s line attributed to the request that wrote it, got %q", stream, line)
+			continue
+		}
+		lines[match[1]]++
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if lines[id] != want {
+			t.Errorf("Want %d %s lines of request %s, got %d in %q", want, stream, id, lines[id], output)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package builder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"eywa/registry/types"
+)
+
+// Test output formats of the runtimes
+const (
+	TestFormatGo       = "go"       // go test -json
+	TestFormatTAP      = "tap"      // Test Anything Protocol
+	TestFormatJUnit    = "junit"    // JUnit XML reports
+	TestFormatCargo    = "cargo"    // cargo test
+	TestFormatUnittest = "unittest" // python -m unittest -v
+	TestFormatMinitest = "minitest" // Ruby minitest
+	TestFormatDotnet   = "dotnet"   // dotnet test
+)
+
+const (
+	// testResultsStage is the template stage the test output, its exit code and reports are exported from
+	testResultsStage = "test-results"
+	testOutputFile   = "output"
+	testExitCodeFile = "exit-code"
+	testReportsDir   = "reports"
+)
+
+var (
+	goCoverageRegexp      = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+	tapRegexp             = regexp.MustCompile(`^(not )?ok\b(?:\s+\d+)?(?:\s+-)?\s*([^#]*?)\s*(?:#\s*(\w+).*)?$`)
+	cargoResultRegexp     = regexp.MustCompile(`test result: \w+\. (\d+) passed; (\d+) failed; (\d+) ignored`)
+	cargoFailureRegexp    = regexp.MustCompile(`^test (\S+) \.\.\. FAILED$`)
+	unittestRanRegexp     = regexp.MustCompile(`^Ran (\d+) tests?`)
+	unittestCountRegexp   = regexp.MustCompile(`(failures|errors|skipped)=(\d+)`)
+	unittestFailureRegexp = regexp.MustCompile(`^(?:FAIL|ERROR): (\S+)`)
+	minitestResultRegexp  = regexp.MustCompile(`(\d+) runs, \d+ assertions, (\d+) failures, (\d+) errors, (\d+) skips`)
+	minitestFailureRegexp = regexp.MustCompile(`^(\S+#\S+) \[`)
+	dotnetResultRegexp    = regexp.MustCompile(`Failed:\s+(\d+), Passed:\s+(\d+), Skipped:\s+(\d+)`)
+	dotnetFailureRegexp   = regexp.MustCompile(`^\s+Failed (\S+) \[`)
+	supportedTestFormats  = []string{TestFormatGo, TestFormatTAP, TestFormatJUnit, TestFormatCargo, TestFormatUnittest, TestFormatMinitest, TestFormatDotnet}
+)
+
+// runTests runs the test stage of the prepared build and stores the result with the build.
+// The test output is written to the build log, the build fails when the tests do not pass.
+func (c *Client) runTests(ctx context.Context, br BuildRequest, format string, logFile *os.File) *Error {
+	if _, err := logFile.WriteString(BuildTestStartMessage()); err != nil {
+		return SystemError(err.Error())
+	}
+
+	resultsDir := filepath.Join(br.tmpDir, testResultsStage)
+	output := fmt.Sprintf("type=local,dest=%s", resultsDir)
+
+	var args []string
+	if br.ExecutablePath != nil {
+		args = append(args, "FPROCESS="+*br.ExecutablePath)
+	}
+
+	err := buildStage(ctx, br.cgroup, imgState(br.cacheDir), br.tmpDir, testResultsStage, output, logFile, args...)
+	if err != nil {
+		return ImageProcessError(err.Error())
+	}
+
+	testOutput, err := ioutil.ReadFile(filepath.Join(resultsDir, testOutputFile))
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	exitCode, err := ioutil.ReadFile(filepath.Join(resultsDir, testExitCodeFile))
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	code, err := strconv.Atoi(strings.TrimSpace(string(exitCode)))
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	if _, err := logFile.Write(testOutput); err != nil {
+		return SystemError(err.Error())
+	}
+
+	result, err := parseTestResult(format, string(testOutput), code, filepath.Join(resultsDir, testReportsDir))
+	if err != nil {
+		return SystemError(err.Error())
+	}
+
+	if err := c.db.SetBuildTests(br.ImageID, result); err != nil {
+		return SystemError(err.Error())
+	}
+
+	if _, err := logFile.WriteString(BuildTestResultMessage(result)); err != nil {
+		return SystemError(err.Error())
+	}
+
+	if result.Status != types.TestsPassed {
+		return UserError(fmt.Sprintf("Tests failed, %d of %d tests failed", result.Failed, result.Passed+result.Failed))
+	}
+
+	return nil
+}
+
+// parseTestResult parses the output of the tests in format, they passed when they exited with
+// exitCode 0 and no test failed
+func parseTestResult(format, output string, exitCode int, reportsDir string) (*types.TestResult, error) {
+	result := &types.TestResult{
+		Format:   format,
+		Failures: []string{},
+	}
+
+	var err error
+	switch format {
+	case TestFormatGo:
+		parseGoTests(result, output)
+	case TestFormatTAP:
+		parseTAPTests(result, output)
+	case TestFormatJUnit:
+		err = parseJUnitTests(result, reportsDir)
+	case TestFormatCargo:
+		parseCargoTests(result, output)
+	case TestFormatUnittest:
+		parseUnittestTests(result, output)
+		// Python 3.12 exits with 5 when there are no tests
+		if exitCode == 5 && result.Passed+result.Failed+result.Skipped == 0 {
+			exitCode = 0
+		}
+	case TestFormatMinitest:
+		parseMinitestTests(result, output)
+	case TestFormatDotnet:
+		parseDotnetTests(result, output)
+	default:
+		err = fmt.Errorf("unsupported test format %s", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Status = types.TestsPassed
+	if exitCode != 0 || result.Failed > 0 {
+		result.Status = types.TestsFailed
+	}
+
+	return result, nil
+}
+
+func parseGoTests(result *types.TestResult, output string) {
+	var coverage float64
+	packages := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		var event struct {
+			Action  string
+			Package string
+			Test    string
+			Output  string
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+
+		if event.Action == "output" {
+			if match := goCoverageRegexp.FindStringSubmatch(event.Output); match != nil && event.Test == "" {
+				if value, err := strconv.ParseFloat(match[1], 64); err == nil {
+					coverage += value
+					packages++
+				}
+			}
+			continue
+		}
+
+		// Package results have no test
+		if event.Test == "" {
+			continue
+		}
+
+		switch event.Action {
+		case "pass":
+			result.Passed++
+		case "fail":
+			result.Failed++
+			result.Failures = append(result.Failures, event.Package+"."+event.Test)
+		case "skip":
+			result.Skipped++
+		}
+	}
+
+	if packages > 0 {
+		coverage /= float64(packages)
+		result.Coverage = &coverage
+	}
+}
+
+// parseTAPTests counts the top level test points, subtests are indented
+func parseTAPTests(result *types.TestResult, output string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		match := tapRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(match[3], "SKIP") || strings.EqualFold(match[3], "TODO"):
+			result.Skipped++
+		case match[1] != "":
+			result.Failed++
+			result.Failures = append(result.Failures, match[2])
+		default:
+			result.Passed++
+		}
+	}
+}
+
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+	Cases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string    `xml:"name,attr"`
+	ClassName string    `xml:"classname,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+func parseJUnitTests(result *types.TestResult, reportsDir string) error {
+	reports, err := filepath.Glob(filepath.Join(reportsDir, "*.xml"))
+	if err != nil {
+		return err
+	}
+
+	var count func(suite junitTestSuite)
+	count = func(suite junitTestSuite) {
+		for _, testCase := range suite.Cases {
+			switch {
+			case testCase.Failure != nil || testCase.Error != nil:
+				result.Failed++
+				result.Failures = append(result.Failures, strings.TrimPrefix(testCase.ClassName+"."+testCase.Name, "."))
+			case testCase.Skipped != nil:
+				result.Skipped++
+			default:
+				result.Passed++
+			}
+		}
+
+		for _, nested := range suite.Suites {
+			count(nested)
+		}
+	}
+
+	for _, report := range reports {
+		content, err := ioutil.ReadFile(report)
+		if err != nil {
+			return err
+		}
+
+		// Reports have a testsuites or a testsuite root
+		var suites junitTestSuites
+		if err := xml.Unmarshal(content, &suites); err == nil && len(suites.Suites) > 0 {
+			for _, suite := range suites.Suites {
+				count(suite)
+			}
+			continue
+		}
+
+		var suite junitTestSuite
+		if err := xml.Unmarshal(content, &suite); err != nil {
+			return fmt.Errorf("invalid test report %s: %s", filepath.Base(report), err)
+		}
+		count(suite)
+	}
+
+	return nil
+}
+
+func parseCargoTests(result *types.TestResult, output string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := cargoResultRegexp.FindStringSubmatch(line); match != nil {
+			result.Passed += atoi(match[1])
+			result.Failed += atoi(match[2])
+			result.Skipped += atoi(match[3])
+		} else if match := cargoFailureRegexp.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[1])
+		}
+	}
+}
+
+func parseUnittestTests(result *types.TestResult, output string) {
+	ran := 0
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := unittestRanRegexp.FindStringSubmatch(line); match != nil {
+			ran = atoi(match[1])
+		} else if match := unittestFailureRegexp.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[1])
+		} else if strings.HasPrefix(line, "OK") || strings.HasPrefix(line, "FAILED") {
+			for _, match := range unittestCountRegexp.FindAllStringSubmatch(line, -1) {
+				if match[1] == "skipped" {
+					result.Skipped += atoi(match[2])
+				} else {
+					result.Failed += atoi(match[2])
+				}
+			}
+		}
+	}
+
+	result.Passed = ran - result.Failed - result.Skipped
+	if result.Passed < 0 {
+		result.Passed = 0
+	}
+}
+
+func parseMinitestTests(result *types.TestResult, output string) {
+	runs := 0
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := minitestResultRegexp.FindStringSubmatch(line); match != nil {
+			runs = atoi(match[1])
+			result.Failed = atoi(match[2]) + atoi(match[3])
+			result.Skipped = atoi(match[4])
+		} else if match := minitestFailureRegexp.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[1])
+		}
+	}
+
+	result.Passed = runs - result.Failed - result.Skipped
+	if result.Passed < 0 {
+		result.Passed = 0
+	}
+}
+
+func parseDotnetTests(result *types.TestResult, output string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := dotnetResultRegexp.FindStringSubmatch(line); match != nil {
+			result.Failed += atoi(match[1])
+			result.Passed += atoi(match[2])
+			result.Skipped += atoi(match[3])
+		} else if match := dotnetFailureRegexp.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[1])
+		}
+	}
+}
+
+func atoi(s string) int {
+	value, _ := strconv.Atoi(s)
+	return value
+}
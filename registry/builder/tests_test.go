@@ -0,0 +1,370 @@
+package builder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"eywa/registry/types"
+)
+
+const goTestOutput = `{"Time":"2024-03-01T10:00:00Z","Action":"run","Package":"handler","Test":"TestHandle"}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Test":"TestHandle","Output":"=== RUN   TestHandle\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Test":"TestHandle","Output":"--- PASS: TestHandle (0.00s)\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"pass","Package":"handler","Test":"TestHandle","Elapsed":0}
+{"Time":"2024-03-01T10:00:00Z","Action":"run","Package":"handler","Test":"TestEmptyBody"}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Test":"TestEmptyBody","Output":"    handler_test.go:21: want status 400, got 200\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"fail","Package":"handler","Test":"TestEmptyBody","Elapsed":0}
+{"Time":"2024-03-01T10:00:00Z","Action":"run","Package":"handler","Test":"TestUpstream"}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Test":"TestUpstream","Output":"    handler_test.go:30: needs network\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"skip","Package":"handler","Test":"TestUpstream","Elapsed":0}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Output":"FAIL\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler","Output":"coverage: 75.0% of statements\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"fail","Package":"handler","Elapsed":0.012}
+{"Time":"2024-03-01T10:00:00Z","Action":"output","Package":"handler/store","Output":"coverage: 25.0% of statements\n"}
+{"Time":"2024-03-01T10:00:00Z","Action":"pass","Package":"handler/store","Elapsed":0.004}
+`
+
+const tapOutput = `TAP version 13
+# Subtest: handle
+    ok 1 - returns 200
+    not ok 2 - flaky retry # TODO fix the backoff
+    1..2
+ok 1 - handle
+not ok 2 - rejects an empty body
+  ---
+  operator: equal
+  expected: 400
+  actual:   200
+  ...
+ok 3 - calls the upstream # SKIP needs network
+not ok 4 - retries # TODO not implemented
+1..4
+# tests 4
+# pass  1
+# fail  1
+# skip  1
+# todo  1
+`
+
+const junitNestedReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="jest tests" tests="4" failures="1" errors="1" time="0.412">
+  <testsuite name="handler" errors="1" failures="1" skipped="1" timestamp="2024-03-01T10:00:00" time="0.3" tests="4">
+    <testcase classname="handler" name="returns 200" time="0.01">
+    </testcase>
+    <testsuite name="errors" tests="2">
+      <testcase classname="handler errors" name="rejects an empty body" time="0.002">
+        <failure message="expected 400, received 200">Error: expect(received).toBe(expected)</failure>
+      </testcase>
+      <testcase classname="handler errors" name="times out" time="0.2">
+        <error message="Timeout">thrown: Exceeded timeout of 5000 ms for a test.</error>
+      </testcase>
+    </testsuite>
+    <testcase classname="handler" name="calls the upstream" time="0">
+      <skipped/>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+const junitSuiteReport = `<?xml version="1.0" encoding="utf-8"?>
+<testsuite name="pytest" errors="0" failures="0" skipped="0" tests="1" time="0.021" timestamp="2024-03-01T10:00:00" hostname="build">
+  <testcase name="test_store" time="0.001"/>
+</testsuite>
+`
+
+const cargoOutput = `   Compiling handler v0.1.0 (/home/app/function)
+    Finished test [unoptimized + debuginfo] target(s) in 1.52s
+     Running unittests src/lib.rs (target/debug/deps/handler-5d3c0e2f4b1a9e87)
+
+running 3 tests
+test tests::handles ... ok
+test tests::rejects_empty_body ... FAILED
+test tests::calls_upstream ... ignored
+
+failures:
+
+---- tests::rejects_empty_body stdout ----
+thread 'tests::rejects_empty_body' panicked at src/lib.rs:24:9:
+assertion ` + "`left == right`" + ` failed
+  left: 200
+ right: 400
+
+
+failures:
+    tests::rejects_empty_body
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out; finished in 0.00s
+
+   Doc-tests handler
+
+running 1 test
+test src/lib.rs - handle (line 5) ... ok
+
+test result: ok. 1 passed; 0 failed; 0 ignored; 0 measured; 0 filtered out; finished in 0.15s
+`
+
+const unittestOutput = `test_empty_body (test_handler.TestHandler.test_empty_body) ... FAIL
+test_handle (test_handler.TestHandler.test_handle) ... ok
+test_upstream (test_handler.TestHandler.test_upstream) ... skipped 'needs network'
+
+======================================================================
+FAIL: test_empty_body (test_handler.TestHandler.test_empty_body)
+----------------------------------------------------------------------
+Traceback (most recent call last):
+  File "/home/app/test_handler.py", line 12, in test_empty_body
+    self.assertEqual(status, 400)
+AssertionError: 200 != 400
+
+----------------------------------------------------------------------
+Ran 3 tests in 0.002s
+
+FAILED (failures=1, skipped=1)
+`
+
+const unittestNoTestsOutput = `
+----------------------------------------------------------------------
+Ran 0 tests in 0.000s
+
+NO TESTS RAN
+`
+
+// unittestSubTestsOutput has more failures than tests, every failing subTest is reported
+const unittestSubTestsOutput = `test_status_codes (test_handler.TestHandler.test_status_codes) ...
+======================================================================
+FAIL: test_status_codes (test_handler.TestHandler.test_status_codes) (code=400)
+----------------------------------------------------------------------
+AssertionError: 200 != 400
+
+======================================================================
+FAIL: test_status_codes (test_handler.TestHandler.test_status_codes) (code=404)
+----------------------------------------------------------------------
+AssertionError: 200 != 404
+
+----------------------------------------------------------------------
+Ran 1 test in 0.001s
+
+FAILED (failures=2)
+`
+
+const minitestOutput = `Run options: --seed 41052
+
+# Running:
+
+.FS
+
+Finished in 0.001245s, 2409.6386 runs/s, 2409.6386 assertions/s.
+
+  1) Failure:
+HandlerTest#test_empty_body [test/handler_test.rb:14]:
+Expected: 400
+  Actual: 200
+
+3 runs, 3 assertions, 1 failures, 0 errors, 1 skips
+
+You have skipped tests. Run with --verbose for details.
+`
+
+// minitestTeardownOutput has a test failing and then erroring in its teardown
+const minitestTeardownOutput = `Run options: --seed 7
+
+# Running:
+
+F
+
+Finished in 0.000912s, 1096.4912 runs/s, 1096.4912 assertions/s.
+
+  1) Failure:
+HandlerTest#test_upstream [test/handler_test.rb:20]:
+Expected: 200
+  Actual: 502
+
+  2) Error:
+HandlerTest#test_upstream [test/handler_test.rb:8]:
+Errno::ECONNREFUSED: Connection refused
+
+1 runs, 1 assertions, 1 failures, 1 errors, 0 skips
+`
+
+const dotnetOutput = `  Determining projects to restore...
+  All projects are up-to-date for restore.
+  Handler.Tests -> /home/app/tests/bin/Debug/net8.0/Handler.Tests.dll
+Test run for /home/app/tests/bin/Debug/net8.0/Handler.Tests.dll (.NETCoreApp,Version=v8.0)
+Starting test execution, please wait...
+A total of 1 test files matched the specified pattern.
+  Failed Handler.Tests.HandleTests.RejectsEmptyBody [12 ms]
+  Error Message:
+   Assert.Equal() Failure
+Expected: 400
+Actual:   200
+  Stack Trace:
+     at Handler.Tests.HandleTests.RejectsEmptyBody() in /home/app/tests/HandleTests.cs:line 21
+
+Failed!  - Failed:     1, Passed:     2, Skipped:     1, Total:     4, Duration: 35 ms - Handler.Tests.dll (net8.0)
+`
+
+func Test_ParseTestResult(t *testing.T) {
+	coverage := 50.0
+
+	tests := []struct {
+		name     string
+		format   string
+		output   string
+		exitCode int
+		reports  map[string]string
+		want     types.TestResult
+	}{
+		{
+			name:     "go",
+			format:   TestFormatGo,
+			output:   goTestOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 1, Failed: 1, Skipped: 1, Coverage: &coverage, Failures: []string{"handler.TestEmptyBody"}},
+		},
+		{
+			name:     "tap with subtests, skip and todo",
+			format:   TestFormatTAP,
+			output:   tapOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 1, Failed: 1, Skipped: 2, Failures: []string{"rejects an empty body"}},
+		},
+		{
+			name:     "tap todo failures do not fail",
+			format:   TestFormatTAP,
+			output:   "TAP version 13\nok 1 - handle\nnot ok 2 - retries # TODO not implemented\n1..2\n",
+			exitCode: 0,
+			want:     types.TestResult{Status: types.TestsPassed, Passed: 1, Skipped: 1, Failures: []string{}},
+		},
+		{
+			name:     "junit nested suites",
+			format:   TestFormatJUnit,
+			exitCode: 1,
+			reports:  map[string]string{"jest.xml": junitNestedReport, "pytest.xml": junitSuiteReport},
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 2, Failed: 2, Skipped: 1, Failures: []string{"handler errors.rejects an empty body", "handler errors.times out"}},
+		},
+		{
+			name:     "cargo with doc tests",
+			format:   TestFormatCargo,
+			output:   cargoOutput,
+			exitCode: 101,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 2, Failed: 1, Skipped: 1, Failures: []string{"tests::rejects_empty_body"}},
+		},
+		{
+			name:     "unittest",
+			format:   TestFormatUnittest,
+			output:   unittestOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 1, Failed: 1, Skipped: 1, Failures: []string{"test_empty_body"}},
+		},
+		{
+			name:     "unittest without tests exits with 5",
+			format:   TestFormatUnittest,
+			output:   unittestNoTestsOutput,
+			exitCode: 5,
+			want:     types.TestResult{Status: types.TestsPassed, Failures: []string{}},
+		},
+		{
+			name:     "unittest exit code 5 with tests",
+			format:   TestFormatUnittest,
+			output:   "Ran 2 tests in 0.001s\n\nOK\n",
+			exitCode: 5,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 2, Failures: []string{}},
+		},
+		{
+			name:     "unittest subtests clamp passed to zero",
+			format:   TestFormatUnittest,
+			output:   unittestSubTestsOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 0, Failed: 2, Failures: []string{"test_status_codes", "test_status_codes"}},
+		},
+		{
+			name:     "minitest",
+			format:   TestFormatMinitest,
+			output:   minitestOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 1, Failed: 1, Skipped: 1, Failures: []string{"HandlerTest#test_empty_body"}},
+		},
+		{
+			name:     "minitest teardown errors clamp passed to zero",
+			format:   TestFormatMinitest,
+			output:   minitestTeardownOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 0, Failed: 2, Failures: []string{"HandlerTest#test_upstream", "HandlerTest#test_upstream"}},
+		},
+		{
+			name:     "dotnet",
+			format:   TestFormatDotnet,
+			output:   dotnetOutput,
+			exitCode: 1,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 2, Failed: 1, Skipped: 1, Failures: []string{"Handler.Tests.HandleTests.RejectsEmptyBody"}},
+		},
+		{
+			name:     "non zero exit code without failures",
+			format:   TestFormatGo,
+			output:   `{"Action":"pass","Package":"handler","Test":"TestHandle"}` + "\n",
+			exitCode: 2,
+			want:     types.TestResult{Status: types.TestsFailed, Passed: 1, Failures: []string{}},
+		},
+	}
+
+	for _, test := range tests {
+		reportsDir, err := ioutil.TempDir("", "reports")
+		if err != nil {
+			t.Fatalf("Failed to create reports dir: %s", err)
+		}
+		defer os.RemoveAll(reportsDir)
+
+		for name, report := range test.reports {
+			if err := ioutil.WriteFile(filepath.Join(reportsDir, name), []byte(report), 0666); err != nil {
+				t.Fatalf("Failed to write report: %s", err)
+			}
+		}
+
+		result, err := parseTestResult(test.format, test.output, test.exitCode, reportsDir)
+		if err != nil {
+			t.Errorf("%s: want a result, got error %s", test.name, err)
+			continue
+		}
+
+		test.want.Format = test.format
+		if !reflect.DeepEqual(*result, test.want) {
+			t.Errorf("%s: want %s, got %s", test.name, describeTestResult(test.want), describeTestResult(*result))
+		}
+	}
+}
+
+func Test_ParseTestResult_InvalidReport(t *testing.T) {
+	reportsDir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatalf("Failed to create reports dir: %s", err)
+	}
+	defer os.RemoveAll(reportsDir)
+
+	if err := ioutil.WriteFile(filepath.Join(reportsDir, "report.xml"), []byte("<testsuite><testcase"), 0666); err != nil {
+		t.Fatalf("Failed to write report: %s", err)
+	}
+
+	if _, err := parseTestResult(TestFormatJUnit, "", 0, reportsDir); err == nil {
+		t.Errorf("Want an error for an invalid report")
+	}
+}
+
+func Test_ParseTestResult_UnsupportedFormat(t *testing.T) {
+	if _, err := parseTestResult("mocha", "", 0, ""); err == nil {
+		t.Errorf("Want an error for an unsupported format")
+	}
+}
+
+func describeTestResult(result types.TestResult) string {
+	coverage := "none"
+	if result.Coverage != nil {
+		coverage = strconv.FormatFloat(*result.Coverage, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%s passed=%d failed=%d skipped=%d coverage=%s failures=%q",
+		result.Status, result.Passed, result.Failed, result.Skipped, coverage, result.Failures)
+}
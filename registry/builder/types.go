@@ -6,8 +6,16 @@ const (
 	StateSuccess  = "success"
 	StateFailed   = "failed"
 	StateQueued   = "queued"
+	// StateCancelled and StateTimedOut are failed builds stopped by a user or the build timeout
+	StateCancelled = "cancelled"
+	StateTimedOut  = "timed_out"
 )
 
+// Finished reports whether a build or image in state is done building
+func Finished(state string) bool {
+	return state != StateQueued && state != StateBuilding
+}
+
 // BuildRequest represents the payload of a request to build a new image
 type BuildRequest struct {
 	ImageID        string
@@ -19,5 +27,9 @@ type BuildRequest struct {
 	tmpDir         string
 	LogFile        string
 	ExecutablePath *string
+	Git            *GitSource // Git is the source of the build when it is not ZippedSource
+	RunTests       bool       // RunTests runs the test stage of the runtime before building the image
 	requiredFiles  []string
+	cacheDir       string // cacheDir is the build cache of the build, empty when caching is disabled
+	cgroup         string // cgroup limits the resources of the build, empty when there are no limits
 }
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/resty.v1"
+
+	gwt "eywa/gateway/types"
+	"eywa/go-libs/auth"
+)
+
+// Client represents gateway client
+type Client struct {
+	rc *resty.Client
+}
+
+// New returns a new gateway client
+func New(gatewayURL string) *Client {
+	return &Client{
+		rc: resty.New().
+			SetHostURL(gatewayURL).
+			SetLogger(ioutil.Discard).
+			SetRetryCount(3).
+			SetTimeout(30 * time.Second),
+	}
+}
+
+// RolloutImage rolls the functions of the user with auto deploy enabled forward to a built image,
+// it fails with the functions that could not be rolled out
+func (c *Client) RolloutImage(imageID, userID string) error {
+	var result gwt.RolloutResponse
+	resp, err := c.rc.R().
+		SetResult(&result).
+		SetHeader("X-Eywa-User-Id", userID).
+		SetHeader("X-Eywa-Real-User-Id", auth.OperatorUserID).
+		Post("/eywa/api/system/images/" + imageID + "/rollout")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("Gateway responded with unexpected status: %s", resp.Status())
+	}
+
+	if len(result.Errors) > 0 {
+		failures := make([]string, 0, len(result.Errors))
+		for _, rolloutError := range result.Errors {
+			failures = append(failures, fmt.Sprintf("%s (%s): %s", rolloutError.FunctionName, rolloutError.FunctionID, rolloutError.Error))
+		}
+		return fmt.Errorf("%d of %d functions failed to roll out: %s", len(result.Errors), len(result.Errors)+result.Total,
+			strings.Join(failures, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	faasNamespace = "faas"
+
+	userIDLabel   = "user_id"
+	secretIDLabel = "secret_id"
+)
+
+// Client reads the secrets users created for their functions
+type Client struct {
+	clientset *kubernetes.Clientset
+}
+
+// New creates a new in cluster k8s client
+func New() (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{clientset: clientset}, nil
+}
+
+// GetSecretData returns the data of a secret of the user, nil when there is no such secret
+func (c *Client) GetSecretData(userID, secretID string) (map[string][]byte, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		userIDLabel:   userID,
+		secretIDLabel: secretID,
+	})
+
+	secrets, err := c.clientset.CoreV1().
+		Secrets(faasNamespace).
+		List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secrets.Items) == 0 {
+		return nil, nil
+	}
+
+	if len(secrets.Items) != 1 {
+		log.Warnf("K8s returned more than one secret %s of user %s", secretID, userID)
+	}
+
+	return secrets.Items[0].Data, nil
+}
@@ -1,12 +1,12 @@
 package controllers
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +20,20 @@ import (
 	"eywa/registry/types"
 )
 
+// buildLogsPollInterval is how often the logs of a running build are checked for new lines
+const buildLogsPollInterval = time.Second
+
+// GetRuntimes returns the runtimes images can be built for
+func GetRuntimes(c echo.Context) error {
+	bc := c.Get("builder").(*builder.Client)
+
+	runtimes := bc.Runtimes()
+	return c.JSON(http.StatusOK, types.GetRuntimesResponse{
+		Objects: runtimes,
+		Total:   len(runtimes),
+	})
+}
+
 // GetImages returns all the images a user can access
 func GetImages(c echo.Context) error {
 	db := c.Get("db").(*db.Client)
@@ -62,137 +76,275 @@ func GetImage(c echo.Context) error {
 		image.TaggedRegistry = ""
 	}
 
+	if image.State == builder.StateQueued || image.State == builder.StateBuilding {
+		bc := c.Get("builder").(*builder.Client)
+
+		image.QueuePosition, image.ETA, err = bc.Estimate(imageID, auth.UserID)
+		if err != nil {
+			log.Errorf("Failed to estimate build completion: %s", err)
+		}
+	}
+
 	return c.JSON(http.StatusOK, image)
 }
 
-// RequestImageBuild queues up a new image build
+// RequestImageBuild queues up a new image build from an uploaded zip or a git repository
 func RequestImageBuild(c echo.Context) error {
-	db := c.Get("db").(*db.Client)
-	bc := c.Get("builder").(*builder.Client)
 	auth := c.Get("auth").(*auth.Auth)
 
-	file, err := c.FormFile("source")
-	if err != nil {
-		log.Errorf("Failed to get source from payload: %s", err)
-		return err
-	}
-
 	runtime := strings.ToLower(c.FormValue("runtime"))
 	version := c.FormValue("version")
 	name := c.FormValue("name")
 
+	bc := c.Get("builder").(*builder.Client)
+	rt, ok := bc.Runtime(runtime)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, builder.ErrUnsupportedLanguage.String())
+	}
+
 	var executablePath *string
-	if runtime == "custom" {
-		executableString := c.FormValue("executable_path")
-		if executableString == "" {
-			return c.JSON(http.StatusBadRequest, "Executable is required when using custom mode")
-		}
+	if executableString := c.FormValue("executable_path"); executableString != "" && rt.ExecutablePath != "" {
 		executablePath = &executableString
 	}
 
-	fullName := fmt.Sprintf("%s##%s##%s", runtime, name, version)
-	id := uuid.NewV5(uuid.FromStringOrNil(auth.UserID), fullName).String()
+	if rt.ExecutablePath == types.ExecutablePathRequired && executablePath == nil {
+		return c.JSON(http.StatusBadRequest, fmt.Sprintf("Executable is required when using %s runtime", runtime))
+	}
 
-	existingImage, err := db.GetImageWithoutSource(id, auth.UserID)
-	if err != nil {
-		log.Errorf("Failed to retrieve image from db: %s", err)
-		return err
+	// Tests run unless they are turned off
+	runTests := true
+	if runTestsString := c.FormValue("run_tests"); runTestsString != "" {
+		var err error
+		runTests, err = strconv.ParseBool(runTestsString)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "run_tests must be a boolean")
+		}
 	}
 
-	if existingImage != nil {
-		return c.JSON(http.StatusConflict, "Exact same image already exists")
+	br := builder.BuildRequest{
+		UserID:         auth.UserID,
+		Name:           name,
+		Runtime:        runtime,
+		Version:        version,
+		ExecutablePath: executablePath,
+		RunTests:       runTests,
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		log.Errorf("Failed to open file header for reading: %s", err)
-		return err
+	switch c.FormValue("source_type") {
+	case builder.SourceGit:
+		gitURL := c.FormValue("git_url")
+		if err := validateGitURL(c.Request().Context(), gitURL); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		br.Git = &builder.GitSource{
+			URL:  gitURL,
+			Ref:  c.FormValue("git_ref"),
+			Path: c.FormValue("git_path"),
+		}
+
+		if br.Git.Ref == "" {
+			br.Git.Ref = "HEAD"
+		}
+
+		if err := builder.ValidateGitRef(br.Git.Ref); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		if secretID := c.FormValue("git_secret_id"); secretID != "" {
+			br.Git.SecretID = &secretID
+		}
+
+	default:
+		file, err := c.FormFile("source")
+		if err != nil {
+			log.Errorf("Failed to get source from payload: %s", err)
+			return err
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			log.Errorf("Failed to open file header for reading: %s", err)
+			return err
+		}
+		defer src.Close()
+
+		br.ZippedSource, err = ioutil.ReadAll(src)
+		if err != nil {
+			log.Errorf("Failed to read body: %s", err)
+			return err
+		}
 	}
-	defer src.Close()
 
-	body, err := ioutil.ReadAll(src)
+	return enqueueBuild(c, br)
+}
+
+// enqueueBuild queues up the build of a new image and responds with its id
+func enqueueBuild(c echo.Context, br builder.BuildRequest) error {
+	db := c.Get("db").(*db.Client)
+	bc := c.Get("builder").(*builder.Client)
+
+	fullName := fmt.Sprintf("%s##%s##%s", br.Runtime, br.Name, br.Version)
+	br.ImageID = uuid.NewV5(uuid.FromStringOrNil(br.UserID), fullName).String()
+
+	existingImage, err := db.GetImageWithoutSource(br.ImageID, br.UserID)
 	if err != nil {
-		log.Errorf("Failed to read body: %s", err)
+		log.Errorf("Failed to retrieve image from db: %s", err)
 		return err
 	}
 
-	builderErr := bc.Enqueue(builder.BuildRequest{
-		ImageID:        id,
-		UserID:         auth.UserID,
-		Name:           name,
-		Runtime:        runtime,
-		Version:        version,
-		ZippedSource:   body,
-		ExecutablePath: executablePath,
-	})
+	if existingImage != nil {
+		return c.JSON(http.StatusConflict, "Exact same image already exists")
+	}
+
+	builderErr := bc.Enqueue(br)
 	if builderErr != nil {
 		if builderErr.Type == builder.ErrTypeUserError {
 			return c.JSON(http.StatusBadRequest, builderErr.String())
 		}
 
 		log.Errorf("Failed enqueue build: %s", builderErr.String())
-		c.JSON(http.StatusInternalServerError, "Internal Server Error")
+		return c.JSON(http.StatusInternalServerError, "Internal Server Error")
 	}
 
 	return c.JSON(http.StatusOK, types.ImageBuildResponse{
-		BuildID:   id,
+		BuildID:   br.ImageID,
 		CreatedAt: time.Now(),
 	})
 }
 
-// GetImageBuildLogs streams image build logs either live or from the db
+// validateGitURL accepts http(s) repository URLs without credentials, those are taken from a secret,
+// whose host resolves to public addresses only
+func validateGitURL(ctx context.Context, gitURL string) error {
+	u, err := url.Parse(gitURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("Git URL must be a http or https repository URL")
+	}
+
+	if u.User != nil {
+		return fmt.Errorf("Git URL must not contain credentials, use git_secret_id instead")
+	}
+
+	return builder.ValidateGitHost(ctx, gitURL)
+}
+
+// GetImageBuildLogs streams the image build logs as server-sent events. Every log line is sent
+// as a "log" event with its index as id, once the build finished a "state" event with its state
+// follows. A client reconnecting with Last-Event-ID resumes after that line.
 func GetImageBuildLogs(c echo.Context) error {
 	db := c.Get("db").(*db.Client)
-	bc := c.Get("builder").(*builder.Client)
 	auth := c.Get("auth").(*auth.Auth)
 	imageID := c.Param("image_id")
 
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
-	c.Response().WriteHeader(http.StatusOK)
+	build, err := db.GetBuild(imageID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get build from db: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
 
-	existingBuild := bc.GetBuild(imageID, auth.UserID)
-	if existingBuild != nil {
-		logs := []string{}
-		logFile, err := os.OpenFile(existingBuild.LogFile, os.O_RDONLY, 0666)
-		if err != nil {
-			log.Errorf("Failed to read logs file: %s", err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	if build == nil {
+		return c.JSON(http.StatusNotFound, "No build logs found")
+	}
+
+	sent := 0
+	if lastEventID, err := strconv.Atoi(c.Request().Header.Get("Last-Event-ID")); err == nil && lastEventID >= 0 {
+		sent = lastEventID + 1
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+	res.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(buildLogsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ; sent < len(build.Logs); sent++ {
+			fmt.Fprintf(res, "id: %d\nevent: log\ndata: %s\n\n", sent, build.Logs[sent])
+		}
+
+		if builder.Finished(build.State) {
+			fmt.Fprintf(res, "event: state\ndata: %s\n\n", build.State)
+			res.Flush()
+			return nil
 		}
-		logFile.Seek(0, io.SeekStart)
+		res.Flush()
 
-		scanner := bufio.NewScanner(logFile)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			logs = append(logs, scanner.Text())
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-ticker.C:
 		}
 
-		return c.JSON(http.StatusOK, types.ImageLogs{Logs: logs})
-	} else {
-		dbBuild, err := db.GetBuild(imageID, auth.UserID)
+		build, err = db.GetBuild(imageID, auth.UserID)
 		if err != nil {
 			log.Errorf("Failed to get build from db: %s", err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+			return nil
 		}
 
-		if dbBuild == nil {
-			return c.JSON(http.StatusNotFound, "No build logs found")
+		// The image was deleted
+		if build == nil {
+			return nil
 		}
-		return c.JSON(http.StatusOK, types.ImageLogs{Logs: dbBuild.Logs})
 	}
 }
 
-// DeleteImage deletes the image from db and registry
-func DeleteImage(c echo.Context) error {
+// GetImageTests returns the result of the function tests of an image build
+func GetImageTests(c echo.Context) error {
 	db := c.Get("db").(*db.Client)
-	builder := c.Get("builder").(*builder.Client)
 	auth := c.Get("auth").(*auth.Auth)
 	imageID := c.Param("image_id")
 
-	if inProgressBuild := builder.GetBuild(imageID, auth.UserID); inProgressBuild != nil {
-		return c.JSON(http.StatusBadRequest, "Cannot delete build in progress")
+	build, err := db.GetBuild(imageID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get build from db: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if build == nil || build.Tests == nil {
+		return c.JSON(http.StatusNotFound, "No test results found")
 	}
 
+	return c.JSON(http.StatusOK, build.Tests)
+}
+
+// CancelImageBuild cancels a queued or running image build, a running build is stopped asynchronously
+func CancelImageBuild(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	bc := c.Get("builder").(*builder.Client)
+	auth := c.Get("auth").(*auth.Auth)
+	imageID := c.Param("image_id")
+
+	build, err := db.GetBuild(imageID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to get build from db: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if build == nil {
+		return c.JSON(http.StatusNotFound, "Not Found")
+	}
+
+	if builderErr := bc.Cancel(build); builderErr != nil {
+		if builderErr.Type == builder.ErrTypeUserError {
+			return c.JSON(http.StatusConflict, builderErr.String())
+		}
+
+		log.Errorf("Failed to cancel build: %s", builderErr.String())
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// DeleteImage deletes the image from db and registry
+func DeleteImage(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	auth := c.Get("auth").(*auth.Auth)
+	imageID := c.Param("image_id")
+
 	image, err := db.GetImageWithoutSource(imageID, auth.UserID)
 	if err != nil {
 		log.Errorf("Failed to retrieve image: %s", err)
@@ -203,6 +355,11 @@ func DeleteImage(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, "Not Found")
 	}
 
+	// Queued builds may be claimed by a worker at any time
+	if !builder.Finished(image.State) {
+		return c.JSON(http.StatusBadRequest, "Cannot delete build in progress, cancel it first")
+	}
+
 	// Don't delete from docker in case some function is still using it.
 	// Docker registry will cleanup eventually.
 
@@ -211,6 +368,11 @@ func DeleteImage(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
 	}
 
+	if err := db.DeleteImageVulnerabilities(imageID); err != nil {
+		log.Errorf("Failed to delete image vulnerabilities from db: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
 	if err := db.DeleteImage(imageID, auth.UserID); err != nil {
 		log.Errorf("Failed to delete image form db: %s", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"eywa/go-libs/auth"
+	"eywa/registry/db"
+	"eywa/registry/types"
+)
+
+// GetImageVulnerabilities returns the vulnerabilities found in an image and whether the
+// vulnerability policy allows to deploy it
+func GetImageVulnerabilities(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	auth := c.Get("auth").(*auth.Auth)
+	imageID := c.Param("image_id")
+
+	image, err := db.GetImageWithoutSource(imageID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to retrieve image: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if image == nil {
+		return c.JSON(http.StatusNotFound, "Not Found")
+	}
+
+	vulnerabilities, err := db.GetImageVulnerabilities(imageID)
+	if err != nil {
+		log.Errorf("Failed to retrieve image vulnerabilities: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	policy, err := db.GetVulnerabilityPolicy()
+	if err != nil {
+		log.Errorf("Failed to retrieve vulnerability policy: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	summary := types.Summarize(vulnerabilities)
+	violations := policy.Violations(image.ScanState, summary)
+
+	return c.JSON(http.StatusOK, types.GetImageVulnerabilitiesResponse{
+		Objects:    vulnerabilities,
+		Total:      len(vulnerabilities),
+		ScanState:  image.ScanState,
+		ScannedAt:  image.ScannedAt,
+		Summary:    summary,
+		Deployable: len(violations) == 0,
+		Violations: violations,
+	})
+}
+
+// GetVulnerabilityPolicy returns the vulnerability policy images are deployed with
+func GetVulnerabilityPolicy(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+
+	policy, err := db.GetVulnerabilityPolicy()
+	if err != nil {
+		log.Errorf("Failed to retrieve vulnerability policy: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdateVulnerabilityPolicy replaces the vulnerability policy, only operators can change it
+func UpdateVulnerabilityPolicy(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	auth := c.Get("auth").(*auth.Auth)
+
+	if !auth.IsOperator() {
+		return c.JSON(http.StatusForbidden, "Forbidden")
+	}
+
+	var policy types.VulnerabilityPolicy
+	if err := c.Bind(&policy); err != nil {
+		log.Errorf("Failed to bind vulnerability policy: %s", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	}
+
+	for _, max := range []*int{policy.MaxCritical, policy.MaxHigh, policy.MaxMedium, policy.MaxLow} {
+		if max != nil && *max < 0 {
+			return c.JSON(http.StatusBadRequest, "Maximum vulnerabilities must not be negative")
+		}
+	}
+
+	now := time.Now()
+	policy.UpdatedAt = &now
+
+	if err := db.UpdateVulnerabilityPolicy(&policy); err != nil {
+		log.Errorf("Failed to update vulnerability policy: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
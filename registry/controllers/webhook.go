@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+
+	"eywa/go-libs/auth"
+	"eywa/go-libs/semver"
+	"eywa/registry/builder"
+	"eywa/registry/db"
+	"eywa/registry/types"
+)
+
+const (
+	// signatureHeader holds the HMAC-SHA256 of a webhook payload signed with the webhook signing secret
+	signatureHeader = "X-Hub-Signature-256"
+	// maxWebhookPayload is the largest webhook payload accepted
+	maxWebhookPayload = 1 << 20
+	// zeroCommit is the commit a push deleting a ref points to
+	zeroCommit = "0000000000000000000000000000000000000000"
+)
+
+// GetGitWebhooks returns the git webhooks of a user
+func GetGitWebhooks(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	auth := c.Get("auth").(*auth.Auth)
+
+	webhooks, err := db.GetGitWebhooks(auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to retrieve git webhooks: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	// The signing secret is only returned when the webhook is created
+	for i := range webhooks {
+		webhooks[i].SigningSecret = ""
+	}
+
+	return c.JSON(http.StatusOK, types.GetGitWebhooksResponse{
+		Objects: webhooks,
+		Total:   len(webhooks),
+	})
+}
+
+// CreateGitWebhook creates a webhook building a new image version on every push to a git ref
+func CreateGitWebhook(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	bc := c.Get("builder").(*builder.Client)
+	auth := c.Get("auth").(*auth.Auth)
+
+	var req types.CreateGitWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		log.Errorf("Failed to bind git webhook request: %s", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	}
+
+	req.Runtime = strings.ToLower(req.Runtime)
+	rt, ok := bc.Runtime(req.Runtime)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, builder.ErrUnsupportedLanguage.String())
+	}
+
+	if rt.ExecutablePath == types.ExecutablePathRequired && req.ExecutablePath == nil {
+		return c.JSON(http.StatusBadRequest, fmt.Sprintf("Executable is required when using %s runtime", req.Runtime))
+	}
+
+	if err := validateGitURL(c.Request().Context(), req.GitURL); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := builder.ValidateGitRef(req.GitRef); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		log.Errorf("Failed to generate git webhook id: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Errorf("Failed to generate git webhook signing secret: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	webhook := &types.GitWebhook{
+		ID:             id.String(),
+		UserID:         auth.UserID,
+		Name:           req.Name,
+		Runtime:        req.Runtime,
+		Version:        req.Version,
+		ExecutablePath: req.ExecutablePath,
+		GitURL:         req.GitURL,
+		GitRef:         req.GitRef,
+		GitPath:        req.GitPath,
+		GitSecretID:    req.GitSecretID,
+		SigningSecret:  hex.EncodeToString(secret),
+		CreatedAt:      time.Now(),
+	}
+
+	if err := db.CreateGitWebhook(webhook); err != nil {
+		log.Errorf("Failed to create git webhook: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteGitWebhook deletes a git webhook
+func DeleteGitWebhook(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	auth := c.Get("auth").(*auth.Auth)
+	webhookID := c.Param("webhook_id")
+
+	deleted, err := db.DeleteGitWebhook(webhookID, auth.UserID)
+	if err != nil {
+		log.Errorf("Failed to delete git webhook: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if !deleted {
+		return c.JSON(http.StatusNotFound, "Not Found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ReceiveGitWebhook builds the pushed commit of a push event signed with the webhook signing
+// secret as the next patch version of the webhook image. Pushes to other refs are ignored.
+func ReceiveGitWebhook(c echo.Context) error {
+	db := c.Get("db").(*db.Client)
+	webhookID := c.Param("webhook_id")
+
+	if _, err := uuid.FromString(webhookID); err != nil {
+		return c.JSON(http.StatusNotFound, "Not Found")
+	}
+
+	webhook, err := db.GetGitWebhook(webhookID)
+	if err != nil {
+		log.Errorf("Failed to retrieve git webhook: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if webhook == nil {
+		return c.JSON(http.StatusNotFound, "Not Found")
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, maxWebhookPayload))
+	if err != nil {
+		log.Errorf("Failed to read git webhook payload: %s", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad Request")
+	}
+
+	if !validSignature(webhook.SigningSecret, payload, c.Request().Header.Get(signatureHeader)) {
+		return c.JSON(http.StatusUnauthorized, "Invalid signature")
+	}
+
+	var event types.GitPushEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid push event")
+	}
+
+	// Ping and other events have no ref
+	if !refMatches(event.Ref, webhook.GitRef) || event.After == "" || event.After == zeroCommit {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	versions, err := db.GetImageVersions(webhook.UserID, webhook.Name, webhook.Runtime)
+	if err != nil {
+		log.Errorf("Failed to retrieve image versions: %s", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	path := ""
+	if webhook.GitPath != nil {
+		path = *webhook.GitPath
+	}
+
+	return enqueueBuild(c, builder.BuildRequest{
+		UserID:         webhook.UserID,
+		Name:           webhook.Name,
+		Runtime:        webhook.Runtime,
+		Version:        nextVersion(webhook.Version, versions),
+		ExecutablePath: webhook.ExecutablePath,
+		RunTests:       true,
+		Git: &builder.GitSource{
+			URL:      webhook.GitURL,
+			Ref:      event.After,
+			Path:     path,
+			SecretID: webhook.GitSecretID,
+		},
+	})
+}
+
+func validSignature(secret string, payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// refMatches reports whether the pushed ref is the branch or tag of the webhook
+func refMatches(pushed, ref string) bool {
+	return pushed != "" && (pushed == ref || pushed == "refs/heads/"+ref || pushed == "refs/tags/"+ref)
+}
+
+// nextVersion returns the release following the highest of versions, initial when there is none.
+// A prerelease is followed by its release, e.g. 1.2.3-rc.1 by 1.2.3.
+func nextVersion(initial string, versions []string) string {
+	var highest *semver.Version
+	for _, version := range versions {
+		parsed, err := semver.Parse(version)
+		if err == nil && (highest == nil || parsed.Compare(*highest) > 0) {
+			highest = &parsed
+		}
+	}
+
+	if highest == nil {
+		return initial
+	}
+
+	return highest.NextPatch().String()
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import "testing"
+
+func Test_nextVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+	}{
+		{"no versions", nil, "0.0.1"},
+		{"no semver versions", []string{"latest", "v2"}, "0.0.1"},
+		{"release", []string{"1.0.0", "1.2.3", "1.1.9"}, "1.2.4"},
+		{"prerelease", []string{"1.2.2", "1.2.3-rc.1"}, "1.2.3"},
+		{"release of a prerelease", []string{"1.2.3-rc.1", "1.2.3"}, "1.2.4"},
+	}
+
+	for _, test := range tests {
+		if got := nextVersion("0.0.1", test.versions); got != test.want {
+			t.Errorf("%s: want %s, got %s", test.name, test.want, got)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"xorm.io/builder"
 
@@ -31,11 +33,13 @@ func (c *Client) GetBuild(imageID, userID string) (*types.Build, error) {
 // CreateBuild inserts a new build into db
 func (c *Client) CreateBuild(build *types.Build) error {
 	query := c.Builder().Insert(builder.Eq{
-		"image_id":   build.ImageID,
-		"user_id":    build.UserID,
-		"logs":       build.Logs,
-		"state":      build.State,
-		"created_at": build.CreatedAt,
+		"image_id":        build.ImageID,
+		"user_id":         build.UserID,
+		"logs":            build.Logs,
+		"state":           build.State,
+		"created_at":      build.CreatedAt,
+		"executable_path": build.ExecutablePath,
+		"run_tests":       build.RunTests,
 	}).Into("builds")
 
 	_, err := c.Exec(query)
@@ -56,6 +60,194 @@ func (c *Client) UpdateBuild(imageID, state string, logs pq.StringArray) error {
 	return err
 }
 
+// SetBuildTests stores the test result of a build
+func (c *Client) SetBuildTests(imageID string, tests *types.TestResult) error {
+	query := c.Builder().
+		Update(builder.Eq{"tests": tests}).
+		From("builds").
+		Where(builder.Eq{"image_id": imageID})
+
+	_, err := c.Exec(query)
+	return err
+}
+
+// AppendBuildLogs appends lines to the logs of a build
+func (c *Client) AppendBuildLogs(imageID string, lines pq.StringArray) error {
+	_, err := c.ex.Exec(`UPDATE builds SET logs = logs || $1::text[] WHERE image_id = $2`, lines, imageID)
+	return err
+}
+
+// FinishBuild stores the outcome of a build
+func (c *Client) FinishBuild(imageID, state string, logs pq.StringArray, finishedAt time.Time) error {
+	query := c.Builder().
+		Update(builder.Eq{
+			"logs":        logs,
+			"state":       state,
+			"finished_at": finishedAt,
+		}).
+		From("builds").
+		Where(builder.Eq{"image_id": imageID})
+
+	_, err := c.Exec(query)
+	return err
+}
+
+// ClaimBuild moves the oldest build in state from to state to, along with its image, and
+// returns it. Builds claimed by another worker are skipped, nil is returned when there is none.
+func (c *Client) ClaimBuild(from, to string, startedAt time.Time) (*types.Build, error) {
+	tx, err := c.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.End()
+
+	var build types.Build
+	err = sqlx.Get(tx.ex, &build, `SELECT * FROM builds WHERE state = $1
+		ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED`, from)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	build.State = to
+	build.Attempts++
+	build.StartedAt = &startedAt
+	build.HeartbeatAt = &startedAt
+
+	query := c.Builder().
+		Update(builder.Eq{
+			"state":        build.State,
+			"attempts":     build.Attempts,
+			"started_at":   startedAt,
+			"heartbeat_at": startedAt,
+		}).
+		From("builds").
+		Where(builder.Eq{"image_id": build.ImageID})
+
+	if _, err := tx.Exec(query); err != nil {
+		return nil, err
+	}
+
+	if err := tx.UpdateImageState(build.ImageID, to); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &build, nil
+}
+
+// HeartbeatBuild records that the worker running a build is alive and returns whether the
+// build was cancelled since
+func (c *Client) HeartbeatBuild(imageID string, at time.Time) (bool, error) {
+	var cancelRequested bool
+	err := sqlx.Get(c.ex, &cancelRequested, `UPDATE builds SET heartbeat_at = $1
+		WHERE image_id = $2 RETURNING cancel_requested`, at, imageID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return cancelRequested, err
+}
+
+// RequestBuildCancel marks the build as cancelled for the worker running it, false is returned
+// when the build is not in state
+func (c *Client) RequestBuildCancel(imageID, state string) (bool, error) {
+	query := c.Builder().
+		Update(builder.Eq{"cancel_requested": true}).
+		From("builds").
+		Where(builder.Eq{"image_id": imageID, "state": state})
+
+	res, err := c.Exec(query)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// CancelBuild moves a build in state from to state to, along with its image, and appends lines
+// to its logs. False is returned when the build is not in state from.
+func (c *Client) CancelBuild(imageID, from, to string, lines pq.StringArray, finishedAt time.Time) (bool, error) {
+	tx, err := c.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.End()
+
+	res, err := tx.ex.Exec(`UPDATE builds SET state = $1, logs = logs || $2::text[], finished_at = $3
+		WHERE image_id = $4 AND state = $5`, to, lines, finishedAt, imageID, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := tx.UpdateImageState(imageID, to); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// GetStaleBuilds returns the builds in state whose last heartbeat is older than before
+func (c *Client) GetStaleBuilds(state string, before time.Time) ([]types.Build, error) {
+	query := c.Builder().Select("*").
+		From("builds").
+		Where(builder.Eq{"state": state}.And(
+			builder.Or(builder.Lt{"heartbeat_at": before}, builder.IsNull{"heartbeat_at"}),
+		))
+
+	builds := []types.Build{}
+	if err := c.Select(&builds, query); err != nil {
+		return nil, err
+	}
+
+	return builds, nil
+}
+
+// GetQueuePosition returns the position of a build among the builds in state, starting at 1
+func (c *Client) GetQueuePosition(imageID, state string) (int, error) {
+	query := c.Builder().Select("count(*)").
+		From("builds").
+		Where(builder.Eq{"state": state}.And(
+			builder.Expr("created_at <= (SELECT created_at FROM builds WHERE image_id = ?)", imageID),
+		))
+
+	var position int
+	if err := c.Get(&position, query); err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+// GetAverageBuildDuration returns the average duration of the last sample builds finished in state,
+// zero when there is none
+func (c *Client) GetAverageBuildDuration(state string, sample int) (time.Duration, error) {
+	var seconds float64
+	err := sqlx.Get(c.ex, &seconds, `SELECT COALESCE(EXTRACT(EPOCH FROM avg(finished_at - started_at)), 0)
+		FROM (
+			SELECT started_at, finished_at FROM builds
+			WHERE state = $1 AND started_at IS NOT NULL AND finished_at IS NOT NULL
+			ORDER BY finished_at DESC LIMIT $2
+		) recent`, state, sample)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
 // DeleteBuild deletes build info form the db
 func (c *Client) DeleteBuild(imageID, userID string) error {
 	query := c.Builder().
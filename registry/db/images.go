@@ -12,7 +12,9 @@ import (
 func (c *Client) GetImageWithoutSource(imageID, userID string) (*types.Image, error) {
 	query := c.Builder().
 		Select(`i.id, i.user_id, i.registry, i.language,
-		 i.name, i.version, i.created_at, i.state, i.size`).
+		 i.name, i.version, i.created_at, i.state, i.size, i.source_type,
+		 i.git_url, i.git_ref, i.git_path, i.git_secret_id, i.commit_sha,
+		 i.scan_state, i.scanned_at`).
 		From("images i").
 		Where(builder.Eq{
 			"i.id":      imageID,
@@ -31,11 +33,28 @@ func (c *Client) GetImageWithoutSource(imageID, userID string) (*types.Image, er
 	return &image, nil
 }
 
+// GetImageSource returns the base64 encoded source of an image
+func (c *Client) GetImageSource(imageID string) (string, error) {
+	query := c.Builder().
+		Select("source").
+		From("images").
+		Where(builder.Eq{"id": imageID})
+
+	var source string
+	if err := c.Get(&source, query); err != nil {
+		return "", err
+	}
+
+	return source, nil
+}
+
 // GetImagesWithoutSource returns images without source
 func (c *Client) GetImagesWithoutSource(userID, filter string, pageNumber, perPage int) ([]types.Image, int, error) {
 	query := c.Builder().
 		Select(`i.id, i.user_id, i.registry, i.language,
-		 i.name, i.version, i.created_at, i.state, i.size`).
+		 i.name, i.version, i.created_at, i.state, i.size, i.source_type,
+		 i.git_url, i.git_ref, i.git_path, i.git_secret_id, i.commit_sha,
+		 i.scan_state, i.scanned_at`).
 		From("images i").
 		Where(builder.Eq{"i.user_id": userID})
 
@@ -54,16 +73,21 @@ func (c *Client) GetImagesWithoutSource(userID, filter string, pageNumber, perPa
 // CreateImage creates a new image inside the db
 func (c *Client) CreateImage(image *types.Image) error {
 	query := c.Builder().Insert(builder.Eq{
-		"id":         image.ID,
-		"user_id":    image.UserID,
-		"registry":   image.TaggedRegistry,
-		"language":   image.Runtime,
-		"name":       image.Name,
-		"version":    image.Version,
-		"created_at": image.CreatedAt,
-		"state":      image.State,
-		"size":       image.Size,
-		"source":     image.Source,
+		"id":            image.ID,
+		"user_id":       image.UserID,
+		"registry":      image.TaggedRegistry,
+		"language":      image.Runtime,
+		"name":          image.Name,
+		"version":       image.Version,
+		"created_at":    image.CreatedAt,
+		"state":         image.State,
+		"size":          image.Size,
+		"source":        image.Source,
+		"source_type":   image.SourceType,
+		"git_url":       image.GitURL,
+		"git_ref":       image.GitRef,
+		"git_path":      image.GitPath,
+		"git_secret_id": image.GitSecretID,
 	}).Into("images")
 
 	_, err := c.Exec(query)
@@ -81,6 +105,36 @@ func (c *Client) UpdateImageState(imageID, state string) error {
 	return err
 }
 
+// SetImageCommit records the commit an image was built from
+func (c *Client) SetImageCommit(imageID, commitSHA string) error {
+	query := c.Builder().
+		Update(builder.Eq{"commit_sha": commitSHA}).
+		From("images").
+		Where(builder.Eq{"id": imageID})
+
+	_, err := c.Exec(query)
+	return err
+}
+
+// GetImageVersions returns the versions of the images of a user with the name and runtime
+func (c *Client) GetImageVersions(userID, name, runtime string) ([]string, error) {
+	query := c.Builder().
+		Select("version").
+		From("images").
+		Where(builder.Eq{
+			"user_id":  userID,
+			"name":     name,
+			"language": runtime,
+		})
+
+	versions := []string{}
+	if err := c.Select(&versions, query); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
 // DeleteImage deletes image from the db
 func (c *Client) DeleteImage(imageID, userID string) error {
 	query := c.Builder().
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"time"
+
+	"xorm.io/builder"
+
+	"eywa/registry/types"
+)
+
+// GetImageVulnerabilities returns the vulnerabilities of an image, the most severe first
+func (c *Client) GetImageVulnerabilities(imageID string) ([]types.Vulnerability, error) {
+	query := c.Builder().Select("*").
+		From("vulnerabilities").
+		Where(builder.Eq{"image_id": imageID}).
+		OrderBy(`CASE severity WHEN 'CRITICAL' THEN 0 WHEN 'HIGH' THEN 1 WHEN 'MEDIUM' THEN 2
+			WHEN 'LOW' THEN 3 ELSE 4 END, vulnerability_id, package`)
+
+	vulnerabilities := []types.Vulnerability{}
+	if err := c.Select(&vulnerabilities, query); err != nil {
+		return nil, err
+	}
+
+	return vulnerabilities, nil
+}
+
+// SetImageScan stores the outcome of the vulnerability scan of an image, replacing the
+// vulnerabilities of a previous scan
+func (c *Client) SetImageScan(imageID, scanState string, vulnerabilities []types.Vulnerability, scannedAt time.Time) error {
+	tx, err := c.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.End()
+
+	if err := tx.DeleteImageVulnerabilities(imageID); err != nil {
+		return err
+	}
+
+	for _, vulnerability := range vulnerabilities {
+		query := c.Builder().Insert(builder.Eq{
+			"image_id":          imageID,
+			"vulnerability_id":  vulnerability.VulnerabilityID,
+			"package":           vulnerability.Package,
+			"installed_version": vulnerability.InstalledVersion,
+			"fixed_version":     vulnerability.FixedVersion,
+			"severity":          vulnerability.Severity,
+			"title":             vulnerability.Title,
+		}).Into("vulnerabilities")
+
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	query := c.Builder().
+		Update(builder.Eq{
+			"scan_state": scanState,
+			"scanned_at": scannedAt,
+		}).
+		From("images").
+		Where(builder.Eq{"id": imageID})
+
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteImageVulnerabilities deletes the vulnerabilities of an image from the db
+func (c *Client) DeleteImageVulnerabilities(imageID string) error {
+	query := c.Builder().
+		Delete(builder.Eq{"image_id": imageID}).
+		From("vulnerabilities")
+
+	_, err := c.Exec(query)
+	return err
+}
+
+// GetVulnerabilityPolicy returns the vulnerability policy images are deployed with
+func (c *Client) GetVulnerabilityPolicy() (*types.VulnerabilityPolicy, error) {
+	query := c.Builder().
+		Select("max_critical, max_high, max_medium, max_low, allow_unscanned, updated_at").
+		From("vulnerability_policy")
+
+	var policy types.VulnerabilityPolicy
+	if err := c.Get(&policy, query); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// UpdateVulnerabilityPolicy replaces the vulnerability policy
+func (c *Client) UpdateVulnerabilityPolicy(policy *types.VulnerabilityPolicy) error {
+	query := c.Builder().
+		Update(builder.Eq{
+			"max_critical":    policy.MaxCritical,
+			"max_high":        policy.MaxHigh,
+			"max_medium":      policy.MaxMedium,
+			"max_low":         policy.MaxLow,
+			"allow_unscanned": policy.AllowUnscanned,
+			"updated_at":      policy.UpdatedAt,
+		}).
+		From("vulnerability_policy").
+		Where(builder.Eq{"id": true})
+
+	_, err := c.Exec(query)
+	return err
+}
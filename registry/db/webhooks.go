@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+
+	"xorm.io/builder"
+
+	"eywa/registry/types"
+)
+
+// GetGitWebhook returns a git webhook from the db
+func (c *Client) GetGitWebhook(webhookID string) (*types.GitWebhook, error) {
+	query := c.Builder().Select("*").
+		From("git_webhooks").
+		Where(builder.Eq{"id": webhookID})
+
+	var webhook types.GitWebhook
+	if err := c.Get(&webhook, query); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetGitWebhooks returns the git webhooks of a user
+func (c *Client) GetGitWebhooks(userID string) ([]types.GitWebhook, error) {
+	query := c.Builder().Select("*").
+		From("git_webhooks").
+		Where(builder.Eq{"user_id": userID}).
+		OrderBy("created_at desc")
+
+	webhooks := []types.GitWebhook{}
+	if err := c.Select(&webhooks, query); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// CreateGitWebhook inserts a new git webhook into db
+func (c *Client) CreateGitWebhook(webhook *types.GitWebhook) error {
+	query := c.Builder().Insert(builder.Eq{
+		"id":              webhook.ID,
+		"user_id":         webhook.UserID,
+		"name":            webhook.Name,
+		"runtime":         webhook.Runtime,
+		"version":         webhook.Version,
+		"executable_path": webhook.ExecutablePath,
+		"git_url":         webhook.GitURL,
+		"git_ref":         webhook.GitRef,
+		"git_path":        webhook.GitPath,
+		"git_secret_id":   webhook.GitSecretID,
+		"signing_secret":  webhook.SigningSecret,
+		"created_at":      webhook.CreatedAt,
+	}).Into("git_webhooks")
+
+	_, err := c.Exec(query)
+	return err
+}
+
+// DeleteGitWebhook deletes a git webhook from the db
+func (c *Client) DeleteGitWebhook(webhookID, userID string) (bool, error) {
+	query := c.Builder().
+		Delete(builder.Eq{"id": webhookID, "user_id": userID}).
+		From("git_webhooks")
+
+	res, err := c.Exec(query)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := res.RowsAffected()
+	return deleted > 0, err
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	"flag"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
 
 	"eywa/registry/builder"
 	"eywa/registry/clients/docker"
+	"eywa/registry/clients/gateway"
+	"eywa/registry/clients/k8s"
 	"eywa/registry/db"
+	"eywa/registry/scanner"
 	"eywa/registry/server"
 )
 
@@ -19,6 +23,23 @@ type Config struct {
 	RegistryUser     string `envconfig:"registry_user" required:"true"`
 	RegistryPassword string `envconfig:"registry_password" required:"true"`
 	NumWorkers       int    `envconfig:"builder_worker_count" default:"3"`
+	GatewayURL       string `envconfig:"gateway_url" default:"http://gateway-api.faas-system:8080"`
+	// Limits of every build, resources are not limited when the build cgroup is empty
+	BuildTimeout     time.Duration `envconfig:"build_timeout" default:"30m"`
+	BuildCgroup      string        `envconfig:"build_cgroup" default:"/sys/fs/cgroup/eywa-builds"`
+	BuildCPULimit    float64       `envconfig:"build_cpu_limit" default:"2"`
+	BuildMemoryLimit int64         `envconfig:"build_memory_limit" default:"4294967296"`
+	// Limits of dockerfile runtime builds
+	DockerfileTimeout time.Duration `envconfig:"dockerfile_build_timeout" default:"15m"`
+	DockerfileMaxSize int64         `envconfig:"dockerfile_max_image_size" default:"1073741824"`
+	// Per user runtime build caches, disabled when the directory is empty
+	CacheDir     string `envconfig:"build_cache_dir" default:"/cache"`
+	CacheMaxSize int64  `envconfig:"build_cache_max_size" default:"21474836480"`
+	// Vulnerability scanning of built images, disabled when the scanner is empty. Offline scans
+	// use the vulnerability db in the db directory without updating it.
+	Scanner        string `envconfig:"image_scanner" default:"trivy"`
+	ScannerDBDir   string `envconfig:"image_scanner_db_dir" default:"/vulnerability-db"`
+	ScannerOffline bool   `envconfig:"image_scanner_offline" default:"false"`
 }
 
 func main() {
@@ -44,12 +65,39 @@ func main() {
 
 	docker := docker.New(conf.Registry, conf.RegistryUser, conf.RegistryPassword)
 
-	builder, err := builder.New(&builder.Config{
-		Registry:         conf.Registry,
+	k8s, err := k8s.New()
+	if err != nil {
+		log.Fatalf("Failed to setup k8s client: %s", err)
+	}
+
+	scanner, err := scanner.New(&scanner.Config{
+		Scanner:          conf.Scanner,
 		RegistryUser:     conf.RegistryUser,
 		RegistryPassword: conf.RegistryPassword,
-		DB:               db,
-		NumWorkers:       3,
+		DBDir:            conf.ScannerDBDir,
+		Offline:          conf.ScannerOffline,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup image scanner: %s", err)
+	}
+
+	builder, err := builder.New(&builder.Config{
+		Registry:          conf.Registry,
+		RegistryUser:      conf.RegistryUser,
+		RegistryPassword:  conf.RegistryPassword,
+		DB:                db,
+		K8s:               k8s,
+		Gateway:           gateway.New(conf.GatewayURL),
+		NumWorkers:        conf.NumWorkers,
+		BuildTimeout:      conf.BuildTimeout,
+		BuildCgroup:       conf.BuildCgroup,
+		BuildCPULimit:     conf.BuildCPULimit,
+		BuildMemoryLimit:  conf.BuildMemoryLimit,
+		DockerfileTimeout: conf.DockerfileTimeout,
+		DockerfileMaxSize: conf.DockerfileMaxSize,
+		CacheDir:          conf.CacheDir,
+		CacheMaxSize:      conf.CacheMaxSize,
+		Scanner:           scanner,
 	})
 	if err != nil {
 		log.Fatalf("Failed to setup builder: %s", err)
@@ -0,0 +1,6 @@
+ALTER TABLE builds
+    ADD COLUMN executable_path text,
+    ADD COLUMN attempts int NOT NULL DEFAULT 0,
+    ADD COLUMN started_at timestamp without time zone,
+    ADD COLUMN heartbeat_at timestamp without time zone,
+    ADD COLUMN finished_at timestamp without time zone;
@@ -0,0 +1,2 @@
+ALTER TABLE builds ALTER COLUMN logs TYPE text[] USING COALESCE(logs, '{}')::text[];
+ALTER TABLE builds ALTER COLUMN logs SET DEFAULT '{}';
@@ -0,0 +1,24 @@
+ALTER TABLE images
+    ADD COLUMN source_type text NOT NULL DEFAULT 'zip',
+    ADD COLUMN git_url text,
+    ADD COLUMN git_ref text,
+    ADD COLUMN git_path text,
+    ADD COLUMN git_secret_id text,
+    ADD COLUMN commit_sha text;
+
+CREATE TABLE git_webhooks (
+    id uuid primary key,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    runtime text NOT NULL,
+    version text NOT NULL,
+    executable_path text,
+    git_url text NOT NULL,
+    git_ref text NOT NULL,
+    git_path text,
+    git_secret_id text,
+    signing_secret text NOT NULL,
+    created_at timestamp without time zone
+);
+
+CREATE INDEX git_webhooks_user_id_idx ON git_webhooks USING btree (user_id);
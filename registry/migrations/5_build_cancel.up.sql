@@ -0,0 +1 @@
+ALTER TABLE builds ADD COLUMN cancel_requested boolean NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+ALTER TABLE builds
+    ADD COLUMN run_tests boolean NOT NULL DEFAULT true,
+    ADD COLUMN tests jsonb;
@@ -0,0 +1,28 @@
+ALTER TABLE images
+    ADD COLUMN scan_state text,
+    ADD COLUMN scanned_at timestamp without time zone;
+
+CREATE TABLE vulnerabilities (
+    image_id uuid NOT NULL,
+    vulnerability_id text NOT NULL,
+    package text NOT NULL,
+    installed_version text NOT NULL,
+    fixed_version text NOT NULL,
+    severity text NOT NULL,
+    title text NOT NULL
+);
+
+CREATE INDEX vulnerabilities_image_id_idx ON vulnerabilities USING btree (image_id);
+
+-- The policy has a single row
+CREATE TABLE vulnerability_policy (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    max_critical int,
+    max_high int,
+    max_medium int,
+    max_low int,
+    allow_unscanned boolean NOT NULL DEFAULT true,
+    updated_at timestamp without time zone
+);
+
+INSERT INTO vulnerability_policy DEFAULT VALUES;
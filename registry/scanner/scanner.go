@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"eywa/registry/types"
+)
+
+// Scanners images can be scanned with
+const (
+	ScannerTrivy = "trivy"
+)
+
+// Scanner scans images for known vulnerabilities
+type Scanner interface {
+	// Scan returns the vulnerabilities found in an image pushed to the registry
+	Scan(ctx context.Context, image string) ([]types.Vulnerability, error)
+}
+
+// Config represents scanner config
+type Config struct {
+	// Scanner is the name of the scanner, images are not scanned when it is empty
+	Scanner          string
+	RegistryUser     string
+	RegistryPassword string
+	// DBDir holds the vulnerability db of the scanner, Offline scans use it without updating it
+	DBDir   string
+	Offline bool
+}
+
+// New returns the scanner of the config, nil when scanning is disabled
+func New(conf *Config) (Scanner, error) {
+	switch conf.Scanner {
+	case "":
+		return nil, nil
+	case ScannerTrivy:
+		return &trivy{
+			registryUser:     conf.RegistryUser,
+			registryPassword: conf.RegistryPassword,
+			dbDir:            conf.DBDir,
+			offline:          conf.Offline,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported image scanner %s", conf.Scanner)
+	}
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"eywa/registry/types"
+)
+
+// trivy scans images with the trivy cli, pulling them straight from the registry
+type trivy struct {
+	registryUser     string
+	registryPassword string
+	dbDir            string
+	offline          bool
+}
+
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			FixedVersion     string
+			Severity         string
+			Title            string
+		}
+	}
+}
+
+// Scan implements Scanner
+func (t *trivy) Scan(ctx context.Context, image string) ([]types.Vulnerability, error) {
+	args := []string{"image", "--format", "json", "--quiet", "--scanners", "vuln", "--image-src", "remote"}
+	if t.dbDir != "" {
+		args = append(args, "--cache-dir", t.dbDir)
+	}
+	if t.offline {
+		args = append(args, "--skip-db-update", "--skip-java-db-update", "--offline-scan")
+	}
+	args = append(args, image)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "trivy", args...)
+	cmd.Env = append(os.Environ(), "TRIVY_USERNAME="+t.registryUser, "TRIVY_PASSWORD="+t.registryPassword)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("trivy failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("invalid trivy report: %s", err)
+	}
+
+	vulnerabilities := []types.Vulnerability{}
+	for _, result := range report.Results {
+		for _, vulnerability := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, types.Vulnerability{
+				VulnerabilityID:  vulnerability.VulnerabilityID,
+				Package:          vulnerability.PkgName,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersion:     vulnerability.FixedVersion,
+				Severity:         vulnerability.Severity,
+				Title:            vulnerability.Title,
+			})
+		}
+	}
+
+	return vulnerabilities, nil
+}
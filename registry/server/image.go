@@ -45,11 +45,41 @@ func imagesAPI() []*swagger.Endpoint {
 		endpoint.Consumes("multipart/form-data"),
 		endpoint.Handler(controllers.RequestImageBuild),
 		endpoint.FormDataMap(map[string]swagger.Parameter{
+			"source_type": {
+				Type:        "string",
+				Format:      "string",
+				Enum:        []string{"zip", "git"},
+				Description: "Where the source is taken from, defaults to zip",
+			},
 			"source": {
 				Type:        "string",
 				Format:      "file",
-				Description: "Zip file containing source",
-				Required:    true,
+				Description: "Zip file containing source (only applicable to zip sources)",
+			},
+			"git_url": {
+				Type:        "string",
+				Format:      "string",
+				Pattern:     "^https?://",
+				Description: "URL of the git repository (only applicable to git sources)",
+			},
+			"git_ref": {
+				Type:        "string",
+				Format:      "string",
+				Description: "Branch, tag or commit to build, defaults to HEAD (only applicable to git sources)",
+			},
+			"git_path": {
+				Type:        "string",
+				Format:      "string",
+				Description: "Directory of the source within the repository (only applicable to git sources)",
+			},
+			"run_tests": {
+				Type:        "boolean",
+				Description: "Run the tests of the function before building the image, defaults to true",
+			},
+			"git_secret_id": {
+				Type:        "string",
+				Format:      "uuid",
+				Description: "Secret holding the username and password or token of the repository (only applicable to git sources)",
 			},
 			"version": {
 				Type:        "string",
@@ -61,14 +91,13 @@ func imagesAPI() []*swagger.Endpoint {
 			"runtime": {
 				Type:        "string",
 				Format:      "string",
-				Enum:        []string{"go", "node14", "python3", "ruby", "csharp", "custom"},
-				Description: "Runtime to be used with your deployment",
+				Description: "Runtime to be used with your deployment, one of GET /runtimes",
 				Required:    true,
 			},
 			"executable_path": {
 				Type:        "string",
 				Format:      "string",
-				Description: "Path to the executable (only applicable to custom runtimes)",
+				Description: "Path to the executable (only applicable to runtimes with an executable_path)",
 			},
 			"name": {
 				Type:        "string",
@@ -82,11 +111,31 @@ func imagesAPI() []*swagger.Endpoint {
 		endpoint.Tags("Images"),
 	)
 
-	getImageBuildLogs := endpoint.New("GET", "/images/{image_id}/buildlogs", "Get image build logs",
-		endpoint.Description("Get image build logs"),
+	getImageBuildLogs := endpoint.New("GET", "/images/{image_id}/buildlogs", "Stream image build logs",
+		endpoint.Description("Stream image build logs as server-sent events. Each log line is sent as a `log` event "+
+			"with its index as id, a `state` event with the final state of the build is sent once it finished. "+
+			"Send `Last-Event-ID` to resume after a line."),
+		endpoint.Produces("text/event-stream"),
 		endpoint.Handler(controllers.GetImageBuildLogs),
 		endpoint.Path("image_id", "string", "uuid", "UUID of an image"),
-		endpoint.Response(http.StatusOK, types.ImageLogs{}, "Success"),
+		endpoint.Response(http.StatusOK, "", "Success"),
+		endpoint.Tags("Images"),
+	)
+
+	getImageTests := endpoint.New("GET", "/images/{image_id}/tests", "Get image test results",
+		endpoint.Description("Get the result of the function tests run by the image build"),
+		endpoint.Handler(controllers.GetImageTests),
+		endpoint.Path("image_id", "string", "uuid", "UUID of an image"),
+		endpoint.Response(http.StatusOK, types.TestResult{}, "Success"),
+		endpoint.Tags("Images"),
+	)
+
+	cancelImageBuild := endpoint.New("POST", "/images/{image_id}/cancel", "Cancel an image build",
+		endpoint.Description("Cancel a queued or running image build. A running build is stopped within seconds "+
+			"and ends in the `cancelled` state."),
+		endpoint.Handler(controllers.CancelImageBuild),
+		endpoint.Path("image_id", "string", "uuid", "UUID of an image"),
+		endpoint.Response(http.StatusAccepted, "", "Success"),
 		endpoint.Tags("Images"),
 	)
 
@@ -98,11 +147,21 @@ func imagesAPI() []*swagger.Endpoint {
 		endpoint.Tags("Images"),
 	)
 
+	getRuntimes := endpoint.New("GET", "/runtimes", "Get the runtimes",
+		endpoint.Description("Get the runtimes images can be built for"),
+		endpoint.Handler(controllers.GetRuntimes),
+		endpoint.Response(http.StatusOK, types.GetRuntimesResponse{}, "Success"),
+		endpoint.Tags("Images"),
+	)
+
 	return []*swagger.Endpoint{
+		getRuntimes,
 		getImages,
 		getImage,
 		queueImageBuild,
 		getImageBuildLogs,
+		getImageTests,
+		cancelImageBuild,
 		deleteImage,
 	}
 }
@@ -16,6 +16,7 @@ import (
 	"eywa/go-libs/pagination"
 	"eywa/registry/builder"
 	"eywa/registry/clients/docker"
+	"eywa/registry/controllers"
 	"eywa/registry/db"
 )
 
@@ -70,6 +71,8 @@ func createRouter(params *ContextParams) *echo.Echo {
 	enableCors := true
 	registryAPI := createRegistryAPI()
 	e.GET("/eywa/api/registry/doc", echo.WrapHandler(registryAPI.Handler(enableCors)))
+	// Git push webhooks are authenticated by their signature
+	e.POST("/eywa/api/registry/webhooks/:webhook_id", controllers.ReceiveGitWebhook)
 
 	api := e.Group("", checkAuth(), sv.SwaggerValidatorEcho(registryAPI), pagination.Validate())
 	registryAPI.Walk(func(path string, endpoint *swagger.Endpoint) {
@@ -89,6 +92,8 @@ func createRegistryAPI() *swagger.API {
 		swag.BasePath("/eywa/api"),
 		swag.Endpoints(aggregateEndpoints(
 			imagesAPI(),
+			webhooksAPI(),
+			vulnerabilitiesAPI(),
 		)...,
 		),
 	)
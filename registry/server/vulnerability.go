@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+
+	"eywa/registry/controllers"
+	"eywa/registry/types"
+)
+
+func vulnerabilitiesAPI() []*swagger.Endpoint {
+	getImageVulnerabilities := endpoint.New("GET", "/images/{image_id}/vulnerabilities", "Get image vulnerabilities",
+		endpoint.Description("Get the vulnerabilities found by the scan of the image, the most severe first, and "+
+			"whether the vulnerability policy allows to deploy the image"),
+		endpoint.Handler(controllers.GetImageVulnerabilities),
+		endpoint.Path("image_id", "string", "uuid", "UUID of an image"),
+		endpoint.Response(http.StatusOK, types.GetImageVulnerabilitiesResponse{}, "Success"),
+		endpoint.Tags("Vulnerabilities"),
+	)
+
+	getPolicy := endpoint.New("GET", "/images/vulnerability-policy", "Get the vulnerability policy",
+		endpoint.Description("Get the maximum vulnerabilities by severity images can have to be deployed"),
+		endpoint.Handler(controllers.GetVulnerabilityPolicy),
+		endpoint.Response(http.StatusOK, types.VulnerabilityPolicy{}, "Success"),
+		endpoint.Tags("Vulnerabilities"),
+	)
+
+	updatePolicy := endpoint.New("PUT", "/images/vulnerability-policy", "Update the vulnerability policy",
+		endpoint.Description("Replace the vulnerability policy, operator only. Severities without a maximum are "+
+			"not limited, e.g. `{\"max_critical\": 0}` blocks the deploy of images with critical vulnerabilities."),
+		endpoint.Handler(controllers.UpdateVulnerabilityPolicy),
+		endpoint.Body(types.VulnerabilityPolicy{}, "Vulnerability policy payload", true),
+		endpoint.Response(http.StatusOK, types.VulnerabilityPolicy{}, "Success"),
+		endpoint.Tags("Vulnerabilities"),
+	)
+
+	return []*swagger.Endpoint{
+		getImageVulnerabilities,
+		getPolicy,
+		updatePolicy,
+	}
+}
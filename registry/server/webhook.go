@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/miketonks/swag/endpoint"
+	"github.com/miketonks/swag/swagger"
+
+	"eywa/registry/controllers"
+	"eywa/registry/types"
+)
+
+func webhooksAPI() []*swagger.Endpoint {
+	getWebhooks := endpoint.New("GET", "/images/webhooks", "Get git webhooks",
+		endpoint.Description("Get all the git webhooks of a user"),
+		endpoint.Handler(controllers.GetGitWebhooks),
+		endpoint.Response(http.StatusOK, types.GetGitWebhooksResponse{}, "Success"),
+		endpoint.Tags("Webhooks"),
+	)
+
+	createWebhook := endpoint.New("POST", "/images/webhooks", "Create a git webhook",
+		endpoint.Description("Create a webhook building the pushed commit as the next patch version of an image "+
+			"on every push to a git ref. Register /eywa/api/registry/webhooks/{webhook_id} as the push webhook "+
+			"of the repository with the returned signing secret, it is not returned again."),
+		endpoint.Handler(controllers.CreateGitWebhook),
+		endpoint.Body(types.CreateGitWebhookRequest{}, "Git webhook creation payload", true),
+		endpoint.Response(http.StatusOK, types.GitWebhook{}, "Success"),
+		endpoint.Tags("Webhooks"),
+	)
+
+	deleteWebhook := endpoint.New("DELETE", "/images/webhooks/{webhook_id}", "Delete a git webhook",
+		endpoint.Description("Delete a git webhook"),
+		endpoint.Handler(controllers.DeleteGitWebhook),
+		endpoint.Path("webhook_id", "string", "uuid", "UUID of a git webhook"),
+		endpoint.Response(http.StatusNoContent, "", "Success"),
+		endpoint.Tags("Webhooks"),
+	)
+
+	return []*swagger.Endpoint{
+		getWebhooks,
+		createWebhook,
+		deleteWebhook,
+	}
+}
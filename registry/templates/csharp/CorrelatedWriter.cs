@@ -0,0 +1,103 @@
+using System;
+using System.Collections.Generic;
+using System.IO;
+using System.Text;
+using System.Threading;
+using System.Threading.Tasks;
+
+// Prefixes the lines a request writes to the console with "[<id>] ", the id the watchdog sends
+// in the X-Eywa-Correlation-Id header. The watchdog attributes prefixed lines to their request
+// when several requests are in flight.
+public class CorrelatedWriter : TextWriter
+{
+    public const string Header = "X-Eywa-Correlation-Id";
+
+    private static readonly AsyncLocal<string> current = new AsyncLocal<string>();
+    private static CorrelatedWriter output;
+    private static CorrelatedWriter error;
+
+    private readonly object mutex = new object();
+    private readonly TextWriter writer;
+    private readonly Dictionary<string, StringBuilder> pending = new Dictionary<string, StringBuilder>();
+
+    private CorrelatedWriter(TextWriter writer)
+    {
+        this.writer = writer;
+    }
+
+    public override Encoding Encoding => writer.Encoding;
+
+    public static void Install()
+    {
+        output = new CorrelatedWriter(Console.Out);
+        error = new CorrelatedWriter(Console.Error);
+        Console.SetOut(output);
+        Console.SetError(error);
+    }
+
+    // Run prefixes everything fn writes with the correlation id, the last line of the
+    // request is written when it did not end with a newline
+    public static async Task Run(string id, Func<Task> fn)
+    {
+        if (string.IsNullOrEmpty(id))
+        {
+            await fn();
+            return;
+        }
+
+        current.Value = id;
+        try
+        {
+            await fn();
+        }
+        finally
+        {
+            output.Finish(id);
+            error.Finish(id);
+        }
+    }
+
+    // A line is written once it is complete so the lines of concurrent requests are never interleaved
+    public override void Write(char value)
+    {
+        var id = current.Value;
+        if (id == null)
+        {
+            writer.Write(value);
+            return;
+        }
+
+        lock (mutex)
+        {
+            if (!pending.TryGetValue(id, out var line))
+            {
+                line = new StringBuilder();
+                pending[id] = line;
+            }
+
+            if (value != '\n')
+            {
+                line.Append(value);
+                return;
+            }
+
+            pending.Remove(id);
+            writer.Write($"[{id}] {line.ToString().TrimEnd('\r')}\n");
+            writer.Flush();
+        }
+    }
+
+    public override void Flush() => writer.Flush();
+
+    private void Finish(string id)
+    {
+        lock (mutex)
+        {
+            if (pending.Remove(id, out var line) && line.Length > 0)
+            {
+                writer.Write($"[{id}] {line}\n");
+                writer.Flush();
+            }
+        }
+    }
+}
@@ -3,7 +3,11 @@ using Microsoft.Extensions.Hosting;
 
 public class Program
 {
-    public static void Main(string[] args) => CreateHostBuilder(args).Build().Run();
+    public static void Main(string[] args)
+    {
+        CorrelatedWriter.Install();
+        CreateHostBuilder(args).Build().Run();
+    }
 
     public static IHostBuilder CreateHostBuilder(string[] args) =>
         Host.CreateDefaultBuilder(args)
@@ -3,6 +3,7 @@ using Microsoft.AspNetCore.Builder;
 using Microsoft.AspNetCore.Hosting;
 using Function;
 using System;
+using System.Threading.Tasks;
 using Microsoft.AspNetCore.Http;
 
 public class Startup
@@ -15,6 +16,12 @@ public class Startup
     {
         app.Run(async (context) =>
         {
+            if (context.Request.Method == "POST" && (context.Request.Path == "/_/init" || context.Request.Path == "/_/shutdown"))
+            {
+                await RunHook(context, context.Request.Path == "/_/init" ? "Init" : "Shutdown");
+                return;
+            }
+
             if (context.Request.Path != "/")
             {
                 context.Response.StatusCode = 404;
@@ -29,23 +36,53 @@ public class Startup
                 return;
             }
 
-            try
+            // The output of the handler is prefixed with the correlation id of the request
+            await CorrelatedWriter.Run(context.Request.Headers[CorrelatedWriter.Header], async () =>
             {
-                var (status, text) = await new FunctionHandler().Handle(context.Request);
-                context.Response.StatusCode = status;
-                if (!string.IsNullOrEmpty(text))
-                    await context.Response.WriteAsync(text);
-            }
-            catch (NotImplementedException nie)
-            {
-                context.Response.StatusCode = 501;
-                await context.Response.WriteAsync(nie.ToString());
-            }
-            catch (Exception ex)
-            {
-                context.Response.StatusCode = 500;
-                await context.Response.WriteAsync(ex.ToString());
-            }
+                try
+                {
+                    var (status, text) = await new FunctionHandler().Handle(context.Request);
+                    context.Response.StatusCode = status;
+                    if (!string.IsNullOrEmpty(text))
+                        await context.Response.WriteAsync(text);
+                }
+                catch (NotImplementedException nie)
+                {
+                    context.Response.StatusCode = 501;
+                    await context.Response.WriteAsync(nie.ToString());
+                }
+                catch (Exception ex)
+                {
+                    context.Response.StatusCode = 500;
+                    await context.Response.WriteAsync(ex.ToString());
+                }
+            });
         });
     }
-}
\ No newline at end of file
+
+    // Lifecycle hooks called by the watchdog, a function implements them by defining
+    // optional public Init and Shutdown methods returning a Task on its FunctionHandler
+    private static async Task RunHook(HttpContext context, string name)
+    {
+        var hook = typeof(FunctionHandler).GetMethod(name, Type.EmptyTypes);
+        if (hook == null)
+        {
+            context.Response.StatusCode = 204;
+            return;
+        }
+
+        try
+        {
+            var target = hook.IsStatic ? null : new FunctionHandler();
+            if (hook.Invoke(target, null) is Task task)
+                await task;
+
+            context.Response.StatusCode = 204;
+        }
+        catch (Exception ex)
+        {
+            context.Response.StatusCode = 500;
+            await context.Response.WriteAsync((ex.InnerException ?? ex).ToString());
+        }
+    }
+}
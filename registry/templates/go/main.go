@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"strconv"
 	"sync/atomic"
 	"syscall"
@@ -21,6 +25,13 @@ var (
 
 const defaultTimeout = 10 * time.Second
 
+// correlationHeader carries the id of the request, lines printed with the "[<id>] " prefix are
+// attributed to the request by the watchdog. Go can not redirect what a goroutine prints, a
+// function prefixes the lines it logs itself, e.g. with
+//
+//	log.New(os.Stderr, "["+r.Header.Get("X-Eywa-Correlation-Id")+"] ", log.LstdFlags)
+const correlationHeader = "X-Eywa-Correlation-Id"
+
 func main() {
 	readTimeout := parseIntOrDurationValue(os.Getenv("read_timeout"), defaultTimeout)
 	writeTimeout := parseIntOrDurationValue(os.Getenv("write_timeout"), defaultTimeout)
@@ -32,11 +43,78 @@ func main() {
 		MaxHeaderBytes: 1 << 20, // Max header of 1MB
 	}
 
-	http.HandleFunc("/", function.Handle)
+	http.HandleFunc("/", handle)
+	registerLifecycleHook("/_/init")
+	registerLifecycleHook("/_/shutdown")
 
 	listenUntilShutdown(s, writeTimeout)
 }
 
+// handle serves the function, a panic is logged with the correlation id of the request
+func handle(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if err := recover(); err != nil {
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			logger := log.New(&prefixWriter{w: os.Stderr, prefix: correlationPrefix(r)}, "", log.LstdFlags)
+			logger.Printf("[entrypoint] panic serving %s: %v\n%s", r.URL.Path, err, debug.Stack())
+			panic(http.ErrAbortHandler)
+		}
+	}()
+
+	function.Handle(w, r)
+}
+
+// correlationPrefix returns the prefix of the lines printed for the request
+func correlationPrefix(r *http.Request) string {
+	if id := r.Header.Get(correlationHeader); id != "" {
+		return "[" + id + "] "
+	}
+
+	return ""
+}
+
+// prefixWriter prefixes every line written to it, a log message is written in one call
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if p.prefix == "" {
+		return p.w.Write(b)
+	}
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	var out []byte
+	for _, line := range lines {
+		if len(line) > 0 {
+			out = append(append(out, p.prefix...), line...)
+		}
+	}
+
+	if _, err := p.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// registerLifecycleHook registers a no-op handler for a hook the watchdog calls, /_/init or
+// /_/shutdown, unless the function registered its own on http.DefaultServeMux, e.g. in an init
+// func of the function package.
+func registerLifecycleHook(path string) {
+	r := &http.Request{Method: http.MethodPost, URL: &url.URL{Path: path}}
+	if _, pattern := http.DefaultServeMux.Handler(r); pattern == path {
+		return
+	}
+
+	http.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
 func listenUntilShutdown(s *http.Server, shutdownTimeout time.Duration) {
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -0,0 +1,183 @@
+import com.sun.net.httpserver.HttpExchange;
+import com.sun.net.httpserver.HttpHandler;
+import com.sun.net.httpserver.HttpServer;
+
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.OutputStream;
+import java.io.PrintStream;
+import java.io.PrintWriter;
+import java.io.StringWriter;
+import java.lang.reflect.InvocationTargetException;
+import java.lang.reflect.Method;
+import java.net.InetSocketAddress;
+import java.nio.charset.StandardCharsets;
+import java.util.concurrent.Executors;
+
+/**
+ * Serves the function.Handler of the function, an implementation of the JDK HttpHandler.
+ * The function implements the lifecycle hooks called by the watchdog by declaring optional
+ * public no-argument init and shutdown methods on its Handler.
+ */
+public class Entrypoint {
+    public static void main(String[] args) throws Exception {
+        CorrelatedStream.install();
+
+        HttpHandler handler = (HttpHandler) Class.forName("function.Handler")
+                .getDeclaredConstructor()
+                .newInstance();
+
+        int port = Integer.parseInt(System.getenv().getOrDefault("http_port", "8082"));
+        HttpServer server = HttpServer.create(new InetSocketAddress(port), 0);
+        server.setExecutor(Executors.newCachedThreadPool());
+
+        server.createContext("/_/init", exchange -> lifecycleHook(exchange, handler, "init"));
+        server.createContext("/_/shutdown", exchange -> lifecycleHook(exchange, handler, "shutdown"));
+        server.createContext("/", exchange -> {
+            CorrelatedStream.begin(exchange.getRequestHeaders().getFirst(CorrelatedStream.HEADER));
+            try {
+                handler.handle(exchange);
+            } catch (Exception e) {
+                e.printStackTrace();
+                respond(exchange, 500, e.toString());
+            } finally {
+                CorrelatedStream.end();
+                exchange.close();
+            }
+        });
+
+        server.start();
+        System.out.println("java17 listening on port: " + port);
+    }
+
+    private static void lifecycleHook(HttpExchange exchange, Object handler, String name) throws IOException {
+        Method hook;
+        try {
+            hook = handler.getClass().getMethod(name);
+        } catch (NoSuchMethodException e) {
+            respond(exchange, 204, "");
+            return;
+        }
+
+        try {
+            hook.invoke(handler);
+            respond(exchange, 204, "");
+        } catch (IllegalAccessException | InvocationTargetException e) {
+            Throwable cause = e.getCause() != null ? e.getCause() : e;
+            StringWriter trace = new StringWriter();
+            cause.printStackTrace(new PrintWriter(trace));
+            System.err.print(trace);
+            respond(exchange, 500, cause.toString());
+        }
+    }
+
+    private static void respond(HttpExchange exchange, int status, String body) throws IOException {
+        byte[] bytes = body.getBytes(StandardCharsets.UTF_8);
+        exchange.sendResponseHeaders(status, bytes.length == 0 ? -1 : bytes.length);
+        if (bytes.length > 0) {
+            try (OutputStream out = exchange.getResponseBody()) {
+                out.write(bytes);
+            }
+        }
+        exchange.close();
+    }
+
+    /**
+     * Prefixes the lines a request writes to stdout or stderr with "[<id>] ", the id the watchdog
+     * sends in the X-Eywa-Correlation-Id header. The watchdog attributes prefixed lines to their
+     * request when several requests are in flight. A request is handled on a single thread of the
+     * server so its id is kept per thread.
+     */
+    static final class CorrelatedStream extends OutputStream {
+        static final String HEADER = "X-Eywa-Correlation-Id";
+
+        private static final ThreadLocal<String> ID = new ThreadLocal<>();
+        private static CorrelatedStream out;
+        private static CorrelatedStream err;
+
+        private final PrintStream stream;
+        private final ThreadLocal<ByteArrayOutputStream> pending = ThreadLocal.withInitial(ByteArrayOutputStream::new);
+
+        private CorrelatedStream(PrintStream stream) {
+            this.stream = stream;
+        }
+
+        static void install() {
+            out = new CorrelatedStream(System.out);
+            err = new CorrelatedStream(System.err);
+            System.setOut(new PrintStream(out, true, StandardCharsets.UTF_8));
+            System.setErr(new PrintStream(err, true, StandardCharsets.UTF_8));
+        }
+
+        static void begin(String id) {
+            if (id != null && !id.isEmpty()) {
+                ID.set(id);
+            }
+        }
+
+        // end writes the last line of the request when it did not end with a newline
+        static void end() {
+            String id = ID.get();
+            if (id == null) {
+                return;
+            }
+
+            System.out.flush();
+            System.err.flush();
+            out.finish(id);
+            err.finish(id);
+            ID.remove();
+        }
+
+        @Override
+        public void write(int b) {
+            String id = ID.get();
+            if (id == null) {
+                stream.write(b);
+                return;
+            }
+
+            ByteArrayOutputStream line = pending.get();
+            line.write(b);
+            if (b == '\n') {
+                writeLine(id, line);
+            }
+        }
+
+        @Override
+        public void write(byte[] b, int off, int len) {
+            if (ID.get() == null) {
+                stream.write(b, off, len);
+                return;
+            }
+
+            for (int i = off; i < off + len; i++) {
+                write(b[i]);
+            }
+        }
+
+        @Override
+        public void flush() {
+            stream.flush();
+        }
+
+        private void finish(String id) {
+            ByteArrayOutputStream line = pending.get();
+            if (line.size() > 0) {
+                line.write('\n');
+                writeLine(id, line);
+            }
+        }
+
+        // A line is written once it is complete so the lines of concurrent requests are never interleaved
+        private void writeLine(String id, ByteArrayOutputStream line) {
+            byte[] prefix = ("[" + id + "] ").getBytes(StandardCharsets.UTF_8);
+            synchronized (stream) {
+                stream.write(prefix, 0, prefix.length);
+                stream.write(line.toByteArray(), 0, line.size());
+                stream.flush();
+            }
+            line.reset();
+        }
+    }
+}
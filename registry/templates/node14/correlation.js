@@ -0,0 +1,82 @@
+"use strict"
+
+// Lines written to stdout or stderr while a request is handled are prefixed with "[<id>] ",
+// the id the watchdog sends in the X-Eywa-Correlation-Id header. The watchdog attributes
+// prefixed lines to their request when several requests are in flight.
+
+const { AsyncLocalStorage } = require('async_hooks');
+
+const header = 'x-eywa-correlation-id';
+const storage = new AsyncLocalStorage();
+const streams = [];
+
+// prefixStream replaces the write of the stream, a line is written once it is complete
+// so the lines of concurrent requests are never interleaved
+const prefixStream = (stream) => {
+    const write = stream.write.bind(stream);
+    const pending = new Map();
+
+    stream.write = (chunk, encoding, cb) => {
+        const id = storage.getStore();
+        if (!id) {
+            return write(chunk, encoding, cb);
+        }
+
+        if (typeof encoding === 'function') {
+            cb = encoding;
+            encoding = undefined;
+        }
+
+        const text = typeof chunk === 'string'
+            ? Buffer.from(chunk, encoding).toString()
+            : Buffer.from(chunk).toString();
+        const lines = ((pending.get(id) || '') + text).split('\n');
+        const rest = lines.pop();
+
+        if (rest) {
+            pending.set(id, rest);
+        } else {
+            pending.delete(id);
+        }
+
+        if (lines.length === 0) {
+            if (cb) {
+                process.nextTick(cb);
+            }
+            return true;
+        }
+
+        return write(lines.map((line) => `[${id}] ${line}\n`).join(''), cb);
+    };
+
+    streams.push((id) => {
+        const rest = pending.get(id);
+        if (rest) {
+            pending.delete(id);
+            write(`[${id}] ${rest}\n`);
+        }
+    });
+};
+
+prefixStream(process.stdout);
+prefixStream(process.stderr);
+
+// run calls fn with the correlation id of the request, everything it writes is prefixed
+const run = (req, fn) => {
+    const id = req.headers[header];
+    if (!id) {
+        return fn();
+    }
+
+    return storage.run(id, fn);
+};
+
+// flush writes the last line of the request when it did not end with a newline
+const flush = (req) => {
+    const id = req.headers[header];
+    if (id) {
+        streams.forEach((flushStream) => flushStream(id));
+    }
+};
+
+module.exports = { run, flush };
@@ -6,6 +6,7 @@
 
 const express = require('express')
 const app = express()
+const correlation = require('./correlation');
 const handler = require('./function/handler');
 const bodyParser = require('body-parser')
 
@@ -113,17 +114,41 @@ const middleware = async (req, res) => {
     const fnEvent = new FunctionEvent(req);
     const fnContext = new FunctionContext(cb);
 
-    Promise.resolve(handler(fnEvent, fnContext, cb))
-    .then(res => {
-        if(!fnContext.cbCalled) {
-            fnContext.succeed(res);
-        }
-    })
-    .catch(e => {
-        cb(e);
+    res.on('finish', () => correlation.flush(req));
+
+    // The output of the handler is prefixed with the correlation id of the request
+    correlation.run(req, () => {
+        Promise.resolve(handler(fnEvent, fnContext, cb))
+        .then(res => {
+            if(!fnContext.cbCalled) {
+                fnContext.succeed(res);
+            }
+        })
+        .catch(e => {
+            cb(e);
+        });
     });
 };
 
+// Lifecycle hooks called by the watchdog, a function implements them by exporting
+// optional init and shutdown functions next to its handler
+const lifecycleHook = (hook) => async (req, res) => {
+    if (typeof hook !== 'function') {
+        return res.status(204).send();
+    }
+
+    try {
+        await hook();
+        res.status(204).send();
+    } catch (e) {
+        console.error(e);
+        res.status(500).send(e.toString ? e.toString() : e);
+    }
+};
+
+app.post('/_/init', lifecycleHook(handler.init));
+app.post('/_/shutdown', lifecycleHook(handler.shutdown));
+
 app.post('/*', middleware);
 app.get('/*', middleware);
 app.patch('/*', middleware);
@@ -0,0 +1,68 @@
+<?php
+
+// Lines written to STDOUT or STDERR while a request is handled are prefixed with "[<id>] ",
+// the id the watchdog sends in the X-Eywa-Correlation-Id header. The watchdog attributes
+// prefixed lines to their request when several requests are in flight. The built-in server
+// does not define STDOUT and STDERR, they are defined here as prefixed streams.
+
+final class CorrelatedStream
+{
+    /** @var resource|null */
+    public $context;
+
+    /** @var resource */
+    private $stream;
+
+    private string $id = '';
+
+    private string $pending = '';
+
+    public function stream_open(string $path, string $mode, int $options, ?string &$openedPath): bool
+    {
+        $this->stream = fopen('php://' . parse_url($path, PHP_URL_HOST), $mode);
+        $this->id = $_SERVER['HTTP_X_EYWA_CORRELATION_ID'] ?? '';
+
+        return $this->stream !== false;
+    }
+
+    // A line is written once it is complete so the lines of concurrent requests are never interleaved
+    public function stream_write(string $data): int
+    {
+        if ($this->id === '') {
+            return fwrite($this->stream, $data);
+        }
+
+        $lines = explode("\n", $this->pending . $data);
+        $this->pending = array_pop($lines);
+        if ($lines) {
+            fwrite($this->stream, implode('', array_map(fn ($line) => "[{$this->id}] $line\n", $lines)));
+        }
+
+        return strlen($data);
+    }
+
+    public function stream_flush(): bool
+    {
+        return fflush($this->stream);
+    }
+
+    // The last line of the request is written when it did not end with a newline
+    public function stream_close(): void
+    {
+        if ($this->pending !== '') {
+            fwrite($this->stream, "[{$this->id}] {$this->pending}\n");
+        }
+
+        fclose($this->stream);
+    }
+}
+
+stream_wrapper_register('correlated', CorrelatedStream::class);
+
+if (!defined('STDOUT')) {
+    define('STDOUT', fopen('correlated://stdout', 'w'));
+}
+
+if (!defined('STDERR')) {
+    define('STDERR', fopen('correlated://stderr', 'w'));
+}
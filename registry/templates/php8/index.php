@@ -0,0 +1,70 @@
+<?php
+
+require_once __DIR__ . '/correlation.php';
+
+if (file_exists(__DIR__ . '/function/vendor/autoload.php')) {
+    require __DIR__ . '/function/vendor/autoload.php';
+}
+
+require_once __DIR__ . '/function/Handler.php';
+
+$handler = new Handler();
+$path = parse_url($_SERVER['REQUEST_URI'], PHP_URL_PATH);
+
+function fail(Throwable $e)
+{
+    fwrite(STDERR, (string) $e . PHP_EOL);
+    http_response_code(500);
+    echo $e->getMessage();
+}
+
+// Lifecycle hooks called by the watchdog, a function implements them by defining
+// optional init and shutdown methods on its Handler
+if ($_SERVER['REQUEST_METHOD'] === 'POST' && ($path === '/_/init' || $path === '/_/shutdown')) {
+    $hook = substr($path, 3);
+    if (method_exists($handler, $hook)) {
+        try {
+            $handler->$hook();
+        } catch (Throwable $e) {
+            fail($e);
+            return;
+        }
+    }
+
+    http_response_code(204);
+    return;
+}
+
+$event = [
+    'body' => file_get_contents('php://input'),
+    'headers' => getallheaders(),
+    'method' => $_SERVER['REQUEST_METHOD'],
+    'query' => $_GET,
+    'path' => $path,
+];
+
+try {
+    $response = $handler->handle($event);
+} catch (Throwable $e) {
+    fail($e);
+    return;
+}
+
+// handle returns the body or an array with an optional statusCode, headers and body
+if (!is_array($response)) {
+    echo $response;
+    return;
+}
+
+http_response_code($response['statusCode'] ?? 200);
+foreach ($response['headers'] ?? [] as $name => $value) {
+    header("$name: $value");
+}
+
+$body = $response['body'] ?? '';
+if (is_array($body)) {
+    header('Content-Type: application/json');
+    $body = json_encode($body);
+}
+
+echo $body;
@@ -2,7 +2,9 @@
 from flask import Flask, request, jsonify
 from waitress import serve
 import os
+import sys
 
+import correlation
 from function import handler
 
 app = Flask(__name__)
@@ -55,12 +57,37 @@ def format_response(resp):
 
     return (body, statusCode, headers)
 
+def run_hook(name):
+    hook = getattr(handler, name, None)
+    if hook is None:
+        return ('', 204)
+
+    try:
+        hook()
+    except Exception as e:
+        print(e, file=sys.stderr)
+        return (str(e), 500)
+
+    return ('', 204)
+
+# Lifecycle hooks called by the watchdog, a function implements them by defining
+# optional init() and shutdown() functions in handler.py
+@app.route('/_/init', methods=['POST'])
+def init_hook():
+    return run_hook('init')
+
+@app.route('/_/shutdown', methods=['POST'])
+def shutdown_hook():
+    return run_hook('shutdown')
+
 @app.route('/', defaults={'path': ''}, methods=['GET', 'PUT', 'POST', 'PATCH', 'DELETE'])
 @app.route('/<path:path>', methods=['GET', 'PUT', 'POST', 'PATCH', 'DELETE'])
 def call_handler(path):
     event = Event()
     context = Context()
-    response_data = handler.handle(event, context)
+    # The output of the handler is prefixed with the correlation id of the request
+    with correlation.request(request.headers):
+        response_data = handler.handle(event, context)
     
     resp = format_response(response_data)
     return resp
@@ -0,0 +1,96 @@
+#!/usr/bin/env python
+from flask import Flask, request, jsonify
+from waitress import serve
+import os
+import sys
+
+import correlation
+from function import handler
+
+app = Flask(__name__)
+
+class Event:
+    def __init__(self):
+        self.body = request.get_data()
+        self.headers = request.headers
+        self.method = request.method
+        self.query = request.args
+        self.path = request.path
+
+class Context:
+    def __init__(self):
+        self.hostname = os.getenv('HOSTNAME', 'localhost')
+
+def format_status_code(resp):
+    if 'statusCode' in resp:
+        return resp['statusCode']
+    
+    return 200
+
+def format_body(resp):
+    if 'body' not in resp:
+        return ""
+    elif type(resp['body']) == dict:
+        return jsonify(resp['body'])
+    else:
+        return str(resp['body'])
+
+def format_headers(resp):
+    if 'headers' not in resp:
+        return []
+    elif type(resp['headers']) == dict:
+        headers = []
+        for key in resp['headers'].keys():
+            header_tuple = (key, resp['headers'][key])
+            headers.append(header_tuple)
+        return headers
+    
+    return resp['headers']
+
+def format_response(resp):
+    if resp == None:
+        return ('', 200)
+
+    statusCode = format_status_code(resp)
+    body = format_body(resp)
+    headers = format_headers(resp)
+
+    return (body, statusCode, headers)
+
+def run_hook(name):
+    hook = getattr(handler, name, None)
+    if hook is None:
+        return ('', 204)
+
+    try:
+        hook()
+    except Exception as e:
+        print(e, file=sys.stderr)
+        return (str(e), 500)
+
+    return ('', 204)
+
+# Lifecycle hooks called by the watchdog, a function implements them by defining
+# optional init() and shutdown() functions in handler.py
+@app.route('/_/init', methods=['POST'])
+def init_hook():
+    return run_hook('init')
+
+@app.route('/_/shutdown', methods=['POST'])
+def shutdown_hook():
+    return run_hook('shutdown')
+
+@app.route('/', defaults={'path': ''}, methods=['GET', 'PUT', 'POST', 'PATCH', 'DELETE'])
+@app.route('/<path:path>', methods=['GET', 'PUT', 'POST', 'PATCH', 'DELETE'])
+def call_handler(path):
+    event = Event()
+    context = Context()
+    # The output of the handler is prefixed with the correlation id of the request
+    with correlation.request(request.headers):
+        response_data = handler.handle(event, context)
+    
+    resp = format_response(response_data)
+    return resp
+
+if __name__ == '__main__':
+    serve(app, host='0.0.0.0', port=5000)
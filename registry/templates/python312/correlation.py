@@ -0,0 +1,67 @@
+# Lines written to stdout or stderr while a request is handled are prefixed with "[<id>] ",
+# the id the watchdog sends in the X-Eywa-Correlation-Id header. The watchdog attributes
+# prefixed lines to their request when several requests are in flight.
+import contextvars
+import sys
+import threading
+from contextlib import contextmanager
+
+HEADER = 'X-Eywa-Correlation-Id'
+
+_current = contextvars.ContextVar('correlation_id', default=None)
+
+class PrefixedStream:
+    """Writes a line of a request once it is complete so the lines of concurrent
+    requests are never interleaved"""
+
+    def __init__(self, stream):
+        self._stream = stream
+        self._lock = threading.Lock()
+        self._pending = {}
+
+    def write(self, text):
+        id = _current.get()
+        if id is None:
+            return self._stream.write(text)
+
+        with self._lock:
+            lines = (self._pending.pop(id, '') + text).split('\n')
+            rest = lines.pop()
+            if rest:
+                self._pending[id] = rest
+
+            if lines:
+                self._stream.write(''.join('[%s] %s\n' % (id, line) for line in lines))
+                self._stream.flush()
+
+        return len(text)
+
+    def finish(self, id):
+        """Writes the last line of the request when it did not end with a newline"""
+        with self._lock:
+            rest = self._pending.pop(id, None)
+            if rest:
+                self._stream.write('[%s] %s\n' % (id, rest))
+                self._stream.flush()
+
+    def __getattr__(self, name):
+        return getattr(self._stream, name)
+
+sys.stdout = PrefixedStream(sys.stdout)
+sys.stderr = PrefixedStream(sys.stderr)
+
+@contextmanager
+def request(headers):
+    """Prefixes everything written in the block with the correlation id in the headers"""
+    id = headers.get(HEADER)
+    if not id:
+        yield
+        return
+
+    token = _current.set(id)
+    try:
+        yield
+    finally:
+        _current.reset(token)
+        sys.stdout.finish(id)
+        sys.stderr.finish(id)
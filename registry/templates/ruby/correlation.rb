@@ -0,0 +1,77 @@
+# Lines written to stdout or stderr while a request is handled are prefixed with "[<id>] ",
+# the id the watchdog sends in the X-Eywa-Correlation-Id header. The watchdog attributes
+# prefixed lines to their request when several requests are in flight.
+require 'delegate'
+
+module Correlation
+  HEADER = 'HTTP_X_EYWA_CORRELATION_ID'.freeze
+
+  # PrefixedIO writes a line of a request once it is complete so the lines of concurrent
+  # requests are never interleaved
+  class PrefixedIO < SimpleDelegator
+    def initialize(io)
+      super
+      @lock = Mutex.new
+      @pending = {}
+    end
+
+    def write(*args)
+      text = args.join
+      id = Thread.current[:correlation_id]
+      return __getobj__.write(text) if id.nil?
+
+      @lock.synchronize do
+        lines = (@pending.delete(id).to_s + text).split("\n", -1)
+        rest = lines.pop
+        @pending[id] = rest unless rest.empty?
+        __getobj__.write(lines.map { |line| "[#{id}] #{line}\n" }.join) unless lines.empty?
+      end
+      text.bytesize
+    end
+
+    def <<(obj)
+      write(obj.to_s)
+      self
+    end
+
+    def print(*args)
+      write(*args.map(&:to_s))
+      nil
+    end
+
+    def puts(*args)
+      args = [''] if args.empty?
+      args.flatten.each do |arg|
+        line = arg.to_s
+        write(line.end_with?("\n") ? line : "#{line}\n")
+      end
+      nil
+    end
+
+    # finish writes the last line of the request when it did not end with a newline
+    def finish(id)
+      @lock.synchronize do
+        rest = @pending.delete(id)
+        __getobj__.write("[#{id}] #{rest}\n") unless rest.nil?
+      end
+    end
+  end
+
+  $stdout = PrefixedIO.new($stdout)
+  $stderr = PrefixedIO.new($stderr)
+
+  # request prefixes everything written in the block with the correlation id of the request
+  def self.request(env)
+    id = env[HEADER]
+    return yield if id.nil? || id.empty?
+
+    Thread.current[:correlation_id] = id
+    begin
+      yield
+    ensure
+      Thread.current[:correlation_id] = nil
+      $stdout.finish(id)
+      $stderr.finish(id)
+    end
+  end
+end
@@ -1,6 +1,7 @@
 # Copyright (c) Alex Ellis 2017. All rights reserved.
 # Licensed under the MIT license. See LICENSE file in the project root for full license information.
 
+require_relative 'correlation'
 require_relative 'function/handler'
 
 require 'sinatra'
@@ -10,26 +11,46 @@ set :port, 5000
 
 handler = Handler.new
 
+# Lifecycle hooks called by the watchdog, a function implements them by defining
+# optional init and shutdown methods on its Handler
+def run_hook(handler, name)
+  return 204 unless handler.respond_to?(name)
+
+  handler.public_send(name)
+  204
+rescue StandardError => e
+  warn e.full_message
+  [500, e.message]
+end
+
+post '/_/init' do
+  run_hook handler, :init
+end
+
+post '/_/shutdown' do
+  run_hook handler, :shutdown
+end
+
 get '/*' do
-  res, res_headers, status = handler.run request.body, request.env
+  res, res_headers, status = Correlation.request(request.env) { handler.run request.body, request.env }
 
   [status || 200, res_headers, res]
 end
 
 post '/*' do
-  res, res_headers, status = handler.run request.body, request.env
+  res, res_headers, status = Correlation.request(request.env) { handler.run request.body, request.env }
 
   [status || 200, res_headers, res]
 end
 
 put '/*' do
-  res, res_headers, status = handler.run request.body, request.env
+  res, res_headers, status = Correlation.request(request.env) { handler.run request.body, request.env }
 
   [status || 200, res_headers, res]
 end
 
 delete '/*' do
-  res, res_headers, status = handler.run request.body, request.env
+  res, res_headers, status = Correlation.request(request.env) { handler.run request.body, request.env }
 
   [status || 200, res_headers, res]
 end
@@ -0,0 +1,100 @@
+use std::cell::RefCell;
+use std::io::Read;
+use std::thread;
+
+use tiny_http::{Header, Request, Response, Server};
+
+// The function crate is a library named handler exporting
+//
+//     pub fn handle(method: &str, path: &str, headers: &[(String, String)], body: Vec<u8>)
+//         -> (u16, Vec<(String, String)>, Vec<u8>)
+//
+// returning the status, headers and body of the response.
+//
+// Lines printed while a request is served are attributed to it by the watchdog when they are
+// prefixed with "[<id>] ", the id is sent in the X-Eywa-Correlation-Id header. Rust can not
+// redirect the output of a thread, a function prefixes the lines it prints with the header.
+fn main() {
+    // A panic is reported with the correlation id of the request the thread serves
+    let default_hook = std::panic::take_hook();
+    std::panic::set_hook(Box::new(move |info| {
+        let prefix = prefix();
+        if prefix.is_empty() {
+            default_hook(info);
+        } else {
+            eprintln!("{}{}", prefix, info);
+        }
+    }));
+
+    let port = std::env::var("http_port").unwrap_or_else(|_| "8082".to_string());
+    let server = Server::http(format!("0.0.0.0:{}", port)).expect("failed to listen");
+    println!("rust listening on port: {}", port);
+
+    for request in server.incoming_requests() {
+        thread::spawn(move || serve(request));
+    }
+}
+
+thread_local! {
+    // The correlation id of the request the thread serves
+    static CORRELATION_ID: RefCell<String> = RefCell::new(String::new());
+}
+
+// prefix returns the "[<id>] " prefix of the lines printed for the request the thread serves
+fn prefix() -> String {
+    CORRELATION_ID.with(|id| {
+        let id = id.borrow();
+        if id.is_empty() {
+            String::new()
+        } else {
+            format!("[{}] ", id)
+        }
+    })
+}
+
+fn serve(mut request: Request) {
+    if let Some(header) = request.headers().iter().find(|h| h.field.equiv("X-Eywa-Correlation-Id")) {
+        CORRELATION_ID.with(|id| *id.borrow_mut() = header.value.to_string());
+    }
+
+    let path = request.url().split('?').next().unwrap_or("/").to_string();
+
+    // Rust has no optional functions, the lifecycle hooks of the watchdog are no-ops. A function
+    // initialises its state on first use instead, e.g. with std::sync::OnceLock.
+    if path == "/_/init" || path == "/_/shutdown" {
+        let _ = request.respond(Response::empty(204));
+        return;
+    }
+
+    let mut body = Vec::new();
+    if let Err(e) = request.as_reader().read_to_end(&mut body) {
+        eprintln!("{}failed to read request body: {}", prefix(), e);
+        let _ = request.respond(Response::from_string(e.to_string()).with_status_code(400));
+        return;
+    }
+
+    let method = request.method().to_string();
+    let headers: Vec<(String, String)> = request
+        .headers()
+        .iter()
+        .map(|h| (h.field.to_string(), h.value.to_string()))
+        .collect();
+
+    let result = std::panic::catch_unwind(|| handler::handle(&method, &path, &headers, body));
+    let (status, headers, body) = match result {
+        Ok(response) => response,
+        Err(_) => (500, Vec::new(), b"function panicked".to_vec()),
+    };
+
+    let mut response = Response::from_data(body).with_status_code(status);
+    for (name, value) in headers {
+        match Header::from_bytes(name.as_bytes(), value.as_bytes()) {
+            Ok(header) => response.add_header(header),
+            Err(_) => eprintln!("{}invalid response header {}", prefix(), name),
+        }
+    }
+
+    if let Err(e) = request.respond(response) {
+        eprintln!("{}failed to write response: {}", prefix(), e);
+    }
+}
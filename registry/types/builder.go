@@ -1,6 +1,9 @@
 package types
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,4 +16,47 @@ type Build struct {
 	Logs      pq.StringArray `db:"logs"`
 	State     string         `db:"state"`
 	CreatedAt time.Time      `db:"created_at"`
+
+	ExecutablePath *string    `db:"executable_path"`
+	Attempts       int        `db:"attempts"`
+	StartedAt      *time.Time `db:"started_at"`
+	HeartbeatAt    *time.Time `db:"heartbeat_at"`
+	FinishedAt     *time.Time `db:"finished_at"`
+	// CancelRequested is set when the build is cancelled while a worker runs it
+	CancelRequested bool `db:"cancel_requested"`
+	// RunTests tells whether the build runs the function tests, Tests is their result once they ran
+	RunTests bool        `db:"run_tests"`
+	Tests    *TestResult `db:"tests"`
+}
+
+// Test results
+const (
+	TestsPassed = "passed"
+	TestsFailed = "failed"
+)
+
+// TestResult represents the result of the function tests of a build
+type TestResult struct {
+	Status   string   `json:"status"`
+	Passed   int      `json:"passed"`
+	Failed   int      `json:"failed"`
+	Skipped  int      `json:"skipped"`
+	Coverage *float64 `json:"coverage,omitempty"` // Coverage is the percentage of statements covered
+	Failures []string `json:"failures"`           // Failures are the names of the failed tests
+	Format   string   `json:"format"`             // Format is the test output format of the runtime
+}
+
+// Value returns marshaled test result
+func (t TestResult) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan decodes postgres value into a TestResult type
+func (t *TestResult) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &t)
 }
@@ -16,6 +16,9 @@ type ImageBuildResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ImageStateSuccess is the state of an image that was built successfully and can be deployed
+const ImageStateSuccess = "success"
+
 // Image represents an image
 type Image struct {
 	ID             string    `db:"id" json:"id"`
@@ -28,9 +31,16 @@ type Image struct {
 	State          string    `db:"state" json:"state"`
 	Size           int       `db:"size" json:"size"`
 	Source         string    `db:"-" json:"-"`
-}
+	SourceType     string    `db:"source_type" json:"source_type"`
+	GitURL         *string   `db:"git_url" json:"git_url,omitempty"`
+	GitRef         *string   `db:"git_ref" json:"git_ref,omitempty"`
+	GitPath        *string   `db:"git_path" json:"git_path,omitempty"`
+	GitSecretID    *string   `db:"git_secret_id" json:"git_secret_id,omitempty"`
+	CommitSHA      *string   `db:"commit_sha" json:"commit_sha,omitempty"`
+	// ScanState is the outcome of the vulnerability scan of the image, ScannedAt when it finished
+	ScanState *string    `db:"scan_state" json:"scan_state,omitempty"`
+	ScannedAt *time.Time `db:"scanned_at" json:"scanned_at,omitempty"`
 
-// ImageLogs represents image build logs responsej
-type ImageLogs struct {
-	Logs []string `json:"logs"`
+	QueuePosition int        `db:"-" json:"queue_position,omitempty"`
+	ETA           *time.Time `db:"-" json:"eta,omitempty"`
 }
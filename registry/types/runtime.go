@@ -0,0 +1,26 @@
+package types
+
+// Executable path requirements of runtimes
+const (
+	ExecutablePathRequired = "required"
+	ExecutablePathOptional = "optional"
+)
+
+// Runtime represents a runtime images can be built for, read from the runtime.json manifest
+// of its template directory. The name of the runtime is the name of the directory.
+type Runtime struct {
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	Version         string   `json:"version"`
+	RequiredFiles   []string `json:"required_files"`
+	DependencyFiles []string `json:"dependency_files"`      // DependencyFiles are the function files declaring its dependencies
+	TestFormat      string   `json:"test_format,omitempty"` // TestFormat of the test output, the runtime has no tests when empty
+	ExecutablePath  string   `json:"executable_path,omitempty"`
+	Deprecated      bool     `json:"deprecated"`
+}
+
+// GetRuntimesResponse represents GET ALL runtimes response
+type GetRuntimesResponse struct {
+	Objects []Runtime `json:"objects"`
+	Total   int       `json:"total_count"`
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Severities of vulnerabilities
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// Scan states of images, images built before or while scanning was disabled have none
+const (
+	ScanStateScanned = "scanned"
+	ScanStateFailed  = "failed"
+)
+
+// Vulnerability represents a known vulnerability found in a package of an image
+type Vulnerability struct {
+	ImageID          string `db:"image_id" json:"-"`
+	VulnerabilityID  string `db:"vulnerability_id" json:"vulnerability_id"`
+	Package          string `db:"package" json:"package"`
+	InstalledVersion string `db:"installed_version" json:"installed_version"`
+	FixedVersion     string `db:"fixed_version" json:"fixed_version,omitempty"` // FixedVersion is empty when there is no fix
+	Severity         string `db:"severity" json:"severity"`
+	Title            string `db:"title" json:"title,omitempty"`
+}
+
+// VulnerabilitySummary counts the vulnerabilities of an image by severity
+type VulnerabilitySummary struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+}
+
+// Summarize counts the vulnerabilities by severity
+func Summarize(vulnerabilities []Vulnerability) VulnerabilitySummary {
+	var summary VulnerabilitySummary
+	for _, vulnerability := range vulnerabilities {
+		switch vulnerability.Severity {
+		case SeverityCritical:
+			summary.Critical++
+		case SeverityHigh:
+			summary.High++
+		case SeverityMedium:
+			summary.Medium++
+		case SeverityLow:
+			summary.Low++
+		default:
+			summary.Unknown++
+		}
+	}
+
+	return summary
+}
+
+// VulnerabilityPolicy represents the vulnerabilities an image can have to be deployed, the
+// severities without a maximum are not limited
+type VulnerabilityPolicy struct {
+	MaxCritical *int `db:"max_critical" json:"max_critical"`
+	MaxHigh     *int `db:"max_high" json:"max_high"`
+	MaxMedium   *int `db:"max_medium" json:"max_medium"`
+	MaxLow      *int `db:"max_low" json:"max_low"`
+	// AllowUnscanned allows to deploy images whose scan failed, images built while scanning was
+	// disabled are always allowed
+	AllowUnscanned bool       `db:"allow_unscanned" json:"allow_unscanned"`
+	UpdatedAt      *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Violations returns how an image in scanState with the summary violates the policy, none when
+// the image can be deployed. Images without a scan state were built before scanning was enabled.
+func (p *VulnerabilityPolicy) Violations(scanState *string, summary VulnerabilitySummary) []string {
+	violations := []string{}
+	if scanState == nil {
+		return violations
+	}
+
+	if *scanState != ScanStateScanned {
+		if p.AllowUnscanned {
+			return violations
+		}
+
+		return append(violations, "Vulnerability scan of the image failed")
+	}
+
+	limits := []struct {
+		severity string
+		max      *int
+		count    int
+	}{
+		{SeverityCritical, p.MaxCritical, summary.Critical},
+		{SeverityHigh, p.MaxHigh, summary.High},
+		{SeverityMedium, p.MaxMedium, summary.Medium},
+		{SeverityLow, p.MaxLow, summary.Low},
+	}
+
+	for _, limit := range limits {
+		if limit.max != nil && limit.count > *limit.max {
+			violations = append(violations, fmt.Sprintf("Image has %d %s vulnerabilities, at most %d are allowed",
+				limit.count, limit.severity, *limit.max))
+		}
+	}
+
+	return violations
+}
+
+// GetImageVulnerabilitiesResponse represents the vulnerabilities of an image and whether the
+// vulnerability policy allows to deploy it
+type GetImageVulnerabilitiesResponse struct {
+	Objects    []Vulnerability      `json:"objects"`
+	Total      int                  `json:"total_count"`
+	ScanState  *string              `json:"scan_state"`
+	ScannedAt  *time.Time           `json:"scanned_at,omitempty"`
+	Summary    VulnerabilitySummary `json:"summary"`
+	Deployable bool                 `json:"deployable"`
+	Violations []string             `json:"violations"`
+}
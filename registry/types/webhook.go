@@ -0,0 +1,43 @@
+package types
+
+import "time"
+
+// GitWebhook represents a webhook building an image on every push to a git repository ref
+type GitWebhook struct {
+	ID             string    `db:"id" json:"id"`
+	UserID         string    `db:"user_id" json:"-"`
+	Name           string    `db:"name" json:"name"`
+	Runtime        string    `db:"runtime" json:"runtime"`
+	Version        string    `db:"version" json:"version"`
+	ExecutablePath *string   `db:"executable_path" json:"executable_path,omitempty"`
+	GitURL         string    `db:"git_url" json:"git_url"`
+	GitRef         string    `db:"git_ref" json:"git_ref"`
+	GitPath        *string   `db:"git_path" json:"git_path,omitempty"`
+	GitSecretID    *string   `db:"git_secret_id" json:"git_secret_id,omitempty"`
+	SigningSecret  string    `db:"signing_secret" json:"signing_secret,omitempty"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// CreateGitWebhookRequest represents a request to create a git webhook
+type CreateGitWebhookRequest struct {
+	Name           string  `json:"name" min_length:"5" binding:"required"`
+	Runtime        string  `json:"runtime" binding:"required"`
+	Version        string  `json:"version" pattern:"^(\\d{1,3}\\.?){3}$" binding:"required"`
+	ExecutablePath *string `json:"executable_path"`
+	GitURL         string  `json:"git_url" pattern:"^https?://" binding:"required"`
+	GitRef         string  `json:"git_ref" binding:"required"`
+	GitPath        *string `json:"git_path"`
+	GitSecretID    *string `json:"git_secret_id"`
+}
+
+// GetGitWebhooksResponse represents GET ALL git webhooks response
+type GetGitWebhooksResponse struct {
+	Objects []GitWebhook `json:"objects"`
+	Total   int          `json:"total_count"`
+}
+
+// GitPushEvent represents the payload of a git push webhook
+type GitPushEvent struct {
+	Ref   string `json:"ref"`
+	After string `json:"after"`
+}
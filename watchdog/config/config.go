@@ -14,6 +14,15 @@ type WatchdogConfig struct {
 	HTTPWriteTimeout time.Duration
 	ExecTimeout      time.Duration
 
+	// DrainTimeout is how long the watchdog waits for requests in
+	// flight to complete after SIGTERM before stopping the function.
+	DrainTimeout time.Duration
+
+	// RestartBackoff is the delay before a crashed function process is
+	// restarted, doubled on every consecutive crash up to MaxRestartBackoff.
+	RestartBackoff    time.Duration
+	MaxRestartBackoff time.Duration
+
 	FunctionProcess  string
 	ContentType      string
 	InjectCGIHeaders bool
@@ -22,6 +31,19 @@ type WatchdogConfig struct {
 	UpstreamURL      string
 	Debug            bool
 
+	// UpstreamReadyPath is polled on the upstream until it answers before
+	// the watchdog reports ready, a TCP connect is used when empty.
+	UpstreamReadyPath     string
+	UpstreamReadyInterval time.Duration
+
+	// InitTimeout and ShutdownTimeout bound the calls to the
+	// /_/init and /_/shutdown lifecycle hooks of the upstream.
+	InitTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// StaticPath directory served in static mode
+	StaticPath string
+
 	// BufferHTTPBody buffers the HTTP body in memory
 	// to prevent transfer type of chunked encoding
 	// which some servers do not support.
@@ -33,8 +55,23 @@ type WatchdogConfig struct {
 	// MaxInflight limits the number of simultaneous
 	// requests that the watchdog allows concurrently.
 	// Any request which exceeds this limit will
-	// have an immediate response of 429.
+	// have an immediate response of 429, unless
+	// MaxQueue is set.
 	MaxInflight int
+
+	// MaxQueue is the number of requests over MaxInflight waiting
+	// up to MaxQueueWait for a slot before getting a 429.
+	MaxQueue     int
+	MaxQueueWait time.Duration
+
+	// MaxLogBytes caps the stdout and stderr returned with a single
+	// request, the rest of the output is dropped. In streaming mode it
+	// also caps the output held back while the request body is read.
+	MaxLogBytes int
+
+	// OTLPEndpoint host:port of an OTLP/HTTP collector spans are exported to.
+	// Tracing is disabled when empty.
+	OTLPEndpoint string
 }
 
 // Process returns a string for the process and a slice for the arguments from the FunctionProcess.
@@ -80,20 +117,37 @@ func New(env []string) WatchdogConfig {
 	}
 
 	config := WatchdogConfig{
-		TCPPort:          getInt(envMap, "port", 8080),
-		HTTPReadTimeout:  getDuration(envMap, "read_timeout", time.Second*10),
-		HTTPWriteTimeout: getDuration(envMap, "write_timeout", time.Second*10),
-		FunctionProcess:  functionProcess,
-		InjectCGIHeaders: true,
-		ExecTimeout:      getDuration(envMap, "exec_timeout", time.Second*10),
-		OperationalMode:  ModeStreaming,
-		ContentType:      contentType,
-		SuppressLock:     getBool(envMap, "suppress_lock"),
-		Debug:            getBool(envMap, "write_debug"),
-		UpstreamURL:      upstreamURL,
-		BufferHTTPBody:   getBools(envMap, "buffer_http", "http_buffer_req_body"),
-		MetricsPort:      8081,
-		MaxInflight:      getInt(envMap, "max_inflight", 0),
+		TCPPort:               getInt(envMap, "port", 8080),
+		HTTPReadTimeout:       getDuration(envMap, "read_timeout", time.Second*10),
+		HTTPWriteTimeout:      getDuration(envMap, "write_timeout", time.Second*10),
+		FunctionProcess:       functionProcess,
+		InjectCGIHeaders:      true,
+		ExecTimeout:           getDuration(envMap, "exec_timeout", time.Second*10),
+		OperationalMode:       ModeStreaming,
+		ContentType:           contentType,
+		SuppressLock:          getBool(envMap, "suppress_lock"),
+		Debug:                 getBool(envMap, "write_debug"),
+		UpstreamURL:           upstreamURL,
+		UpstreamReadyPath:     envMap["upstream_ready_path"],
+		UpstreamReadyInterval: getDuration(envMap, "upstream_ready_interval", time.Millisecond*100),
+		InitTimeout:           getDuration(envMap, "init_timeout", time.Second*30),
+		ShutdownTimeout:       getDuration(envMap, "shutdown_timeout", time.Second*5),
+		StaticPath:            "/home/app/public",
+		BufferHTTPBody:        getBools(envMap, "buffer_http", "http_buffer_req_body"),
+		MetricsPort:           8081,
+		MaxInflight:           getInt(envMap, "max_inflight", 0),
+		MaxQueue:              getInt(envMap, "max_queue", 0),
+		MaxQueueWait:          getDuration(envMap, "max_queue_wait", time.Second*10),
+		MaxLogBytes:           getInt(envMap, "max_log_bytes", 64*1024),
+		RestartBackoff:        getDuration(envMap, "restart_backoff", time.Millisecond*250),
+		MaxRestartBackoff:     getDuration(envMap, "max_restart_backoff", time.Second*30),
+		OTLPEndpoint:          envMap["otlp_endpoint"],
+	}
+
+	config.DrainTimeout = getDuration(envMap, "drain_timeout", config.HTTPWriteTimeout)
+
+	if val := envMap["static_path"]; len(val) > 0 {
+		config.StaticPath = val
 	}
 
 	if val := envMap["mode"]; len(val) > 0 {
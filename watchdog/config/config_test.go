@@ -20,6 +20,18 @@ func Test_OperationalMode_Default(t *testing.T) {
 	}
 }
 
+func Test_OTLPEndpoint(t *testing.T) {
+	env := []string{
+		"otlp_endpoint=otel-collector:4318",
+	}
+
+	actual := New(env)
+	want := "otel-collector:4318"
+	if actual.OTLPEndpoint != want {
+		t.Errorf("Want %v. got: %v", want, actual.OTLPEndpoint)
+	}
+}
+
 func Test_BufferHttpModeDefaultsToFalse(t *testing.T) {
 	env := []string{}
 
@@ -92,6 +104,175 @@ func Test_OperationalMode_Static(t *testing.T) {
 	}
 }
 
+func Test_StaticPath_Default(t *testing.T) {
+	actual := New([]string{})
+	want := "/home/app/public"
+	if actual.StaticPath != want {
+		t.Errorf("Want %v. got: %v", want, actual.StaticPath)
+	}
+}
+
+func Test_StaticPath_Override(t *testing.T) {
+	env := []string{
+		"static_path=/srv/www",
+	}
+
+	actual := New(env)
+	want := "/srv/www"
+	if actual.StaticPath != want {
+		t.Errorf("Want %v. got: %v", want, actual.StaticPath)
+	}
+}
+
+func Test_MaxLogBytes_Default(t *testing.T) {
+	actual := New([]string{})
+	want := 64 * 1024
+	if actual.MaxLogBytes != want {
+		t.Errorf("Want %v. got: %v", want, actual.MaxLogBytes)
+	}
+}
+
+func Test_MaxLogBytes_Override(t *testing.T) {
+	env := []string{
+		"max_log_bytes=1024",
+	}
+
+	actual := New(env)
+	want := 1024
+	if actual.MaxLogBytes != want {
+		t.Errorf("Want %v. got: %v", want, actual.MaxLogBytes)
+	}
+}
+
+func Test_DrainTimeout_DefaultsToWriteTimeout(t *testing.T) {
+	env := []string{
+		"write_timeout=30s",
+	}
+
+	actual := New(env)
+	want := time.Second * 30
+	if actual.DrainTimeout != want {
+		t.Errorf("Want %v. got: %v", want, actual.DrainTimeout)
+	}
+}
+
+func Test_DrainTimeout_Override(t *testing.T) {
+	env := []string{
+		"write_timeout=30s",
+		"drain_timeout=1m",
+	}
+
+	actual := New(env)
+	want := time.Minute
+	if actual.DrainTimeout != want {
+		t.Errorf("Want %v. got: %v", want, actual.DrainTimeout)
+	}
+}
+
+func Test_RestartBackoff_Defaults(t *testing.T) {
+	actual := New([]string{})
+
+	if actual.RestartBackoff != time.Millisecond*250 {
+		t.Errorf("Want %v. got: %v", time.Millisecond*250, actual.RestartBackoff)
+	}
+	if actual.MaxRestartBackoff != time.Second*30 {
+		t.Errorf("Want %v. got: %v", time.Second*30, actual.MaxRestartBackoff)
+	}
+}
+
+func Test_RestartBackoff_Override(t *testing.T) {
+	env := []string{
+		"restart_backoff=1s",
+		"max_restart_backoff=2m",
+	}
+
+	actual := New(env)
+	if actual.RestartBackoff != time.Second {
+		t.Errorf("Want %v. got: %v", time.Second, actual.RestartBackoff)
+	}
+	if actual.MaxRestartBackoff != time.Minute*2 {
+		t.Errorf("Want %v. got: %v", time.Minute*2, actual.MaxRestartBackoff)
+	}
+}
+
+func Test_UpstreamReadiness_Defaults(t *testing.T) {
+	actual := New([]string{})
+
+	if actual.UpstreamReadyPath != "" {
+		t.Errorf("Want a TCP check by default. got path: %q", actual.UpstreamReadyPath)
+	}
+	if actual.UpstreamReadyInterval != time.Millisecond*100 {
+		t.Errorf("Want %v. got: %v", time.Millisecond*100, actual.UpstreamReadyInterval)
+	}
+}
+
+func Test_UpstreamReadiness_Override(t *testing.T) {
+	env := []string{
+		"upstream_ready_path=/ready",
+		"upstream_ready_interval=1s",
+	}
+
+	actual := New(env)
+	if actual.UpstreamReadyPath != "/ready" {
+		t.Errorf("Want %q. got: %q", "/ready", actual.UpstreamReadyPath)
+	}
+	if actual.UpstreamReadyInterval != time.Second {
+		t.Errorf("Want %v. got: %v", time.Second, actual.UpstreamReadyInterval)
+	}
+}
+
+func Test_MaxQueue_DisabledByDefault(t *testing.T) {
+	actual := New([]string{})
+
+	if actual.MaxQueue != 0 {
+		t.Errorf("Want %d. got: %d", 0, actual.MaxQueue)
+	}
+	if actual.MaxQueueWait != time.Second*10 {
+		t.Errorf("Want %v. got: %v", time.Second*10, actual.MaxQueueWait)
+	}
+}
+
+func Test_MaxQueue_Override(t *testing.T) {
+	env := []string{
+		"max_queue=50",
+		"max_queue_wait=2s",
+	}
+
+	actual := New(env)
+	if actual.MaxQueue != 50 {
+		t.Errorf("Want %d. got: %d", 50, actual.MaxQueue)
+	}
+	if actual.MaxQueueWait != time.Second*2 {
+		t.Errorf("Want %v. got: %v", time.Second*2, actual.MaxQueueWait)
+	}
+}
+
+func Test_LifecycleHookTimeouts_Defaults(t *testing.T) {
+	actual := New([]string{})
+
+	if actual.InitTimeout != time.Second*30 {
+		t.Errorf("Want %v. got: %v", time.Second*30, actual.InitTimeout)
+	}
+	if actual.ShutdownTimeout != time.Second*5 {
+		t.Errorf("Want %v. got: %v", time.Second*5, actual.ShutdownTimeout)
+	}
+}
+
+func Test_LifecycleHookTimeouts_Override(t *testing.T) {
+	env := []string{
+		"init_timeout=2m",
+		"shutdown_timeout=15s",
+	}
+
+	actual := New(env)
+	if actual.InitTimeout != time.Minute*2 {
+		t.Errorf("Want %v. got: %v", time.Minute*2, actual.InitTimeout)
+	}
+	if actual.ShutdownTimeout != time.Second*15 {
+		t.Errorf("Want %v. got: %v", time.Second*15, actual.ShutdownTimeout)
+	}
+}
+
 func Test_ContentType_Default(t *testing.T) {
 	env := []string{}
 
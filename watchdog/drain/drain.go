@@ -0,0 +1,59 @@
+package drain
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// pollInterval is how often Wait checks the number of requests in flight
+const pollInterval = 50 * time.Millisecond
+
+// Tracker counts the requests in flight and tracks whether the watchdog is draining
+type Tracker struct {
+	inFlight int64
+	draining int32
+}
+
+// Handler counts the requests served by next as in flight
+func (t *Tracker) Handler(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&t.inFlight, 1)
+		defer atomic.AddInt64(&t.inFlight, -1)
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// InFlight returns the number of requests in flight
+func (t *Tracker) InFlight() int64 {
+	return atomic.LoadInt64(&t.inFlight)
+}
+
+// Start marks the watchdog as draining
+func (t *Tracker) Start() {
+	atomic.StoreInt32(&t.draining, 1)
+}
+
+// Draining checks if the watchdog is draining
+func (t *Tracker) Draining() bool {
+	return atomic.LoadInt32(&t.draining) == 1
+}
+
+// Wait blocks until no request is in flight or the timeout passes.
+// It returns the number of requests still in flight.
+func (t *Tracker) Wait(timeout time.Duration) int64 {
+	deadline := time.Now().Add(timeout)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		inFlight := t.InFlight()
+		if inFlight == 0 || !time.Now().Before(deadline) {
+			return inFlight
+		}
+
+		<-ticker.C
+	}
+}
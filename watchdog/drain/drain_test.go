@@ -0,0 +1,61 @@
+package drain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Wait_ReturnsOnceRequestsComplete(t *testing.T) {
+	tracker := &Tracker{}
+	release := make(chan struct{})
+
+	handler := tracker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+
+	for tracker.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+
+	if remaining := tracker.Wait(5 * time.Second); remaining != 0 {
+		t.Errorf("Want 0 requests in flight, got %d", remaining)
+	}
+	<-done
+}
+
+func Test_Wait_GivesUpAfterTimeout(t *testing.T) {
+	tracker := &Tracker{}
+	release := make(chan struct{})
+	defer close(release)
+
+	handler := tracker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	go handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for tracker.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	started := time.Now()
+	if remaining := tracker.Wait(100 * time.Millisecond); remaining != 1 {
+		t.Errorf("Want 1 request in flight, got %d", remaining)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Want Wait to give up after the timeout, took %s", elapsed)
+	}
+}
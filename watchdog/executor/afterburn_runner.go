@@ -0,0 +1,211 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"eywa/go-libs/tracing"
+)
+
+// AfterBurnFunctionRunner keeps one process alive and exchanges HTTP/1.1 messages with it
+// over stdin and stdout. The process handles one request at a time and receives the request
+// headers within the message so no CGI environment is injected.
+type AfterBurnFunctionRunner struct {
+	ExecTimeout time.Duration // ExecTimeout the maximum duration of a function call
+	Process     string        // Process to run as fprocess
+	ProcessArgs []string      // ProcessArgs to pass to command
+	WriteDebug  bool
+	MaxLogBytes int // MaxLogBytes caps the output returned for one request
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	logs     *logCapture
+	exited   chan struct{} // exited is closed when the current process has exited
+	stopping bool
+}
+
+type afterBurnResult struct {
+	response *http.Response
+	body     []byte
+	err      error
+}
+
+// Start forks the process used for processing incoming requests
+func (f *AfterBurnFunctionRunner) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.logs = newLogCapture(f.MaxLogBytes)
+	return f.start()
+}
+
+// start forks a new process, the caller must hold mu
+func (f *AfterBurnFunctionRunner) start() error {
+	cmd := exec.Command(f.Process, f.ProcessArgs...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	cmd.Stderr = f.logs.writer("stderr")
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+
+	f.cmd = cmd
+	f.stdin = stdin
+	f.stdout = bufio.NewReader(stdout)
+	f.exited = exited
+
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+
+		f.mu.Lock()
+		replaced := f.cmd != cmd
+		stopping := f.stopping
+		f.mu.Unlock()
+
+		if stopping {
+			log.Printf("Forked function has exited: %v", err)
+			return
+		}
+
+		// A process killed after exec_timeout is replaced with a new one
+		if !replaced {
+			log.Fatalf("Forked function has terminated: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop sends SIGTERM to the function process and waits up to timeout for it to exit.
+// It waits for the request being processed to complete first.
+func (f *AfterBurnFunctionRunner) Stop(timeout time.Duration) error {
+	f.mu.Lock()
+	f.stopping = true
+	cmd, exited := f.cmd, f.exited
+	f.mu.Unlock()
+
+	return stopProcess(cmd, exited, timeout)
+}
+
+// Run writes the request to the process and responds with the HTTP response it prints
+func (f *AfterBurnFunctionRunner) Run(req FunctionRequest, r *http.Request, w http.ResponseWriter) error {
+	startedTime := time.Now()
+
+	ctx, span := startRunSpan(r)
+	defer span.End()
+
+	request, err := http.NewRequest(r.Method, r.URL.String(), req.InputReader)
+	if err != nil {
+		return err
+	}
+	request.Host = r.Host
+	request.ContentLength = r.ContentLength
+
+	correlationID := newCorrelationID()
+	copyHeaders(request.Header, &r.Header)
+	request.Header.Set(CorrelationHeader, correlationID)
+
+	// Forward the watchdog span as the parent of any span created by the function
+	tracing.Inject(ctx, request.Header)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.logs.begin(correlationID)
+	defer f.logs.end(correlationID)
+
+	// Requests are serialized, the process serves this one exclusively
+	usage := measureProcess(f.cmd.Process.Pid, true)
+
+	done := make(chan afterBurnResult, 1)
+	go func() {
+		if err := request.Write(f.stdin); err != nil {
+			done <- afterBurnResult{err: err}
+			return
+		}
+
+		res, err := http.ReadResponse(f.stdout, request)
+		if err != nil {
+			done <- afterBurnResult{err: err}
+			return
+		}
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		done <- afterBurnResult{response: res, body: body, err: err}
+	}()
+
+	var timeout <-chan time.Time
+	if f.ExecTimeout.Nanoseconds() > 0 {
+		timer := time.NewTimer(f.ExecTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			log.Printf("Afterburn exchange error: %s\n", result.err)
+			tracing.RecordError(span, result.err)
+			return result.err
+		}
+
+		resp := FunctionResponse{
+			Status:  result.response.StatusCode,
+			Headers: http.Header{},
+			Body:    result.body,
+			Usage:   usage(),
+		}
+		copyHeaders(resp.Headers, &result.response.Header)
+
+		if f.WriteDebug {
+			logs := f.logs.end(correlationID)
+			resp.Stderr, resp.Logs = logs.stderr, logs.entries
+
+			log.Printf("STDERR: %v\n", resp.Stderr)
+		}
+
+		writeFunctionResponse(w, &resp, startedTime)
+		return nil
+
+	case <-timeout:
+		log.Printf("Afterburn process killed due to exec_timeout: %s\n", f.ExecTimeout)
+		tracing.RecordError(span, context.DeadlineExceeded)
+
+		if err := f.cmd.Process.Kill(); err != nil {
+			log.Printf("Failed to kill afterburn process: %s\n", err)
+		}
+		// Pipes of the killed process are closed so the exchange returns
+		<-done
+
+		if err := f.start(); err != nil {
+			return fmt.Errorf("failed to restart afterburn process: %s", err)
+		}
+
+		writeRunError(w, http.StatusGatewayTimeout, ErrorTimeout, "Function timed out", startedTime)
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The watchdog responds with the status, headers and raw body of the function, marked with
+// EnvelopeHeader. The output captured for the request and its resource usage follow the body
+// as JSON encoded trailers. A streamed response is sent with status 200 and carries the status
+// of the function in StatusTrailer. Errors of the watchdog itself carry ErrorHeader.
+const (
+	// EnvelopeHeader holds the version of the envelope the watchdog responded with
+	EnvelopeHeader = "X-Eywa-Envelope"
+	// EnvelopeVersion is the version of the envelope written by this watchdog
+	EnvelopeVersion = "2"
+	// ErrorHeader holds the kind of error of a response produced by the watchdog, not by the function
+	ErrorHeader = "X-Eywa-Error"
+
+	// StatusTrailer holds the status of a streamed function response
+	StatusTrailer = "X-Eywa-Status"
+	// StdoutTrailer holds the stdout lines of the function
+	StdoutTrailer = "X-Eywa-Stdout"
+	// StderrTrailer holds the stderr lines of the function
+	StderrTrailer = "X-Eywa-Stderr"
+	// LogsTrailer holds the structured log entries of the function
+	LogsTrailer = "X-Eywa-Logs"
+	// UsageTrailer holds the resource usage of the function
+	UsageTrailer = "X-Eywa-Usage"
+)
+
+// Kinds of errors produced by the watchdog
+const (
+	// ErrorTimeout the function did not respond within exec_timeout
+	ErrorTimeout = "timeout"
+	// ErrorUnavailable the function is not ready to serve requests
+	ErrorUnavailable = "unavailable"
+	// ErrorConcurrencyLimit the watchdog is at its concurrency limit
+	ErrorConcurrencyLimit = "concurrency_limit"
+	// ErrorFunction the function could not be run
+	ErrorFunction = "function"
+)
+
+var envelopeTrailers = []string{StatusTrailer, StdoutTrailer, StderrTrailer, LogsTrailer, UsageTrailer}
+
+// watchdogHeaders are set by the watchdog and its HTTP server, not by the function
+var watchdogHeaders = []string{
+	EnvelopeHeader, ErrorHeader, "X-Duration-Seconds",
+	"Trailer", "Transfer-Encoding", "Content-Length", "Connection", "Keep-Alive", "Date",
+}
+
+// hopHeaders describe the connection between the function and the watchdog, not the
+// response. Passing on the Content-Length of the function in particular would make the
+// HTTP server send a fixed length body and drop the envelope trailers.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length",
+}
+
+// removeHopHeaders removes the hop-by-hop headers, including those named by Connection
+func removeHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+// writeFunctionResponse writes the function response in the envelope
+func writeFunctionResponse(w http.ResponseWriter, resp *FunctionResponse, startedTime time.Time) {
+	header := w.Header()
+	copyHeaders(header, &resp.Headers)
+	removeHopHeaders(header)
+	for _, name := range append([]string{ErrorHeader}, envelopeTrailers...) {
+		header.Del(name)
+	}
+	header.Set(EnvelopeHeader, EnvelopeVersion)
+	header.Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
+
+	// A response without a body can not have trailers, its output is sent in the header
+	if !bodyAllowed(resp.Status) {
+		setTrailers(header, resp)
+		w.WriteHeader(resp.Status)
+		return
+	}
+
+	declareTrailers(header)
+	w.WriteHeader(resp.Status)
+	if _, err := w.Write(resp.Body); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+
+	setTrailers(header, resp)
+}
+
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// declareTrailers announces the envelope trailers, it must be called before the header is written
+func declareTrailers(header http.Header) {
+	header.Set("Trailer", strings.Join(envelopeTrailers, ", "))
+}
+
+// setTrailers sets the trailers carrying the output and usage of the function response
+func setTrailers(header http.Header, resp *FunctionResponse) {
+	setJSONTrailer(header, StdoutTrailer, resp.Stdout, len(resp.Stdout) > 0)
+	setJSONTrailer(header, StderrTrailer, resp.Stderr, len(resp.Stderr) > 0)
+	setJSONTrailer(header, LogsTrailer, resp.Logs, len(resp.Logs) > 0)
+	setJSONTrailer(header, UsageTrailer, resp.Usage, resp.Usage != nil)
+}
+
+func setJSONTrailer(header http.Header, name string, value interface{}, present bool) {
+	if !present {
+		return
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Failed to encode %s: %s", name, err)
+		return
+	}
+
+	header.Set(name, string(encoded))
+}
+
+// WriteError responds with an error produced by the watchdog
+func WriteError(w http.ResponseWriter, status int, kind, message string) {
+	w.Header().Set(EnvelopeHeader, EnvelopeVersion)
+	w.Header().Set(ErrorHeader, kind)
+
+	http.Error(w, message, status)
+}
+
+// writeRunError responds with an error of a function run started at startedTime
+func writeRunError(w http.ResponseWriter, status int, kind, message string, startedTime time.Time) {
+	w.Header().Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
+	WriteError(w, status, kind, message)
+}
+
+// DecodeFunctionResponse decodes the response of a watchdog, body is the response body read
+// in full. Watchdogs predating the envelope respond with a JSON encoded FunctionResponse and
+// report their own errors with a bare status.
+func DecodeFunctionResponse(res *http.Response, body []byte) (*FunctionResponse, error) {
+	if res.Header.Get(EnvelopeHeader) == "" {
+		return decodeLegacyResponse(res, body)
+	}
+
+	resp := &FunctionResponse{
+		Status:  res.StatusCode,
+		Headers: http.Header{},
+		Body:    body,
+		Error:   res.Header.Get(ErrorHeader),
+	}
+
+	copyHeaders(resp.Headers, &res.Header)
+	for _, name := range append(watchdogHeaders, envelopeTrailers...) {
+		resp.Headers.Del(name)
+	}
+
+	// Trailers of a response without a body are sent in the header
+	trailer := res.Trailer
+	if !bodyAllowed(res.StatusCode) {
+		trailer = res.Header
+	}
+
+	if status := trailer.Get(StatusTrailer); status != "" {
+		parsed, err := strconv.Atoi(status)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s trailer %q", StatusTrailer, status)
+		}
+		resp.Status = parsed
+	}
+
+	for name, value := range map[string]interface{}{
+		StdoutTrailer: &resp.Stdout,
+		StderrTrailer: &resp.Stderr,
+		LogsTrailer:   &resp.Logs,
+		UsageTrailer:  &resp.Usage,
+	} {
+		if encoded := trailer.Get(name); encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), value); err != nil {
+				return nil, fmt.Errorf("invalid %s trailer: %s", name, err)
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+func decodeLegacyResponse(res *http.Response, body []byte) (*FunctionResponse, error) {
+	if res.StatusCode >= http.StatusBadRequest {
+		kind := ErrorFunction
+		switch res.StatusCode {
+		case http.StatusGatewayTimeout:
+			kind = ErrorTimeout
+		case http.StatusServiceUnavailable:
+			kind = ErrorUnavailable
+		case http.StatusTooManyRequests:
+			kind = ErrorConcurrencyLimit
+		}
+
+		return &FunctionResponse{Status: res.StatusCode, Body: body, Error: kind}, nil
+	}
+
+	resp := &FunctionResponse{Status: res.StatusCode}
+	if len(body) == 0 {
+		return resp, nil
+	}
+
+	if err := json.Unmarshal(body, resp); err != nil {
+		return nil, fmt.Errorf("invalid function response: %s", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,200 @@
+package executor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func roundTrip(t *testing.T, handler http.HandlerFunc) *FunctionResponse {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %s", err)
+	}
+
+	resp, err := DecodeFunctionResponse(res, body)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+
+	return resp
+}
+
+func Test_Envelope_RoundTripsBinaryBody(t *testing.T) {
+	body := []byte{0x00, 0xff, 0xfe, '\n', 0x80}
+	cpuSeconds := 0.5
+
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		writeFunctionResponse(w, &FunctionResponse{
+			Status:  http.StatusCreated,
+			Headers: http.Header{"Content-Type": []string{"application/octet-stream"}, "X-Custom": []string{"a", "b"}},
+			Body:    body,
+			Stdout:  []string{"out"},
+			Stderr:  []string{"err"},
+			Logs:    []LogEntry{{Level: "info", Message: "hello"}},
+			Usage:   &ResourceUsage{CPUSeconds: &cpuSeconds, MemoryBytes: 1024},
+		}, time.Now())
+	})
+
+	if resp.Status != http.StatusCreated {
+		t.Errorf("Want status %d, got %d", http.StatusCreated, resp.Status)
+	}
+	if !bytes.Equal(resp.Body, body) {
+		t.Errorf("Want body %v, got %v", body, resp.Body)
+	}
+	if got := resp.Headers.Get("Content-Type"); got != "application/octet-stream" {
+		t.Errorf("Want content type %q, got %q", "application/octet-stream", got)
+	}
+	if got := resp.Headers["X-Custom"]; len(got) != 2 {
+		t.Errorf("Want both X-Custom values, got %v", got)
+	}
+	for _, name := range []string{EnvelopeHeader, "X-Duration-Seconds", "Trailer", StdoutTrailer} {
+		if resp.Headers.Get(name) != "" {
+			t.Errorf("Want %s removed from the function headers", name)
+		}
+	}
+	if len(resp.Stdout) != 1 || resp.Stdout[0] != "out" || len(resp.Stderr) != 1 || resp.Stderr[0] != "err" {
+		t.Errorf("Want stdout and stderr from the trailers, got %v %v", resp.Stdout, resp.Stderr)
+	}
+	if len(resp.Logs) != 1 || resp.Logs[0].Message != "hello" {
+		t.Errorf("Want the log entry from the trailers, got %+v", resp.Logs)
+	}
+	if resp.Usage == nil || resp.Usage.MemoryBytes != 1024 || resp.Usage.CPUSeconds == nil || *resp.Usage.CPUSeconds != 0.5 {
+		t.Errorf("Want the usage from the trailers, got %+v", resp.Usage)
+	}
+	if resp.Error != "" {
+		t.Errorf("Want no error, got %q", resp.Error)
+	}
+}
+
+func Test_Envelope_UpstreamFramingHeadersKeepTrailers(t *testing.T) {
+	body := []byte("hello")
+
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		writeFunctionResponse(w, &FunctionResponse{
+			Status: http.StatusOK,
+			Headers: http.Header{
+				"Content-Length":    []string{"5"},
+				"Transfer-Encoding": []string{"identity"},
+				"Trailer":           []string{"X-Upstream"},
+				"Connection":        []string{"keep-alive, X-Hop"},
+				"Keep-Alive":        []string{"timeout=5"},
+				"X-Hop":             []string{"1"},
+				"X-Custom":          []string{"kept"},
+			},
+			Body:   body,
+			Stdout: []string{"out"},
+			Stderr: []string{"err"},
+			Logs:   []LogEntry{{Level: "info", Message: "hello"}},
+			Usage:  &ResourceUsage{MemoryBytes: 1024},
+		}, time.Now())
+	})
+
+	if !bytes.Equal(resp.Body, body) {
+		t.Errorf("Want body %q, got %q", body, resp.Body)
+	}
+	if len(resp.Stdout) != 1 || resp.Stdout[0] != "out" || len(resp.Stderr) != 1 || resp.Stderr[0] != "err" {
+		t.Errorf("Want stdout and stderr from the trailers, got %v %v", resp.Stdout, resp.Stderr)
+	}
+	if len(resp.Logs) != 1 || resp.Logs[0].Message != "hello" {
+		t.Errorf("Want the log entry from the trailers, got %+v", resp.Logs)
+	}
+	if resp.Usage == nil || resp.Usage.MemoryBytes != 1024 {
+		t.Errorf("Want the usage from the trailers, got %+v", resp.Usage)
+	}
+	if resp.Headers.Get("X-Hop") != "" {
+		t.Errorf("Want the headers named by Connection removed")
+	}
+	if resp.Headers.Get("X-Custom") != "kept" {
+		t.Errorf("Want the function headers kept, got %v", resp.Headers)
+	}
+}
+
+func Test_Envelope_EmptyBody(t *testing.T) {
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		writeFunctionResponse(w, &FunctionResponse{Status: http.StatusOK, Headers: http.Header{}}, time.Now())
+	})
+
+	if resp.Status != http.StatusOK || len(resp.Body) != 0 || resp.Error != "" {
+		t.Errorf("Want an empty 200 response, got %+v", resp)
+	}
+}
+
+func Test_Envelope_NoContentCarriesOutputInHeader(t *testing.T) {
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		writeFunctionResponse(w, &FunctionResponse{
+			Status:  http.StatusNoContent,
+			Headers: http.Header{},
+			Stderr:  []string{"err"},
+		}, time.Now())
+	})
+
+	if resp.Status != http.StatusNoContent {
+		t.Errorf("Want status %d, got %d", http.StatusNoContent, resp.Status)
+	}
+	if len(resp.Stderr) != 1 || resp.Stderr[0] != "err" {
+		t.Errorf("Want stderr from the header, got %v", resp.Stderr)
+	}
+	if resp.Headers.Get(StderrTrailer) != "" {
+		t.Errorf("Want %s removed from the function headers", StderrTrailer)
+	}
+}
+
+func Test_Envelope_WatchdogError(t *testing.T) {
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, http.StatusTooManyRequests, ErrorConcurrencyLimit, "limit exceeded")
+	})
+
+	if resp.Status != http.StatusTooManyRequests || resp.Error != ErrorConcurrencyLimit {
+		t.Errorf("Want a concurrency limit error, got status %d error %q", resp.Status, resp.Error)
+	}
+}
+
+func Test_Envelope_FunctionErrorIsNotWatchdogError(t *testing.T) {
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		writeFunctionResponse(w, &FunctionResponse{
+			Status:  http.StatusTooManyRequests,
+			Headers: http.Header{},
+			Body:    []byte("slow down"),
+		}, time.Now())
+	})
+
+	if resp.Status != http.StatusTooManyRequests || resp.Error != "" {
+		t.Errorf("Want the function's own 429, got status %d error %q", resp.Status, resp.Error)
+	}
+}
+
+func Test_Envelope_DecodesLegacyResponses(t *testing.T) {
+	resp := roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"body":"aGVsbG8=","headers":{"Content-Type":["text/plain"]},"status":202,"stderr":["err"]}`))
+	})
+
+	if resp.Status != http.StatusAccepted || string(resp.Body) != "hello" || len(resp.Stderr) != 1 {
+		t.Errorf("Want the legacy envelope decoded, got %+v", resp)
+	}
+
+	resp = roundTrip(t, func(w http.ResponseWriter, r *http.Request) {})
+	if resp.Status != http.StatusOK || len(resp.Body) != 0 || resp.Error != "" {
+		t.Errorf("Want an empty legacy response decoded as an empty 200, got %+v", resp)
+	}
+
+	resp = roundTrip(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	if resp.Error != ErrorTimeout {
+		t.Errorf("Want a legacy 504 decoded as a timeout, got %q", resp.Error)
+	}
+}
@@ -3,19 +3,20 @@ package executor
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"os/exec"
-	"os/signal"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"eywa/go-libs/tracing"
 )
 
 // FunctionRunner runs a function
@@ -42,76 +43,135 @@ type HTTPFunctionRunner struct {
 	Process        string        // Process to run as fprocess
 	ProcessArgs    []string      // ProcessArgs to pass to command
 	Command        *exec.Cmd
-	Stdout         *[]string
-	Stderr         *[]string
 	Client         *http.Client
 	UpstreamURL    *url.URL
 	BufferHTTPBody bool
 	WriteDebug     bool
+	MaxLogBytes    int // MaxLogBytes caps the output returned for one request
+	MaxInflight    int // MaxInflight limits the concurrent requests, their CPU time is only reported when it is 1
+
+	ReadyPath         string        // ReadyPath polled on the upstream until it answers, a TCP connect is used when empty
+	ReadyInterval     time.Duration // ReadyInterval between two upstream readiness checks
+	InitTimeout       time.Duration // InitTimeout the maximum duration of the init hook
+	ShutdownTimeout   time.Duration // ShutdownTimeout the maximum duration of the shutdown hook
+	RestartBackoff    time.Duration // RestartBackoff the delay before restarting a crashed process
+	MaxRestartBackoff time.Duration // MaxRestartBackoff caps the delay doubled on every consecutive crash
+
+	logs     *logCapture
+	stopping int32
+	ready    int32
+	restarts uint64
+
+	mu         sync.Mutex
+	generation uint64        // generation counts the processes forked
+	exited     chan struct{} // exited is closed when the current process has exited
+	flush      func()
+	lastCrash  *ProcessCrash
 }
 
+// FunctionResponse is the response of a function as exchanged between the watchdog and the gateway
 type FunctionResponse struct {
 	Body    []byte      `json:"body,omitempty"`
 	Headers http.Header `json:"headers,omitempty"`
 	Status  int         `json:"status"`
 	Stdout  []string    `json:"stdout,omitempty"`
 	Stderr  []string    `json:"stderr,omitempty"`
+	Logs    []LogEntry  `json:"logs,omitempty"`
+
+	Usage *ResourceUsage `json:"usage,omitempty"`
+
+	// Error is the kind of error when the watchdog failed to run the function
+	Error string `json:"error,omitempty"`
 }
 
-// Start forks the process used for processing incoming requests
+// Start forks the process used for processing incoming requests and supervises it
 func (f *HTTPFunctionRunner) Start() error {
+	f.logs = newLogCapture(f.MaxLogBytes)
+	f.Client = makeProxyClient(f.ExecTimeout)
+
+	if err := f.fork(); err != nil {
+		return err
+	}
+
+	go f.supervise()
+
+	return nil
+}
+
+// fork starts a new function process
+func (f *HTTPFunctionRunner) fork() error {
 	cmd := exec.Command(f.Process, f.ProcessArgs...)
 
-	f.Stdout = &[]string{}
-	f.Stderr = &[]string{}
+	// Captures lines from stderr and stdout for the requests in flight
+	stdout, stderr := f.logs.writer("stdout"), f.logs.writer("stderr")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
 
-	f.Command = cmd
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return err
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if atomic.LoadInt32(&f.stopping) == 1 {
+		return errStopping
 	}
 
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
+	if err := cmd.Start(); err != nil {
 		return err
 	}
 
-	// Logs lines from stderr and stdout to the output buffer
-	bindLoggingPipe("stderr", stdoutPipe, f.Stdout)
-	bindLoggingPipe("stdout", stderrPipe, f.Stderr)
+	f.Command = cmd
+	f.flush = func() {
+		stdout.Flush()
+		stderr.Flush()
+	}
+	f.generation++
+	f.exited = make(chan struct{})
 
-	f.Client = makeProxyClient(f.ExecTimeout)
+	go f.awaitUpstream(f.generation, f.exited)
 
-	go func() {
-		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, syscall.SIGTERM)
+	return nil
+}
 
-		<-sig
-		err := cmd.Process.Signal(syscall.SIGTERM)
-		if err != nil {
-			log.Fatalf("Failed to process SIGTERM: %s", err)
+// Stop calls the shutdown hook of a ready function, sends SIGTERM to the function process
+// and waits up to timeout for it to exit
+func (f *HTTPFunctionRunner) Stop(timeout time.Duration) error {
+	if f.Ready() {
+		if err := f.callHook(ShutdownHookPath, f.ShutdownTimeout); err != nil {
+			log.Printf("Shutdown hook failed: %s", err)
 		}
+	}
 
-	}()
+	atomic.StoreInt32(&f.stopping, 1)
 
-	err = cmd.Start()
-	go func() {
-		err := cmd.Wait()
-		if err != nil {
-			log.Fatalf("Forked function has terminated: %s", err.Error())
-		}
-	}()
+	f.mu.Lock()
+	cmd, exited := f.Command, f.exited
+	f.mu.Unlock()
 
-	return err
+	select {
+	case <-exited:
+		// Crashed and not restarted yet
+		return nil
+	default:
+		return stopProcess(cmd, exited, timeout)
+	}
 }
 
 // Run a function with a long-running process with a HTTP protocol for communication
 func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *http.Request, w http.ResponseWriter) error {
 	startedTime := time.Now()
 
-	// Wipe any previous output
-	*f.Stderr = []string{}
-	*f.Stdout = []string{}
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "watchdog run",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if !f.Ready() {
+		writeRunError(w, http.StatusServiceUnavailable, ErrorUnavailable, "Function process is not ready", startedTime)
+		return nil
+	}
+
+	generation, pid, exited := f.current()
+
+	correlationID := newCorrelationID()
+	f.logs.begin(correlationID)
+	defer f.logs.end(correlationID)
 
 	upstreamURL := f.UpstreamURL.String()
 
@@ -131,14 +191,18 @@ func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *ht
 	request.Host = r.Host
 
 	copyHeaders(request.Header, &r.Header)
+	request.Header.Set(CorrelationHeader, correlationID)
+
+	// Forward the watchdog span as the parent of any span created by the function
+	tracing.Inject(ctx, request.Header)
 
 	var reqCtx context.Context
 	var cancel context.CancelFunc
 
 	if f.ExecTimeout.Nanoseconds() > 0 {
-		reqCtx, cancel = context.WithTimeout(r.Context(), f.ExecTimeout)
+		reqCtx, cancel = context.WithTimeout(ctx, f.ExecTimeout)
 	} else {
-		reqCtx = r.Context()
+		reqCtx = ctx
 		cancel = func() {
 
 		}
@@ -146,16 +210,21 @@ func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *ht
 
 	defer cancel()
 
+	usage := measureProcess(pid, f.MaxInflight == 1)
+
 	res, err := f.Client.Do(request.WithContext(reqCtx))
 	if err != nil {
 		log.Printf("Upstream HTTP request error: %s\n", err.Error())
+		tracing.RecordError(span, err)
 
 		// Error unrelated to context / deadline
 		if reqCtx.Err() == nil {
-			w.Header().Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
-
-			w.WriteHeader(http.StatusInternalServerError)
+			if crash := f.crashedDuring(generation, exited); crash != nil {
+				writeCrashResponse(w, crash, startedTime)
+				return nil
+			}
 
+			writeRunError(w, http.StatusInternalServerError, ErrorFunction, "Function process did not respond", startedTime)
 			return nil
 		}
 
@@ -163,20 +232,14 @@ func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *ht
 		if reqCtx.Err() != nil {
 			// Error due to timeout / deadline
 			log.Printf("Upstream HTTP killed due to exec_timeout: %s\n", f.ExecTimeout)
-			w.Header().Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
-
-			w.WriteHeader(http.StatusGatewayTimeout)
+			writeRunError(w, http.StatusGatewayTimeout, ErrorTimeout, "Function timed out", startedTime)
 			return nil
 		}
 
-		w.Header().Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeRunError(w, http.StatusInternalServerError, ErrorFunction, "Function process did not respond", startedTime)
 		return err
 	}
 
-	w.Header().Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
-	w.Header().Set("Content-Type", "application/json")
-
 	resp := FunctionResponse{
 		Status:  res.StatusCode,
 		Headers: http.Header{},
@@ -184,14 +247,6 @@ func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *ht
 
 	copyHeaders(resp.Headers, &res.Header)
 
-	if f.WriteDebug {
-		resp.Stdout = *f.Stdout
-		resp.Stderr = *f.Stderr
-
-		log.Printf("STDOUT: %v\n", resp.Stdout)
-		log.Printf("STDERR: %v\n", resp.Stderr)
-	}
-
 	if res.Body != nil {
 		defer res.Body.Close()
 
@@ -202,12 +257,18 @@ func (f *HTTPFunctionRunner) Run(req FunctionRequest, contentLength int64, r *ht
 		resp.Body = bodyBytes
 	}
 
-	if !resp.isEmpty() {
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Printf("Failed to write response")
-		}
+	resp.Usage = usage()
+
+	if f.WriteDebug {
+		logs := f.logs.end(correlationID)
+		resp.Stdout, resp.Stderr, resp.Logs = logs.stdout, logs.stderr, logs.entries
+
+		log.Printf("STDOUT: %v\n", resp.Stdout)
+		log.Printf("STDERR: %v\n", resp.Stderr)
 	}
 
+	writeFunctionResponse(w, &resp, startedTime)
+
 	return nil
 }
 
@@ -240,10 +301,3 @@ func makeProxyClient(dialTimeout time.Duration) *http.Client {
 
 	return &proxyClient
 }
-
-func (fr *FunctionResponse) isEmpty() bool {
-	if len(fr.Body) > 0 || len(fr.Stderr) > 0 || len(fr.Stdout) > 0 || len(fr.Headers) > 0 {
-		return false
-	}
-	return true
-}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	// InitHookPath is called on the upstream once it answers, before the watchdog reports ready
+	InitHookPath = "/_/init"
+	// ShutdownHookPath is called on the upstream once requests are drained, before the process is stopped
+	ShutdownHookPath = "/_/shutdown"
+)
+
+// callHook posts to the lifecycle hook of the upstream. A function not implementing
+// the hook is not an error.
+func (f *HTTPFunctionRunner) callHook(path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, f.UpstreamURL.String()+path, nil)
+
+	res, err := f.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound,
+		res.StatusCode == http.StatusMethodNotAllowed,
+		res.StatusCode == http.StatusNotImplemented:
+		return nil
+	case res.StatusCode >= http.StatusMultipleChoices:
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s responded with %d: %s", path, res.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_callHook(t *testing.T) {
+	cases := []struct {
+		status  int
+		wantErr bool
+	}{
+		{status: http.StatusNoContent},
+		{status: http.StatusNotFound},
+		{status: http.StatusMethodNotAllowed},
+		{status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, c := range cases {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != InitHookPath {
+				t.Errorf("Want POST %s, got %s %s", InitHookPath, r.Method, r.URL.Path)
+			}
+			w.WriteHeader(c.status)
+		}))
+
+		upstreamURL, _ := url.Parse(upstream.URL)
+		runner := &HTTPFunctionRunner{UpstreamURL: upstreamURL, Client: makeProxyClient(time.Second)}
+
+		err := runner.callHook(InitHookPath, time.Second)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Upstream responding %d: want error %v, got %v", c.status, c.wantErr, err)
+		}
+
+		upstream.Close()
+	}
+}
+
+func Test_callHook_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+	runner := &HTTPFunctionRunner{UpstreamURL: upstreamURL, Client: makeProxyClient(time.Second)}
+
+	if err := runner.callHook(ShutdownHookPath, 20*time.Millisecond); err == nil {
+		t.Errorf("Want an error once the hook timed out")
+	}
+}
@@ -1,25 +1,224 @@
 package executor
 
 import (
-	"bufio"
-	"io"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// bindLoggingPipe spawns a goroutine for passing through logging of the given output pipe.
-func bindLoggingPipe(name string, pipe io.Reader, output *[]string) {
-	log.Infof("Started logging %s from function.", name)
+// CorrelationHeader carries the id of a request to the function. Lines the function writes
+// to stdout or stderr prefixed with "[<id>] " are attributed to that request.
+const CorrelationHeader = "X-Eywa-Correlation-Id"
 
-	scanner := bufio.NewScanner(pipe)
+const (
+	// maxLineSize is the longest line kept, the rest of a longer line is dropped
+	maxLineSize = 64 * 1024
+	// maxRecentLines is the number of last stderr lines kept to report a crash
+	maxRecentLines = 20
+)
+
+// newCorrelationID returns a random request correlation id
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Failed to generate correlation id: %s", err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// requestLogs holds the output attributed to one request
+type requestLogs struct {
+	stdout    []string
+	stderr    []string
+	entries   []LogEntry
+	size      int
+	truncated bool
+}
+
+func (l *requestLogs) append(stream, line string, maxBytes int) {
+	if l.truncated {
+		return
+	}
+
+	if maxBytes > 0 && l.size+len(line) > maxBytes {
+		l.truncated = true
+		line = fmt.Sprintf("[watchdog] output truncated, limit of %d bytes reached", maxBytes)
+	}
+	l.size += len(line)
+
+	if entry, ok := parseLogLine(stream, line); ok {
+		l.entries = append(l.entries, entry)
+	} else if stream == "stdout" {
+		l.stdout = append(l.stdout, line)
+	} else {
+		l.stderr = append(l.stderr, line)
+	}
+}
+
+// logCapture routes the output of a long running process to the requests in flight.
+// Prefixed lines go to the request with the matching correlation id, lines without a
+// prefix go to the only request in flight and are logged by the watchdog otherwise.
+type logCapture struct {
+	mu       sync.Mutex
+	maxBytes int
+	requests map[string]*requestLogs
+	recent   []string
+}
+
+func newLogCapture(maxBytes int) *logCapture {
+	return &logCapture{
+		maxBytes: maxBytes,
+		requests: make(map[string]*requestLogs),
+	}
+}
+
+// begin starts capturing output for the request
+func (c *logCapture) begin(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests[id] = &requestLogs{}
+}
+
+// end stops capturing output for the request and returns what was captured
+func (c *logCapture) end(id string) requestLogs {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	logs, exists := c.requests[id]
+	if !exists {
+		return requestLogs{}
+	}
+	delete(c.requests, id)
+
+	return *logs
+}
+
+func (c *logCapture) write(stream, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stream == "stderr" {
+		if len(c.recent) == maxRecentLines {
+			c.recent = c.recent[1:]
+		}
+		c.recent = append(c.recent, line)
+	}
+
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "] "); end > 0 {
+			if logs, exists := c.requests[line[1:end]]; exists {
+				logs.append(stream, line[end+2:], c.maxBytes)
+				return
+			}
+		}
+	}
+
+	if len(c.requests) == 1 {
+		for _, logs := range c.requests {
+			logs.append(stream, line, c.maxBytes)
+		}
+		return
+	}
 
-	go func() {
-		for scanner.Scan() {
-			text := scanner.Text()
-			*output = append(*output, text)
+	log.Infof("%s: %s", stream, line)
+}
+
+// writer returns a writer capturing the lines written to the stream. Lines are captured
+// synchronously so everything a process printed is captured once cmd.Wait returns.
+func (c *logCapture) writer(stream string) *lineWriter {
+	log.Infof("Started logging %s from function.", stream)
+
+	return &lineWriter{
+		line: func(line string) {
+			c.write(stream, line)
+		},
+	}
+}
+
+// tail returns the last stderr lines printed by the process
+func (c *logCapture) tail() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.recent...)
+}
+
+// lineWriter splits the output written to it into lines
+type lineWriter struct {
+	mu      sync.Mutex
+	line    func(string)
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range p {
+		if b == '\n' {
+			w.emit()
+			continue
 		}
-		if err := scanner.Err(); err != nil {
-			log.Printf("Error scanning %s: %s", name, err.Error())
+
+		// The rest of a line longer than maxLineSize is dropped
+		if len(w.pending) < maxLineSize {
+			w.pending = append(w.pending, b)
 		}
-	}()
+	}
+
+	return len(p), nil
+}
+
+// Flush captures the last line when it did not end with a newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) > 0 {
+		w.emit()
+	}
+}
+
+func (w *lineWriter) emit() {
+	w.line(strings.TrimSuffix(string(w.pending), "\r"))
+	w.pending = w.pending[:0]
+}
+
+// cappedBuffer keeps the first maxBytes written to it and counts the rest
+type cappedBuffer struct {
+	maxBytes int
+	data     []byte
+	dropped  int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	keep := len(p)
+	if b.maxBytes > 0 && len(b.data)+keep > b.maxBytes {
+		keep = b.maxBytes - len(b.data)
+	}
+
+	b.data = append(b.data, p[:keep]...)
+	b.dropped += len(p) - keep
+
+	return len(p), nil
+}
+
+// logs returns the captured output of the stream split into lines
+func (b *cappedBuffer) logs(stream string) requestLogs {
+	logs := requestLogs{}
+	for _, line := range splitLines(b.data) {
+		logs.append(stream, line, 0)
+	}
+
+	if b.dropped > 0 {
+		logs.append(stream, fmt.Sprintf("[watchdog] output truncated, limit of %d bytes reached", b.maxBytes), 0)
+	}
+
+	return logs
 }
@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_logCapture_RoutesPrefixedLines(t *testing.T) {
+	capture := newLogCapture(0)
+	capture.begin("a")
+	capture.begin("b")
+
+	capture.write("stdout", "[a] first")
+	capture.write("stderr", "[b] second")
+	capture.write("stdout", "unattributed")
+
+	logs := capture.end("a")
+	stdout, stderr := logs.stdout, logs.stderr
+	if len(stdout) != 1 || stdout[0] != "first" || len(stderr) != 0 {
+		t.Errorf("Want stdout [first] for a, got stdout %v stderr %v", stdout, stderr)
+	}
+
+	logs = capture.end("b")
+	stdout, stderr = logs.stdout, logs.stderr
+	if len(stdout) != 0 || len(stderr) != 1 || stderr[0] != "second" {
+		t.Errorf("Want stderr [second] for b, got stdout %v stderr %v", stdout, stderr)
+	}
+}
+
+func Test_logCapture_SingleRequestGetsUnprefixedLines(t *testing.T) {
+	capture := newLogCapture(0)
+	capture.begin("a")
+
+	capture.write("stdout", "plain line")
+
+	stdout := capture.end("a").stdout
+	if len(stdout) != 1 || stdout[0] != "plain line" {
+		t.Errorf("Want stdout [plain line], got %v", stdout)
+	}
+}
+
+func Test_logCapture_SizeCap(t *testing.T) {
+	capture := newLogCapture(8)
+	capture.begin("a")
+
+	capture.write("stdout", "[a] 12345")
+	capture.write("stdout", "[a] 67890")
+	capture.write("stdout", "[a] dropped")
+
+	stdout := capture.end("a").stdout
+	if len(stdout) != 2 || stdout[0] != "12345" || !strings.Contains(stdout[1], "truncated") {
+		t.Errorf("Want one line and a truncation notice, got %v", stdout)
+	}
+}
+
+func Test_logCapture_ConcurrentRequests(t *testing.T) {
+	capture := newLogCapture(0)
+	writer := capture.writer("stdout")
+
+	var wg sync.WaitGroup
+	results := make([][]string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := newCorrelationID()
+			capture.begin(id)
+			for j := 0; j < 5; j++ {
+				fmt.Fprintf(writer, "[%s] request %d line %d\n", id, i, j)
+			}
+
+			results[i] = capture.end(id).stdout
+		}(i)
+	}
+	wg.Wait()
+
+	for i, lines := range results {
+		if len(lines) != 5 {
+			t.Fatalf("Want 5 lines for request %d, got %v", i, lines)
+		}
+		for j, line := range lines {
+			want := fmt.Sprintf("request %d line %d", i, j)
+			if line != want {
+				t.Errorf("Want %q, got %q", want, line)
+			}
+		}
+	}
+}
+
+func Test_cappedBuffer_DropsOverflow(t *testing.T) {
+	buffer := &cappedBuffer{maxBytes: 4}
+	fmt.Fprint(buffer, "ab\ncdef\n")
+
+	lines := buffer.logs("stderr").stderr
+	if len(lines) != 3 || lines[0] != "ab" || lines[1] != "c" || !strings.Contains(lines[2], "truncated") {
+		t.Errorf("Want [ab c <truncated>], got %v", lines)
+	}
+}
+
+func Test_logCapture_ParsesStructuredLines(t *testing.T) {
+	capture := newLogCapture(0)
+	capture.begin("a")
+
+	capture.write("stderr", `[a] {"level":"WARNING","msg":"slow query","duration_ms":120}`)
+	capture.write("stderr", `[a] {"msg":"no level"}`)
+
+	logs := capture.end("a")
+	if len(logs.entries) != 1 {
+		t.Fatalf("Want 1 structured entry, got %v", logs.entries)
+	}
+
+	entry := logs.entries[0]
+	if entry.Level != "warn" || entry.Message != "slow query" || entry.Stream != "stderr" || entry.Fields["duration_ms"] != float64(120) {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+
+	if len(logs.stderr) != 1 || logs.stderr[0] != `{"msg":"no level"}` {
+		t.Errorf("Want the line without a level kept as plain text, got %v", logs.stderr)
+	}
+}
+
+func Test_logCapture_KeepsLastStderrLines(t *testing.T) {
+	capture := newLogCapture(0)
+	writer := capture.writer("stderr")
+
+	for i := 0; i < maxRecentLines+5; i++ {
+		fmt.Fprintf(writer, "line %d\n", i)
+	}
+	fmt.Fprint(writer, "partial")
+	writer.Flush()
+
+	tail := capture.tail()
+	if len(tail) != maxRecentLines || tail[len(tail)-1] != "partial" || tail[0] != "line 6" {
+		t.Errorf("Want the last %d lines, got %v", maxRecentLines, tail)
+	}
+}
@@ -0,0 +1,23 @@
+package executor
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// stopProcess sends SIGTERM to the process and kills it when it has not exited within timeout
+func stopProcess(cmd *exec.Cmd, exited <-chan struct{}, timeout time.Duration) error {
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(timeout):
+		log.Printf("Forked function did not exit within %s, killing it\n", timeout)
+		return cmd.Process.Kill()
+	}
+}
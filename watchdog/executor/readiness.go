@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readyCheckTimeout bounds a single upstream readiness check
+const readyCheckTimeout = time.Second
+
+// awaitUpstream polls the upstream of the process of the generation until it answers, calls
+// its init hook and marks the runner ready. It gives up once the process has exited.
+func (f *HTTPFunctionRunner) awaitUpstream(generation uint64, exited <-chan struct{}) {
+	startedAt := time.Now()
+
+	ticker := time.NewTicker(f.ReadyInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.checkUpstream(); err == nil {
+			break
+		}
+
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+	}
+
+	if err := f.callHook(InitHookPath, f.InitTimeout); err != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		// The supervisor restarts the process
+		if f.generation == generation {
+			log.Printf("Init hook failed: %s, killing the function process", err)
+			f.Command.Process.Kill()
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.generation == generation {
+		log.Printf("Upstream ready after %s", time.Since(startedAt))
+		atomic.StoreInt32(&f.ready, 1)
+	}
+}
+
+// checkUpstream returns an error unless the upstream answers the readiness check. Any response
+// below 500 counts as an answer so the function does not need a dedicated path.
+func (f *HTTPFunctionRunner) checkUpstream() error {
+	if len(f.ReadyPath) == 0 {
+		conn, err := net.DialTimeout("tcp", f.UpstreamURL.Host, readyCheckTimeout)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	client := http.Client{Timeout: readyCheckTimeout}
+	res, err := client.Get(f.UpstreamURL.String() + f.ReadyPath)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errNotReady
+	}
+
+	return nil
+}
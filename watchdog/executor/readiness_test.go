@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func Test_checkUpstream_Path(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			t.Errorf("Want the readiness path polled, got %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	defer upstream.Close()
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+	runner := &HTTPFunctionRunner{UpstreamURL: upstreamURL, ReadyPath: "/ready"}
+
+	if err := runner.checkUpstream(); err == nil {
+		t.Errorf("Want not ready while the upstream responds %d", status)
+	}
+
+	status = http.StatusNotFound
+	if err := runner.checkUpstream(); err != nil {
+		t.Errorf("Want ready once the upstream answers, got %s", err)
+	}
+}
+
+func Test_checkUpstream_TCP(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstreamURL, _ := url.Parse(upstream.URL)
+	runner := &HTTPFunctionRunner{UpstreamURL: upstreamURL}
+
+	if err := runner.checkUpstream(); err != nil {
+		t.Errorf("Want ready while the upstream listens, got %s", err)
+	}
+
+	upstream.Close()
+	if err := runner.checkUpstream(); err == nil {
+		t.Errorf("Want not ready once the upstream stopped listening")
+	}
+}
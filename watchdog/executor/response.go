@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"eywa/go-libs/tracing"
+)
+
+// startRunSpan starts the server span of a function run as a child of the caller's trace
+func startRunSpan(r *http.Request) (context.Context, trace.Span) {
+	return tracing.Start(tracing.Extract(r.Context(), r.Header), "watchdog run",
+		trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// withExecTimeout bounds ctx by timeout, a zero timeout never expires
+func withExecTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout.Nanoseconds() > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// processEnvironment returns the environment of a forked process. With injectCGI the
+// request metadata and headers are added as Http_ prefixed variables.
+func processEnvironment(ctx context.Context, req FunctionRequest, r *http.Request, injectCGI bool) []string {
+	env := append(os.Environ(), req.Environment...)
+	if !injectCGI {
+		return env
+	}
+
+	headers := http.Header{}
+	copyHeaders(headers, &r.Header)
+
+	// Forward the watchdog span as the parent of any span created by the function
+	tracing.Inject(ctx, headers)
+
+	for k, v := range headers {
+		if len(v) > 0 {
+			env = append(env, fmt.Sprintf("Http_%s=%s", strings.Replace(k, "-", "_", -1), v[0]))
+		}
+	}
+
+	env = append(env,
+		fmt.Sprintf("Http_Method=%s", r.Method),
+		fmt.Sprintf("Http_Path=%s", r.URL.Path),
+		fmt.Sprintf("Http_Query=%s", r.URL.RawQuery),
+		fmt.Sprintf("Http_Host=%s", r.Host),
+	)
+
+	if r.ContentLength > 0 {
+		env = append(env, fmt.Sprintf("Http_ContentLength=%d", r.ContentLength))
+	}
+
+	if len(r.TransferEncoding) > 0 {
+		env = append(env, fmt.Sprintf("Http_Transfer_Encoding=%s", r.TransferEncoding[0]))
+	}
+
+	return env
+}
+
+// exitStatus maps the result of a forked process to the status of the function response
+func exitStatus(ctx context.Context, err error) int {
+	if ctx.Err() == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusOK
+}
+
+// splitLines splits captured process output into lines
+func splitLines(output []byte) []string {
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runFork(t *testing.T, runner interface {
+	Run(FunctionRequest, *http.Request, http.ResponseWriter) error
+}, process string, args []string, body string) (*httptest.ResponseRecorder, FunctionResponse) {
+	r := httptest.NewRequest(http.MethodPost, "/path?q=1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	req := FunctionRequest{
+		Process:     process,
+		ProcessArgs: args,
+		InputReader: r.Body,
+	}
+
+	if err := runner.Run(req, r, w); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+
+	resp, err := DecodeFunctionResponse(w.Result(), w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decode response %q: %s", w.Body.String(), err)
+	}
+
+	return w, *resp
+}
+
+func Test_StreamingFunctionRunner_EchoesStdin(t *testing.T) {
+	runner := &StreamingFunctionRunner{ContentType: "text/plain"}
+
+	_, resp := runFork(t, runner, "cat", nil, "hello world")
+
+	if string(resp.Body) != "hello world" {
+		t.Errorf("Want body %q, got %q", "hello world", resp.Body)
+	}
+	if resp.Status != http.StatusOK {
+		t.Errorf("Want status %d, got %d", http.StatusOK, resp.Status)
+	}
+	if resp.Headers.Get("Content-Type") != "text/plain" {
+		t.Errorf("Want content type %q, got %q", "text/plain", resp.Headers.Get("Content-Type"))
+	}
+	if runtime.GOOS == "linux" && (resp.Usage == nil || resp.Usage.MemoryBytes == 0) {
+		t.Errorf("Want the process usage reported, got %+v", resp.Usage)
+	}
+}
+
+func Test_StreamingFunctionRunner_NonZeroExit(t *testing.T) {
+	runner := &StreamingFunctionRunner{WriteDebug: true}
+
+	_, resp := runFork(t, runner, "sh", []string{"-c", "echo out; echo err >&2; exit 3"}, "")
+
+	if resp.Status != http.StatusInternalServerError {
+		t.Errorf("Want status %d, got %d", http.StatusInternalServerError, resp.Status)
+	}
+	if string(resp.Body) != "out\n" {
+		t.Errorf("Want body %q, got %q", "out\n", resp.Body)
+	}
+	if len(resp.Stderr) != 1 || resp.Stderr[0] != "err" {
+		t.Errorf("Want stderr [err], got %v", resp.Stderr)
+	}
+}
+
+func Test_StreamingFunctionRunner_ExecTimeout(t *testing.T) {
+	runner := &StreamingFunctionRunner{ExecTimeout: 100 * time.Millisecond}
+
+	_, resp := runFork(t, runner, "sleep", []string{"5"}, "")
+
+	if resp.Status != http.StatusGatewayTimeout {
+		t.Errorf("Want status %d, got %d", http.StatusGatewayTimeout, resp.Status)
+	}
+}
+
+func Test_SerializingFunctionRunner_InjectsCGIHeaders(t *testing.T) {
+	runner := &SerializingFunctionRunner{InjectCGIHeaders: true}
+
+	_, resp := runFork(t, runner, "sh", []string{"-c", `printf "%s %s %s" "$Http_Method" "$Http_Path" "$Http_Query"`}, "")
+
+	want := "POST /path q=1"
+	if string(resp.Body) != want {
+		t.Errorf("Want body %q, got %q", want, resp.Body)
+	}
+}
+
+func Test_SerializingFunctionRunner_ExecTimeout(t *testing.T) {
+	runner := &SerializingFunctionRunner{ExecTimeout: 100 * time.Millisecond}
+
+	w, _ := runFork(t, runner, "sleep", []string{"5"}, "")
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Want status %d, got %d", http.StatusGatewayTimeout, w.Code)
+	}
+}
+
+func Test_StreamingFunctionRunner_OutputBeforeReadingStdin(t *testing.T) {
+	runner := &StreamingFunctionRunner{MaxLogBytes: 1024}
+
+	_, resp := runFork(t, runner, "sh", []string{"-c", "head -c 100000 /dev/zero; cat"}, "tail")
+
+	if len(resp.Body) != 100004 || !strings.HasSuffix(string(resp.Body), "tail") {
+		t.Errorf("Want the output and the echoed body, got %d bytes", len(resp.Body))
+	}
+}
+
+func Test_streamWriter_CapsPendingOutput(t *testing.T) {
+	w := httptest.NewRecorder()
+	s := newStreamWriter(w, 4)
+
+	written := make(chan int)
+	go func() {
+		n, _ := s.Write([]byte("0123456789"))
+		written <- n
+	}()
+
+	select {
+	case n := <-written:
+		t.Fatalf("Want the write to block once 4 bytes are pending, wrote %d", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.mu.Lock()
+	pending := s.pending.Len()
+	s.mu.Unlock()
+	if pending != 4 {
+		t.Errorf("Want 4 bytes pending, got %d", pending)
+	}
+
+	s.goLive()
+	if n := <-written; n != 10 {
+		t.Errorf("Want 10 bytes written, got %d", n)
+	}
+	if w.Body.String() != "0123456789" {
+		t.Errorf("Want the output in order, got %q", w.Body.String())
+	}
+}
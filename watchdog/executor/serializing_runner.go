@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"eywa/go-libs/tracing"
+)
+
+// SerializingFunctionRunner forks a process per request and buffers its output before responding
+type SerializingFunctionRunner struct {
+	ExecTimeout      time.Duration // ExecTimeout the maximum duration of a process
+	ContentType      string        // ContentType of the process output
+	InjectCGIHeaders bool
+	WriteDebug       bool
+	MaxLogBytes      int // MaxLogBytes caps the output returned for one request
+}
+
+// Run forks the process with the whole request body on stdin and responds with its stdout
+func (f *SerializingFunctionRunner) Run(req FunctionRequest, r *http.Request, w http.ResponseWriter) error {
+	startedTime := time.Now()
+
+	ctx, span := startRunSpan(r)
+	defer span.End()
+
+	var input []byte
+	if req.InputReader != nil {
+		var err error
+		input, err = ioutil.ReadAll(req.InputReader)
+		if err != nil {
+			return err
+		}
+	}
+
+	execCtx, cancel := withExecTimeout(ctx, f.ExecTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	stderr := &cappedBuffer{maxBytes: f.MaxLogBytes}
+	cmd := exec.CommandContext(execCtx, req.Process, req.ProcessArgs...)
+	cmd.Env = processEnvironment(ctx, req, r, f.InjectCGIHeaders)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if execCtx.Err() == context.DeadlineExceeded {
+		log.Printf("Function process killed due to exec_timeout: %s\n", f.ExecTimeout)
+		tracing.RecordError(span, execCtx.Err())
+
+		writeRunError(w, http.StatusGatewayTimeout, ErrorTimeout, "Function timed out", startedTime)
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	status := exitStatus(execCtx, err)
+	if err != nil {
+		log.Printf("Function process exited: %s\n", err)
+		tracing.RecordError(span, err)
+	}
+
+	resp := FunctionResponse{
+		Status:  status,
+		Headers: http.Header{"Content-Type": []string{f.ContentType}},
+		Body:    stdout.Bytes(),
+		Usage:   processUsage(cmd.ProcessState),
+	}
+
+	if f.WriteDebug {
+		logs := stderr.logs("stderr")
+		resp.Stderr, resp.Logs = logs.stderr, logs.entries
+
+		log.Printf("STDERR: %v\n", resp.Stderr)
+	}
+
+	writeFunctionResponse(w, &resp, startedTime)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// StaticFunctionRunner serves files from a directory
+type StaticFunctionRunner struct {
+	root string
+}
+
+// NewStaticFunctionRunner returns a runner serving files from root
+func NewStaticFunctionRunner(root string) *StaticFunctionRunner {
+	return &StaticFunctionRunner{
+		root: root,
+	}
+}
+
+// Run responds with the file at the request path, a directory responds with its index.html.
+// Files are streamed from disk, range and conditional requests are answered by http.ServeContent.
+func (f *StaticFunctionRunner) Run(_ FunctionRequest, r *http.Request, w http.ResponseWriter) error {
+	startedTime := time.Now()
+
+	_, span := startRunSpan(r)
+	defer span.End()
+
+	file, info, err := f.open(r.URL.Path)
+	if err != nil {
+		status := staticErrorStatus(err)
+		writeFunctionResponse(w, &FunctionResponse{
+			Status:  status,
+			Headers: http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			Body:    []byte(http.StatusText(status) + "\n"),
+		}, startedTime)
+		return nil
+	}
+	defer file.Close()
+
+	header := w.Header()
+	header.Set(EnvelopeHeader, EnvelopeVersion)
+	header.Set("X-Duration-Seconds", fmt.Sprintf("%f", time.Since(startedTime).Seconds()))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+
+	return nil
+}
+
+// open opens the file at the URL path below the root, the path can not leave the root
+func (f *StaticFunctionRunner) open(urlPath string) (*os.File, os.FileInfo, error) {
+	name := filepath.Join(f.root, filepath.FromSlash(path.Clean("/"+urlPath)))
+
+	file, info, err := openFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		file.Close()
+		return openIndex(name)
+	}
+
+	return file, info, nil
+}
+
+func openIndex(dir string) (*os.File, os.FileInfo, error) {
+	file, info, err := openFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, os.ErrNotExist
+	}
+
+	return file, info, nil
+}
+
+func openFile(name string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
+
+func staticErrorStatus(err error) int {
+	switch {
+	case os.IsNotExist(err), errors.Is(err, syscall.ENOTDIR):
+		return http.StatusNotFound
+	case os.IsPermission(err):
+		return http.StatusForbidden
+	}
+
+	return http.StatusInternalServerError
+}
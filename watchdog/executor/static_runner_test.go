@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_StaticFunctionRunner_ServesFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "static")
+	if err != nil {
+		t.Fatalf("Failed to create root: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"index.html":      "<h1>home</h1>",
+		"docs/index.html": "<h1>docs</h1>",
+		"data.txt":        "0123456789",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %s", err)
+	}
+
+	runner := NewStaticFunctionRunner(root)
+
+	tests := []struct {
+		path   string
+		ranges string
+		status int
+		body   string
+	}{
+		{path: "/", status: http.StatusOK, body: "<h1>home</h1>"},
+		{path: "/docs/", status: http.StatusOK, body: "<h1>docs</h1>"},
+		{path: "/data.txt", status: http.StatusOK, body: "0123456789"},
+		{path: "/data.txt", ranges: "bytes=2-4", status: http.StatusPartialContent, body: "234"},
+		{path: "/missing.txt", status: http.StatusNotFound},
+		{path: "/data.txt/child", status: http.StatusNotFound},
+		{path: "/empty", status: http.StatusNotFound},
+		{path: "/../../etc/passwd", status: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = test.path
+		if test.ranges != "" {
+			r.Header.Set("Range", test.ranges)
+		}
+		w := httptest.NewRecorder()
+
+		if err := runner.Run(FunctionRequest{}, r, w); err != nil {
+			t.Fatalf("Run returned error: %s", err)
+		}
+
+		resp, err := DecodeFunctionResponse(w.Result(), w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("Failed to decode response: %s", err)
+		}
+
+		if resp.Status != test.status {
+			t.Errorf("%s: want status %d, got %d", test.path, test.status, resp.Status)
+		}
+		if test.body != "" && string(resp.Body) != test.body {
+			t.Errorf("%s: want body %q, got %q", test.path, test.body, resp.Body)
+		}
+	}
+}
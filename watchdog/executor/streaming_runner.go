@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"eywa/go-libs/tracing"
+)
+
+// StreamingFunctionRunner forks a process per request, pipes the request body to its stdin
+// and streams its stdout to the caller as it is printed
+type StreamingFunctionRunner struct {
+	ExecTimeout      time.Duration // ExecTimeout the maximum duration of a process
+	ContentType      string        // ContentType of the process output
+	InjectCGIHeaders bool
+	WriteDebug       bool
+	MaxLogBytes      int // MaxLogBytes caps the output returned for one request
+}
+
+// Run forks the process and streams its output. The response is sent with status 200 while
+// the process runs, the status of the function is sent in StatusTrailer once it has exited.
+// An error is returned only when nothing was written to w yet.
+func (f *StreamingFunctionRunner) Run(req FunctionRequest, r *http.Request, w http.ResponseWriter) error {
+	ctx, span := startRunSpan(r)
+	defer span.End()
+
+	execCtx, cancel := withExecTimeout(ctx, f.ExecTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, req.Process, req.ProcessArgs...)
+	cmd.Env = processEnvironment(ctx, req, r, f.InjectCGIHeaders)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr := &cappedBuffer{maxBytes: f.MaxLogBytes}
+	cmd.Stderr = stderr
+
+	out := newStreamWriter(w, f.MaxLogBytes)
+	cmd.Stdout = out
+
+	if err := cmd.Start(); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", f.ContentType)
+	header.Set(EnvelopeHeader, EnvelopeVersion)
+	declareTrailers(header)
+	w.WriteHeader(http.StatusOK)
+
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		defer out.goLive()
+
+		if req.InputReader != nil {
+			if _, err := io.Copy(stdin, req.InputReader); err != nil {
+				log.Printf("Failed to write request body to stdin: %s", err)
+			}
+		}
+		stdin.Close()
+	}()
+
+	waitErr := cmd.Wait()
+	<-inputDone
+
+	status := exitStatus(execCtx, waitErr)
+	if waitErr != nil {
+		log.Printf("Function process exited: %s, status: %d\n", waitErr, status)
+		tracing.RecordError(span, waitErr)
+	}
+
+	resp := FunctionResponse{
+		Status: status,
+		Usage:  processUsage(cmd.ProcessState),
+	}
+
+	if f.WriteDebug {
+		logs := stderr.logs("stderr")
+		resp.Stderr, resp.Logs = logs.stderr, logs.entries
+
+		log.Printf("STDERR: %v\n", resp.Stderr)
+	}
+
+	header.Set(StatusTrailer, strconv.Itoa(resp.Status))
+	setTrailers(header, &resp)
+
+	return nil
+}
+
+// streamWriter holds the output back until the request body was consumed as the HTTP/1
+// server discards the unread request body once the response is flushed, then it flushes
+// every write to the caller. Once maxPending bytes are held back writes block until then.
+type streamWriter struct {
+	mu         sync.Mutex
+	live       *sync.Cond
+	w          http.ResponseWriter
+	pending    bytes.Buffer
+	maxPending int
+	isLive     bool
+}
+
+func newStreamWriter(w http.ResponseWriter, maxPending int) *streamWriter {
+	s := &streamWriter{w: w, maxPending: maxPending}
+	s.live = sync.NewCond(&s.mu)
+
+	return s
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	held := 0
+	for !s.isLive {
+		room := len(p) - held
+		if s.maxPending > 0 && s.maxPending-s.pending.Len() < room {
+			room = s.maxPending - s.pending.Len()
+		}
+
+		s.pending.Write(p[held : held+room])
+		held += room
+		if held == len(p) {
+			return held, nil
+		}
+
+		s.live.Wait()
+	}
+
+	n, err := s.w.Write(p[held:])
+	s.flush()
+
+	return held + n, err
+}
+
+func (s *streamWriter) goLive() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.isLive = true
+	s.live.Broadcast()
+	if _, err := s.pending.WriteTo(s.w); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+	s.flush()
+}
+
+func (s *streamWriter) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// LogEntry is a structured line printed by a function. A line is structured when it is a
+// JSON object with string "level" and "msg" keys, other keys are kept as fields.
+type LogEntry struct {
+	Stream  string                 `json:"stream"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"msg"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// logLevels maps accepted level names to the level stored
+var logLevels = map[string]string{
+	"trace":    "debug",
+	"debug":    "debug",
+	"info":     "info",
+	"warn":     "warn",
+	"warning":  "warn",
+	"err":      "error",
+	"error":    "error",
+	"fatal":    "fatal",
+	"panic":    "fatal",
+	"critical": "fatal",
+}
+
+// parseLogLine parses a structured log line, plain text lines are not parsed
+func parseLogLine(stream, line string) (LogEntry, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return LogEntry{}, false
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return LogEntry{}, false
+	}
+
+	rawLevel, ok := fields["level"].(string)
+	if !ok {
+		return LogEntry{}, false
+	}
+
+	level, ok := logLevels[strings.ToLower(rawLevel)]
+	if !ok {
+		return LogEntry{}, false
+	}
+
+	message, ok := fields["msg"].(string)
+	if !ok {
+		return LogEntry{}, false
+	}
+
+	delete(fields, "level")
+	delete(fields, "msg")
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	return LogEntry{
+		Stream:  stream,
+		Level:   level,
+		Message: message,
+		Fields:  fields,
+	}, true
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// crashWait is how long a failed upstream request waits for the process exit to be observed
+const crashWait = time.Second
+
+var (
+	errStopping = errors.New("function runner is stopping")
+	errNotReady = errors.New("upstream is not ready")
+)
+
+// ProcessCrash describes why the function process exited
+type ProcessCrash struct {
+	ExitCode int       `json:"exit_code"`
+	Reason   string    `json:"reason"`
+	Stderr   []string  `json:"stderr,omitempty"`
+	At       time.Time `json:"at"`
+
+	generation uint64
+}
+
+// Ready reports whether the function process is running and accepting requests
+func (f *HTTPFunctionRunner) Ready() bool {
+	return atomic.LoadInt32(&f.ready) == 1
+}
+
+// Restarts returns the number of times a crashed function process was restarted
+func (f *HTTPFunctionRunner) Restarts() uint64 {
+	return atomic.LoadUint64(&f.restarts)
+}
+
+// LastCrash returns how the function process last crashed, nil when it never did
+func (f *HTTPFunctionRunner) LastCrash() *ProcessCrash {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.lastCrash
+}
+
+// current returns the generation and pid of the running process and the channel closed on its exit
+func (f *HTTPFunctionRunner) current() (uint64, int, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.generation, f.Command.Process.Pid, f.exited
+}
+
+// supervise waits for the function process to exit and restarts it with an exponential
+// backoff until the runner is stopped. The backoff is reset once a process stayed up
+// for longer than MaxRestartBackoff.
+func (f *HTTPFunctionRunner) supervise() {
+	backoff := f.RestartBackoff
+
+	for {
+		f.mu.Lock()
+		cmd, exited, flush, generation := f.Command, f.exited, f.flush, f.generation
+		f.mu.Unlock()
+
+		startedAt := time.Now()
+		err := cmd.Wait()
+		atomic.StoreInt32(&f.ready, 0)
+		flush()
+
+		if atomic.LoadInt32(&f.stopping) == 1 {
+			close(exited)
+			log.Printf("Forked function has exited: %v", err)
+			return
+		}
+
+		crash := &ProcessCrash{
+			ExitCode:   cmd.ProcessState.ExitCode(),
+			Reason:     exitReason(err),
+			Stderr:     f.logs.tail(),
+			At:         time.Now(),
+			generation: generation,
+		}
+
+		f.mu.Lock()
+		f.lastCrash = crash
+		f.mu.Unlock()
+		close(exited)
+
+		if time.Since(startedAt) > f.MaxRestartBackoff {
+			backoff = f.RestartBackoff
+		}
+
+		log.Printf("Forked function has terminated: %s, restarting in %s", crash.Reason, backoff)
+		for {
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff, f.MaxRestartBackoff)
+
+			err := f.fork()
+			if err == errStopping {
+				return
+			}
+			if err == nil {
+				break
+			}
+
+			log.Printf("Failed to restart forked function: %s, retrying in %s", err, backoff)
+		}
+
+		atomic.AddUint64(&f.restarts, 1)
+	}
+}
+
+// crashedDuring waits for the process of the generation to exit and returns the crash
+// when it did, nil when the process is still running
+func (f *HTTPFunctionRunner) crashedDuring(generation uint64, exited <-chan struct{}) *ProcessCrash {
+	select {
+	case <-exited:
+	case <-time.After(crashWait):
+		return nil
+	}
+
+	crash := f.LastCrash()
+	if crash == nil || crash.generation != generation {
+		return nil
+	}
+
+	return crash
+}
+
+func nextBackoff(backoff, max time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > max {
+		return max
+	}
+
+	return backoff
+}
+
+func exitReason(err error) string {
+	if err == nil {
+		return "exited with status 0"
+	}
+
+	return err.Error()
+}
+
+// writeCrashResponse reports the crash to the request that was in flight when it happened
+func writeCrashResponse(w http.ResponseWriter, crash *ProcessCrash, startedTime time.Time) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": "Function process crashed",
+		"crash": crash,
+	})
+
+	writeFunctionResponse(w, &FunctionResponse{
+		Status:  http.StatusBadGateway,
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+		Body:    body,
+		Stderr:  crash.Stderr,
+	}, startedTime)
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_HTTPFunctionRunner_RestartsCrashedProcess(t *testing.T) {
+	runner := &HTTPFunctionRunner{
+		Process:           "sh",
+		ProcessArgs:       []string{"-c", "echo boom >&2; exit 3"},
+		UpstreamURL:       &url.URL{Scheme: "http", Host: "127.0.0.1:1"},
+		ReadyInterval:     10 * time.Millisecond,
+		RestartBackoff:    10 * time.Millisecond,
+		MaxRestartBackoff: 20 * time.Millisecond,
+	}
+
+	if err := runner.Start(); err != nil {
+		t.Fatalf("Failed to start: %s", err)
+	}
+	defer runner.Stop(time.Second)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runner.Restarts() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Want the process restarted twice, got %d restarts", runner.Restarts())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	crash := runner.LastCrash()
+	if crash == nil || crash.ExitCode != 3 {
+		t.Fatalf("Want exit code 3, got %+v", crash)
+	}
+	if len(crash.Stderr) == 0 || crash.Stderr[len(crash.Stderr)-1] != "boom" {
+		t.Errorf("Want the last stderr line reported, got %v", crash.Stderr)
+	}
+}
+
+func Test_nextBackoff_IsCapped(t *testing.T) {
+	if got := nextBackoff(time.Second, 30*time.Second); got != 2*time.Second {
+		t.Errorf("Want 2s, got %s", got)
+	}
+	if got := nextBackoff(20*time.Second, 30*time.Second); got != 30*time.Second {
+		t.Errorf("Want 30s, got %s", got)
+	}
+}
@@ -0,0 +1,51 @@
+package executor
+
+// ResourceUsage is the CPU time and peak memory the function process used to serve a request.
+// For a long running process MemoryBytes is its peak resident size since the request started
+// when it serves one request at a time and since it started otherwise. CPUSeconds is nil when
+// the process serves requests concurrently, its CPU time can not be attributed to one of them.
+type ResourceUsage struct {
+	CPUSeconds  *float64 `json:"cpu_seconds,omitempty"`
+	MemoryBytes uint64   `json:"memory_bytes"`
+}
+
+// processSample is the CPU time and peak resident size of a running process at one point in time
+type processSample struct {
+	cpuSeconds float64
+	peakBytes  uint64
+}
+
+// usageSince returns the usage between the start sample and this one, the CPU time is only
+// returned for a process serving the request exclusively
+func (s processSample) usageSince(start processSample, exclusive bool) *ResourceUsage {
+	usage := &ResourceUsage{MemoryBytes: s.peakBytes}
+	if exclusive {
+		cpuSeconds := s.cpuSeconds - start.cpuSeconds
+		usage.CPUSeconds = &cpuSeconds
+	}
+
+	return usage
+}
+
+// measureProcess samples the process and returns a func measuring its usage since.
+// When the process serves the request exclusively its peak resident size is reset first.
+// The func returns nil when the process could not be sampled.
+func measureProcess(pid int, exclusive bool) func() *ResourceUsage {
+	if exclusive {
+		resetPeak(pid)
+	}
+
+	start, err := sampleProcess(pid)
+	if err != nil {
+		return func() *ResourceUsage { return nil }
+	}
+
+	return func() *ResourceUsage {
+		end, err := sampleProcess(pid)
+		if err != nil {
+			return nil
+		}
+
+		return end.usageSince(start, exclusive)
+	}
+}
@@ -0,0 +1,137 @@
+//go:build linux
+// +build linux
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// clockTicks is the USER_HZ /proc reports CPU time in, 100 on every architecture Linux runs on
+const clockTicks = 100
+
+// processUsage returns the CPU time and peak memory of an exited process
+func processUsage(state *os.ProcessState) *ResourceUsage {
+	if state == nil {
+		return nil
+	}
+
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return nil
+	}
+
+	cpuSeconds := (time.Duration(rusage.Utime.Nano()) + time.Duration(rusage.Stime.Nano())).Seconds()
+
+	return &ResourceUsage{
+		CPUSeconds: &cpuSeconds,
+		// Maxrss is in kilobytes
+		MemoryBytes: uint64(rusage.Maxrss) * 1024,
+	}
+}
+
+// cgroupPeakFiles hold the peak memory usage of the cgroup of the watchdog, cgroup v1 and v2
+var cgroupPeakFiles = []string{
+	"/sys/fs/cgroup/memory/memory.max_usage_in_bytes",
+	"/sys/fs/cgroup/memory.peak",
+}
+
+// sampleProcess reads the CPU time and peak resident size of a running process from /proc.
+// The peak falls back to the one of the cgroup when the process status has none.
+func sampleProcess(pid int) (processSample, error) {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return processSample{}, err
+	}
+
+	// The command name in parentheses may contain spaces, fields are indexed after it
+	// starting with the state, the 3rd field of proc(5)
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return processSample{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 13 {
+		return processSample{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return processSample{}, err
+	}
+
+	peak, err := processPeak(pid)
+	if err != nil {
+		return processSample{}, err
+	}
+
+	return processSample{
+		cpuSeconds: float64(utime+stime) / clockTicks,
+		peakBytes:  peak,
+	}, nil
+}
+
+// processPeak returns the peak resident size of the process, the VmHWM of its status
+func processPeak(pid int) (uint64, error) {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "VmHWM:") {
+			continue
+		}
+
+		// The size is in kilobytes, e.g. "VmHWM:     1234 kB"
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return kb * 1024, nil
+	}
+
+	return cgroupPeak()
+}
+
+// cgroupPeak returns the peak memory usage of the cgroup of the watchdog and the function
+func cgroupPeak() (uint64, error) {
+	for _, file := range cgroupPeakFiles {
+		content, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	}
+
+	return 0, fmt.Errorf("no peak memory usage found")
+}
+
+// resetPeak resets the peak resident size of the process to its current resident size, see
+// clear_refs in proc(5). The cgroup peak can not be reset, failures leave the peak as it is.
+func resetPeak(pid int) {
+	ioutil.WriteFile(fmt.Sprintf("/proc/%d/clear_refs", pid), []byte("5"), 0)
+}
@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func Test_sampleProcess(t *testing.T) {
+	sample, err := sampleProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("Failed to sample the test process: %s", err)
+	}
+
+	if sample.peakBytes == 0 {
+		t.Errorf("Want a peak resident size, got %+v", sample)
+	}
+}
+
+func Test_processUsage(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run: %s", err)
+	}
+
+	usage := processUsage(cmd.ProcessState)
+	if usage == nil || usage.CPUSeconds == nil || *usage.CPUSeconds <= 0 || usage.MemoryBytes == 0 {
+		t.Errorf("Want CPU time and memory measured, got %+v", usage)
+	}
+}
+
+func Test_measureProcess_ReportsCPUOnlyWhenExclusive(t *testing.T) {
+	exclusive := measureProcess(os.Getpid(), true)()
+	if exclusive == nil || exclusive.CPUSeconds == nil || exclusive.MemoryBytes == 0 {
+		t.Errorf("Want CPU time and peak memory measured, got %+v", exclusive)
+	}
+
+	concurrent := measureProcess(os.Getpid(), false)()
+	if concurrent == nil || concurrent.CPUSeconds != nil || concurrent.MemoryBytes == 0 {
+		t.Errorf("Want only peak memory measured, got %+v", concurrent)
+	}
+}
+
+func Test_measureProcess_ReportsPeakMemory(t *testing.T) {
+	measure := measureProcess(os.Getpid(), true)
+
+	// Touch every page so the allocation is resident, the peak stays once it is collected
+	allocation := make([]byte, 64<<20)
+	for i := range allocation {
+		allocation[i] = 1
+	}
+	allocation = nil
+	runtime.GC()
+	debug.FreeOSMemory()
+
+	usage := measure()
+	if usage == nil || usage.MemoryBytes < 64<<20 {
+		t.Errorf("Want the peak memory to include the 64MB allocation, got %+v", usage)
+	}
+}
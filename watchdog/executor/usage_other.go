@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package executor
+
+import (
+	"errors"
+	"os"
+)
+
+// processUsage is only supported on Linux
+func processUsage(state *os.ProcessState) *ResourceUsage {
+	return nil
+}
+
+// sampleProcess is only supported on Linux
+func sampleProcess(pid int) (processSample, error) {
+	return processSample{}, errors.New("process sampling is only supported on linux")
+}
+
+// resetPeak is only supported on Linux
+func resetPeak(pid int) {}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"sync/atomic"
+
+	"eywa/watchdog/executor"
 )
 
 type ConcurrencyLimiter struct {
@@ -42,8 +44,8 @@ func (cl *ConcurrencyLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	if requestsStarted-completedRequested > cl.maxInflightRequests {
 		// This is a failure pathway, and we do not want to block on the write to finish
 		atomic.AddUint64(&cl.requestsCompleted, 1)
-		w.WriteHeader(http.StatusTooManyRequests)
-		fmt.Fprintf(w, "Concurrent request limit exceeded. Max concurrent requests: %d\n", cl.maxInflightRequests)
+		executor.WriteError(w, http.StatusTooManyRequests, executor.ErrorConcurrencyLimit,
+			fmt.Sprintf("Concurrent request limit exceeded. Max concurrent requests: %d", cl.maxInflightRequests))
 		return
 	}
 	cl.backendHTTPHandler.ServeHTTP(w, r)
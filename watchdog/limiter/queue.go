@@ -0,0 +1,126 @@
+package limiter
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"eywa/watchdog/executor"
+)
+
+// QueueingLimiter limits the number of concurrent requests like ConcurrencyLimiter, but requests
+// over the limit wait in a bounded FIFO queue for up to maxWait before being rejected. A request
+// completing hands its slot over to the head of the queue.
+type QueueingLimiter struct {
+	backendHTTPHandler http.Handler
+
+	maxInflightRequests int
+	maxQueueLength      int
+	maxWait             time.Duration
+
+	// ObserveWait is called with the time a queued request waited for a slot
+	ObserveWait func(time.Duration)
+
+	mu       sync.Mutex
+	inFlight int
+	queue    *list.List // queue holds a chan struct{} per waiting request, closed once it gets a slot
+}
+
+// NewQueueingLimiter creates a handler which queues up to queueLength requests once concurrencyLimit
+// requests are in flight.
+func NewQueueingLimiter(handler http.Handler, concurrencyLimit, queueLength int, maxWait time.Duration) *QueueingLimiter {
+	return &QueueingLimiter{
+		backendHTTPHandler:  handler,
+		maxInflightRequests: concurrencyLimit,
+		maxQueueLength:      queueLength,
+		maxWait:             maxWait,
+		queue:               list.New(),
+	}
+}
+
+func (ql *QueueingLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ql.mu.Lock()
+
+	if ql.inFlight < ql.maxInflightRequests {
+		ql.inFlight++
+		ql.mu.Unlock()
+
+		ql.serve(w, r)
+		return
+	}
+
+	if ql.queue.Len() >= ql.maxQueueLength {
+		ql.mu.Unlock()
+
+		executor.WriteError(w, http.StatusTooManyRequests, executor.ErrorConcurrencyLimit,
+			fmt.Sprintf("Concurrent request limit exceeded. Max concurrent requests: %d, max queued: %d",
+				ql.maxInflightRequests, ql.maxQueueLength))
+		return
+	}
+
+	slot := make(chan struct{})
+	waiter := ql.queue.PushBack(slot)
+	ql.mu.Unlock()
+
+	queuedAt := time.Now()
+	timer := time.NewTimer(ql.maxWait)
+	defer timer.Stop()
+
+	select {
+	case <-slot:
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+
+	ql.mu.Lock()
+	select {
+	case <-slot:
+		// The slot may have been handed over while timing out
+		ql.mu.Unlock()
+		ql.observeWait(queuedAt)
+
+		ql.serve(w, r)
+	default:
+		ql.queue.Remove(waiter)
+		ql.mu.Unlock()
+		ql.observeWait(queuedAt)
+
+		executor.WriteError(w, http.StatusTooManyRequests, executor.ErrorConcurrencyLimit,
+			fmt.Sprintf("Timed out after %s waiting for a concurrent request slot", ql.maxWait))
+	}
+}
+
+// QueueLength returns the number of requests waiting for a slot
+func (ql *QueueingLimiter) QueueLength() int {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+
+	return ql.queue.Len()
+}
+
+func (ql *QueueingLimiter) serve(w http.ResponseWriter, r *http.Request) {
+	defer ql.release()
+
+	ql.backendHTTPHandler.ServeHTTP(w, r)
+}
+
+// release hands the slot over to the oldest waiting request or frees it
+func (ql *QueueingLimiter) release() {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+
+	if head := ql.queue.Front(); head != nil {
+		close(ql.queue.Remove(head).(chan struct{}))
+		return
+	}
+
+	ql.inFlight--
+}
+
+func (ql *QueueingLimiter) observeWait(queuedAt time.Time) {
+	if ql.ObserveWait != nil {
+		ql.ObserveWait(time.Since(queuedAt))
+	}
+}
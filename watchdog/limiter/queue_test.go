@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingHandler records the order requests are served in and blocks them until released
+type blockingHandler struct {
+	mu      sync.Mutex
+	served  []string
+	release chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.served = append(h.served, r.URL.Path)
+	h.mu.Unlock()
+
+	<-h.release
+}
+
+func serveAsync(handler http.Handler, path string) <-chan int {
+	code := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		code <- rec.Code
+	}()
+
+	return code
+}
+
+func waitForQueue(t *testing.T, limiter *QueueingLimiter, length int) {
+	deadline := time.Now().Add(time.Second)
+	for limiter.QueueLength() != length {
+		if time.Now().After(deadline) {
+			t.Fatalf("Want %d queued requests, got %d", length, limiter.QueueLength())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_QueueingLimiter_ServesQueuedRequestsInOrder(t *testing.T) {
+	backend := &blockingHandler{release: make(chan struct{})}
+	limiter := NewQueueingLimiter(backend, 1, 2, time.Second)
+
+	first := serveAsync(limiter, "/first")
+	waitForQueue(t, limiter, 0)
+	time.Sleep(10 * time.Millisecond)
+
+	second := serveAsync(limiter, "/second")
+	waitForQueue(t, limiter, 1)
+	third := serveAsync(limiter, "/third")
+	waitForQueue(t, limiter, 2)
+
+	if code := <-serveAsync(limiter, "/rejected"); code != http.StatusTooManyRequests {
+		t.Errorf("Want %d once the queue is full, got %d", http.StatusTooManyRequests, code)
+	}
+
+	close(backend.release)
+	for _, code := range []<-chan int{first, second, third} {
+		if got := <-code; got != http.StatusOK {
+			t.Errorf("Want %d, got %d", http.StatusOK, got)
+		}
+	}
+
+	want := []string{"/first", "/second", "/third"}
+	for i, path := range want {
+		if backend.served[i] != path {
+			t.Errorf("Want requests served in order %v, got %v", want, backend.served)
+			break
+		}
+	}
+}
+
+func Test_QueueingLimiter_RejectsAfterMaxWait(t *testing.T) {
+	backend := &blockingHandler{release: make(chan struct{})}
+	defer close(backend.release)
+
+	limiter := NewQueueingLimiter(backend, 1, 1, 20*time.Millisecond)
+
+	var waited time.Duration
+	limiter.ObserveWait = func(wait time.Duration) { waited = wait }
+
+	serveAsync(limiter, "/first")
+	time.Sleep(10 * time.Millisecond)
+
+	if code := <-serveAsync(limiter, "/second"); code != http.StatusTooManyRequests {
+		t.Errorf("Want %d after waiting, got %d", http.StatusTooManyRequests, code)
+	}
+	if waited < 20*time.Millisecond {
+		t.Errorf("Want the wait observed, got %s", waited)
+	}
+	if limiter.QueueLength() != 0 {
+		t.Errorf("Want the timed out request removed from the queue, got %d", limiter.QueueLength())
+	}
+}
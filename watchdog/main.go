@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -16,7 +17,9 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"eywa/go-libs/tracing"
 	"eywa/watchdog/config"
+	"eywa/watchdog/drain"
 	"eywa/watchdog/executor"
 	limiter "eywa/watchdog/limiter"
 	"eywa/watchdog/metrics"
@@ -57,13 +60,52 @@ func main() {
 		log.Infof("Debug mode enabled")
 	}
 
-	requestHandler := buildRequestHandler(wc)
+	shutdownTracing, err := tracing.Setup(&tracing.Config{
+		ServiceName: "watchdog",
+		Endpoint:    wc.OTLPEndpoint,
+		Insecure:    true,
+		SampleRatio: 1,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup tracing: %s", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	requestHandler, functionProcess := buildRequestHandler(wc)
 
 	log.Infof("OperationalMode: %s\n", config.WatchdogMode(wc.OperationalMode))
 
+	tracker := &drain.Tracker{}
+	metrics.RegisterDrain(
+		func() float64 { return float64(tracker.InFlight()) },
+		func() float64 {
+			if tracker.Draining() {
+				return 1
+			}
+			return 0
+		},
+	)
+
+	supervised, _ := functionProcess.(supervisedProcess)
+	if supervised != nil {
+		metrics.RegisterSupervisor(
+			func() float64 { return float64(supervised.Restarts()) },
+			func() float64 {
+				if supervised.Ready() {
+					return 1
+				}
+				return 0
+			},
+		)
+	}
+
 	httpMetrics := metrics.NewHttp()
-	http.HandleFunc("/", metrics.InstrumentHandler(requestHandler, httpMetrics))
-	http.HandleFunc("/_/health", makeHealthHandler())
+	http.HandleFunc("/", metrics.InstrumentHandler(tracker.Handler(requestHandler), httpMetrics))
+	http.HandleFunc("/_/health", makeHealthHandler(tracker, supervised))
+
+	// Lifecycle hooks are only called by the watchdog
+	http.Handle(executor.InitHookPath, http.NotFoundHandler())
+	http.Handle(executor.ShutdownHookPath, http.NotFoundHandler())
 
 	metricsServer := metrics.MetricsServer{}
 	metricsServer.Register(wc.MetricsPort)
@@ -86,7 +128,19 @@ func main() {
 		wc.ExecTimeout)
 	log.Printf("Listening on port: %d\n", wc.TCPPort)
 
-	listenUntilShutdown(shutdownTimeout, s, wc.SuppressLock)
+	listenUntilShutdown(shutdownTimeout, wc.DrainTimeout, s, wc.SuppressLock, tracker, functionProcess)
+}
+
+// functionProcess is a long running function process stopped once requests are drained
+type functionProcess interface {
+	Stop(timeout time.Duration) error
+}
+
+// supervisedProcess is a function process restarted when it crashes
+type supervisedProcess interface {
+	Ready() bool
+	Restarts() uint64
+	LastCrash() *executor.ProcessCrash
 }
 
 func markUnhealthy() error {
@@ -98,7 +152,8 @@ func markUnhealthy() error {
 	return removeErr
 }
 
-func listenUntilShutdown(shutdownTimeout time.Duration, s *http.Server, suppressLock bool) {
+func listenUntilShutdown(shutdownTimeout, drainTimeout time.Duration, s *http.Server, suppressLock bool,
+	tracker *drain.Tracker, process functionProcess) {
 
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -107,24 +162,34 @@ func listenUntilShutdown(shutdownTimeout time.Duration, s *http.Server, suppress
 
 		<-sig
 
-		log.Printf("SIGTERM received.. shutting down server in %s\n", shutdownTimeout.String())
+		log.Printf("SIGTERM received.. draining %d requests in flight for up to %s\n", tracker.InFlight(), drainTimeout)
 
+		tracker.Start()
 		healthErr := markUnhealthy()
 
 		if healthErr != nil {
 			log.Printf("Unable to mark unhealthy during shutdown: %s\n", healthErr.Error())
 		}
 
-		<-time.Tick(shutdownTimeout)
+		if remaining := tracker.Wait(drainTimeout); remaining > 0 {
+			log.Printf("Drain timeout reached with %d requests in flight\n", remaining)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
 
-		if err := s.Shutdown(context.Background()); err != nil {
+		if err := s.Shutdown(ctx); err != nil {
 			// Error from closing listeners, or context timeout:
 			log.Printf("Error in Shutdown: %v", err)
 		}
 
-		log.Printf("No new connections allowed. Exiting in: %s\n", shutdownTimeout.String())
+		if process != nil {
+			log.Printf("No new connections allowed. Stopping function process\n")
 
-		<-time.Tick(shutdownTimeout)
+			if err := process.Stop(shutdownTimeout); err != nil {
+				log.Printf("Error stopping function process: %v", err)
+			}
+		}
 
 		close(idleConnsClosed)
 	}()
@@ -152,21 +217,50 @@ func listenUntilShutdown(shutdownTimeout time.Duration, s *http.Server, suppress
 	<-idleConnsClosed
 }
 
-func buildRequestHandler(wc config.WatchdogConfig) http.Handler {
+func buildRequestHandler(wc config.WatchdogConfig) (http.Handler, functionProcess) {
 	var requestHandler http.HandlerFunc
+	var process functionProcess
 
 	switch wc.OperationalMode {
+	case config.ModeStreaming:
+		requestHandler = makeRunnerRequestHandler(wc, &executor.StreamingFunctionRunner{
+			ExecTimeout:      wc.ExecTimeout,
+			ContentType:      wc.ContentType,
+			InjectCGIHeaders: wc.InjectCGIHeaders,
+			WriteDebug:       wc.Debug,
+			MaxLogBytes:      wc.MaxLogBytes,
+		})
+	case config.ModeSerializing:
+		requestHandler = makeRunnerRequestHandler(wc, &executor.SerializingFunctionRunner{
+			ExecTimeout:      wc.ExecTimeout,
+			ContentType:      wc.ContentType,
+			InjectCGIHeaders: wc.InjectCGIHeaders,
+			WriteDebug:       wc.Debug,
+			MaxLogBytes:      wc.MaxLogBytes,
+		})
+	case config.ModeAfterBurn:
+		requestHandler, process = makeAfterBurnRequestHandler(wc)
 	case config.ModeHTTP:
-		requestHandler = makeHTTPRequestHandler(wc)
+		requestHandler, process = makeHTTPRequestHandler(wc)
+	case config.ModeStatic:
+		requestHandler = makeRunnerRequestHandler(wc, executor.NewStaticFunctionRunner(wc.StaticPath))
 	default:
 		log.Panicf("unknown watchdog mode: %d", wc.OperationalMode)
 	}
 
+	if wc.MaxInflight > 0 && wc.MaxQueue > 0 {
+		queue := limiter.NewQueueingLimiter(requestHandler, wc.MaxInflight, wc.MaxQueue, wc.MaxQueueWait)
+		waitSeconds := metrics.RegisterQueue(func() float64 { return float64(queue.QueueLength()) })
+		queue.ObserveWait = func(wait time.Duration) { waitSeconds.Observe(wait.Seconds()) }
+
+		return queue, process
+	}
+
 	if wc.MaxInflight > 0 {
-		return limiter.NewConcurrencyLimiter(requestHandler, wc.MaxInflight)
+		return limiter.NewConcurrencyLimiter(requestHandler, wc.MaxInflight), process
 	}
 
-	return requestHandler
+	return requestHandler, process
 }
 
 // createLockFile returns a path to a lock file and/or an error
@@ -189,14 +283,23 @@ func createLockFile() (string, error) {
 	return path, nil
 }
 
-func makeHTTPRequestHandler(wc config.WatchdogConfig) func(http.ResponseWriter, *http.Request) {
+func makeHTTPRequestHandler(wc config.WatchdogConfig) (http.HandlerFunc, functionProcess) {
 	commandName, arguments := wc.Process()
-	functionInvoker := executor.HTTPFunctionRunner{
+	functionInvoker := &executor.HTTPFunctionRunner{
 		ExecTimeout:    wc.ExecTimeout,
 		Process:        commandName,
 		ProcessArgs:    arguments,
 		BufferHTTPBody: wc.BufferHTTPBody,
 		WriteDebug:     wc.Debug,
+		MaxLogBytes:    wc.MaxLogBytes,
+		MaxInflight:    wc.MaxInflight,
+
+		ReadyPath:         wc.UpstreamReadyPath,
+		ReadyInterval:     wc.UpstreamReadyInterval,
+		InitTimeout:       wc.InitTimeout,
+		ShutdownTimeout:   wc.ShutdownTimeout,
+		RestartBackoff:    wc.RestartBackoff,
+		MaxRestartBackoff: wc.MaxRestartBackoff,
 	}
 
 	if len(wc.UpstreamURL) == 0 {
@@ -216,7 +319,6 @@ func makeHTTPRequestHandler(wc config.WatchdogConfig) func(http.ResponseWriter,
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-
 		req := executor.FunctionRequest{
 			Process:      commandName,
 			ProcessArgs:  arguments,
@@ -230,14 +332,60 @@ func makeHTTPRequestHandler(wc config.WatchdogConfig) func(http.ResponseWriter,
 
 		err := functionInvoker.Run(req, r.ContentLength, r, w)
 		if err != nil {
-			log.Errorf("Failed to run function invocation: %s", err)
-			w.WriteHeader(500)
-			_, err := w.Write([]byte(err.Error()))
-			if err != nil {
-				log.Printf("Failed to wrtie error response: %s", err)
-			}
+			writeRunError(w, err)
 		}
+	}, functionInvoker
+}
+
+// requestRunner runs a function invocation for one of the fork or static modes
+type requestRunner interface {
+	Run(req executor.FunctionRequest, r *http.Request, w http.ResponseWriter) error
+}
+
+func makeRunnerRequestHandler(wc config.WatchdogConfig, runner requestRunner) func(http.ResponseWriter, *http.Request) {
+	commandName, arguments := wc.Process()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := executor.FunctionRequest{
+			Process:      commandName,
+			ProcessArgs:  arguments,
+			InputReader:  r.Body,
+			OutputWriter: w,
+		}
+
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+
+		err := runner.Run(req, r, w)
+		if err != nil {
+			writeRunError(w, err)
+		}
+	}
+}
+
+func makeAfterBurnRequestHandler(wc config.WatchdogConfig) (http.HandlerFunc, functionProcess) {
+	commandName, arguments := wc.Process()
+	functionInvoker := &executor.AfterBurnFunctionRunner{
+		ExecTimeout: wc.ExecTimeout,
+		Process:     commandName,
+		ProcessArgs: arguments,
+		WriteDebug:  wc.Debug,
+		MaxLogBytes: wc.MaxLogBytes,
+	}
+
+	fmt.Printf("Forking - %s %s\n", commandName, arguments)
+	err := functionInvoker.Start()
+	if err != nil {
+		log.Fatalf("Failed to start function invoker: %s", err)
 	}
+
+	return makeRunnerRequestHandler(wc, functionInvoker), functionInvoker
+}
+
+func writeRunError(w http.ResponseWriter, err error) {
+	log.Errorf("Failed to run function invocation: %s", err)
+	executor.WriteError(w, http.StatusInternalServerError, executor.ErrorFunction, err.Error())
 }
 
 func lockFilePresent() bool {
@@ -249,18 +397,51 @@ func lockFilePresent() bool {
 	return true
 }
 
-func makeHealthHandler() func(http.ResponseWriter, *http.Request) {
+// healthStatus is the body of the health endpoint
+type healthStatus struct {
+	Status   string `json:"status"`
+	InFlight int64  `json:"in_flight"`
+	Restarts uint64 `json:"restarts,omitempty"`
+}
+
+func makeHealthHandler(tracker *drain.Tracker, process supervisedProcess) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			if atomic.LoadInt32(&acceptingConnections) == 0 || !lockFilePresent() {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				return
+			status := healthStatus{
+				Status:   "ok",
+				InFlight: tracker.InFlight(),
+			}
+
+			code := http.StatusOK
+			if tracker.Draining() {
+				status.Status = "draining"
+				code = http.StatusServiceUnavailable
+			} else if atomic.LoadInt32(&acceptingConnections) == 0 || !lockFilePresent() {
+				status.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+			}
+
+			if process != nil {
+				status.Restarts = process.Restarts()
+				if code == http.StatusOK && !process.Ready() {
+					status.Status = "starting"
+					if process.LastCrash() != nil {
+						status.Status = "restarting"
+					}
+					code = http.StatusServiceUnavailable
+				}
+			}
+
+			// The liveness probe only checks the watchdog answers, a function still
+			// starting or restarting must not get the pod killed
+			if r.URL.Query().Get("probe") == "liveness" {
+				code = http.StatusOK
 			}
 
-			w.WriteHeader(http.StatusOK)
-			_, err := w.Write([]byte("OK"))
-			if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			if err := json.NewEncoder(w).Encode(status); err != nil {
 				log.Printf("Failed to write to health handler response: %s", err)
 			}
 
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RegisterDrain exposes the number of requests in flight and whether the watchdog is draining
+func RegisterDrain(inFlight func() float64, draining func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "number of HTTP requests being served",
+	}, inFlight)
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "watchdog",
+		Name:      "draining",
+		Help:      "1 while the watchdog waits for requests in flight before shutting down",
+	}, draining)
+}
+
+// RegisterSupervisor exposes the restarts of the function process and whether it is up
+func RegisterSupervisor(restarts func() float64, up func() float64) {
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Subsystem: "watchdog",
+		Name:      "function_restarts_total",
+		Help:      "number of times the crashed function process was restarted",
+	}, restarts)
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "watchdog",
+		Name:      "function_up",
+		Help:      "1 while the function process is running",
+	}, up)
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RegisterQueue exposes the number of requests waiting for a concurrency slot and returns
+// the histogram of the time they waited
+func RegisterQueue(length func() float64) prometheus.Histogram {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "watchdog",
+		Name:      "queue_length",
+		Help:      "number of requests waiting for a concurrent request slot",
+	}, length)
+
+	return promauto.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "watchdog",
+		Name:      "queue_wait_seconds",
+		Help:      "Seconds requests waited for a concurrent request slot.",
+		Buckets:   prometheus.DefBuckets,
+	})
+}